@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+//
+// ---------- RAW API RESPONSE ----------
+//
+
+type openMeteoAirResponse struct {
+	Current struct {
+		Time          string  `json:"time"`
+		EuropeanAQI   float64 `json:"european_aqi"`
+		PM25          float64 `json:"pm2_5"`
+		PM10          float64 `json:"pm10"`
+		Ozone         float64 `json:"ozone"`
+		BirchPollen   float64 `json:"birch_pollen"`
+		GrassPollen   float64 `json:"grass_pollen"`
+		RagweedPollen float64 `json:"ragweed_pollen"`
+	} `json:"current"`
+}
+
+//
+// ---------- VIEW MODELS ----------
+//
+
+type airLevel int
+
+const (
+	airLevelLow airLevel = iota
+	airLevelModerate
+	airLevelHigh
+	airLevelVeryHigh
+)
+
+func (l airLevel) Text(lang Lang) string {
+	return lang.T(fmt.Sprintf("air.level.%d", l))
+}
+
+type AirReading struct {
+	Label string // ключ каталога
+	Value float64
+	Unit  string
+	Level airLevel
+}
+
+type AirQualityView struct {
+	UpdatedAt time.Time
+	AQI       AirReading
+	Pollutant []AirReading
+	Pollen    []AirReading
+}
+
+// Пороги (moderate, high, very high). Для AQI — европейская шкала,
+// для пыльцы — зёрна/м³ примерно как у Polleninfo/ePIN.
+type airThresholds [3]float64
+
+var (
+	aqiThresholds     = airThresholds{40, 60, 80}
+	pm25Thresholds    = airThresholds{10, 25, 50}
+	pm10Thresholds    = airThresholds{20, 50, 100}
+	ozoneThresholds   = airThresholds{100, 130, 240}
+	birchThresholds   = airThresholds{10, 100, 1000}
+	grassThresholds   = airThresholds{5, 30, 150}
+	ragweedThresholds = airThresholds{5, 20, 100}
+)
+
+func (t airThresholds) level(v float64) airLevel {
+	switch {
+	case v >= t[2]:
+		return airLevelVeryHigh
+	case v >= t[1]:
+		return airLevelHigh
+	case v >= t[0]:
+		return airLevelModerate
+	}
+	return airLevelLow
+}
+
+func reading(label string, v float64, unit string, t airThresholds) AirReading {
+	return AirReading{Label: label, Value: v, Unit: unit, Level: t.level(v)}
+}
+
+// Warning возвращает текст для баннера на странице погоды или "",
+// если всё в пределах нормы.
+func (v *AirQualityView) Warning(lang Lang) string {
+	var parts []string
+	if v.AQI.Level >= airLevelHigh {
+		parts = append(parts, lang.T("air.warning_aqi", v.AQI.Level.Text(lang), v.AQI.Value))
+	}
+	for _, p := range v.Pollen {
+		if p.Level >= airLevelHigh {
+			parts = append(parts, lang.T("air.warning_pollen", lang.T(p.Label), p.Level.Text(lang)))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+//
+// ---------- LOADING ----------
+//
+
+// Последние данные о воздухе — их подхватывает страница погоды для баннера.
+var (
+	airQualityMu     sync.RWMutex
+	latestAirQuality *AirQualityView
+)
+
+// airQualityBanner включает предупреждение на странице погоды.
+var airQualityBanner = envBool("DASHBOARD_AIR_QUALITY_BANNER", true)
+
+func loadAirQuality(ctx context.Context) (*AirQualityView, error) {
+	u := "https://air-quality-api.open-meteo.com/v1/air-quality" +
+		fmt.Sprintf("?latitude=%.4f&longitude=%.4f", homeLocation.Latitude, homeLocation.Longitude) +
+		"&current=european_aqi,pm2_5,pm10,ozone,birch_pollen,grass_pollen,ragweed_pollen" +
+		"&timezone=" + url.QueryEscape(openMeteoZone(displayZone))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("air quality fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+
+	var raw openMeteoAirResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+
+	c := raw.Current
+	return &AirQualityView{
+		UpdatedAt: displayNow(),
+		AQI:       reading("air.aqi", c.EuropeanAQI, "", aqiThresholds),
+		Pollutant: []AirReading{
+			reading("air.pm25", c.PM25, "µg/m³", pm25Thresholds),
+			reading("air.pm10", c.PM10, "µg/m³", pm10Thresholds),
+			reading("air.ozone", c.Ozone, "µg/m³", ozoneThresholds),
+		},
+		Pollen: []AirReading{
+			reading("air.birch", c.BirchPollen, "/m³", birchThresholds),
+			reading("air.grass", c.GrassPollen, "/m³", grassThresholds),
+			reading("air.ragweed", c.RagweedPollen, "/m³", ragweedThresholds),
+		},
+	}, nil
+}
+
+// openMeteoZone — имя зоны для параметра timezone. У системной зоны имени
+// нет, тогда Open-Meteo сам определит её по координатам.
+func openMeteoZone(loc *time.Location) string {
+	if loc == time.Local || loc.String() == "Local" {
+		return "auto"
+	}
+	return loc.String()
+}
+
+// airQualityWarning — текст баннера для страницы погоды.
+func airQualityWarning(lang Lang) string {
+	if !airQualityBanner {
+		return ""
+	}
+	airQualityMu.RLock()
+	defer airQualityMu.RUnlock()
+	if latestAirQuality == nil {
+		return ""
+	}
+	return latestAirQuality.Warning(lang)
+}
+
+//
+// ---------- RENDERING ----------
+//
+
+// renderAirQuality рисует страницу сразу в 1 бит: чёрный текст и шкалы
+// из сегментов на белом, без полутонов.
+func renderAirQuality(v *AirQualityView, lang Lang) *gg.Context {
+	const W, H = 800, 480
+
+	dc := gg.NewContext(W, H)
+	dc.SetRGB(1, 1, 1)
+	dc.Clear()
+	dc.SetRGB(0, 0, 0)
+
+	dc.SetFontFace(boldFace(20))
+	dc.DrawString(lang.T("air.title"), 24, 44)
+	dc.SetFontFace(boldFace(11))
+	dc.DrawStringAnchored(lang.T("page.updated", lang.Date(v.UpdatedAt, "short_stamp")), W-24, 44, 1, 0)
+	dc.SetLineWidth(2)
+	dc.DrawLine(24, 58, W-24, 58)
+	dc.Stroke()
+
+	// Крупный индекс слева
+	dc.SetFontFace(boldFace(64))
+	dc.DrawStringAnchored(fmt.Sprintf("%.0f", v.AQI.Value), 130, 170, 0.5, 0.5)
+	dc.SetFontFace(boldFace(14))
+	dc.DrawStringAnchored(lang.T(v.AQI.Label), 130, 236, 0.5, 0.5)
+	dc.DrawStringAnchored(v.AQI.Level.Text(lang), 130, 266, 0.5, 0.5)
+	drawLevelBar(dc, 58, 290, 144, v.AQI.Level)
+
+	dc.SetLineWidth(1)
+	dc.DrawLine(260, 80, 260, H-40)
+	dc.Stroke()
+
+	y := 100.0
+	dc.SetFontFace(boldFace(14))
+	for _, r := range v.Pollutant {
+		drawAirRow(dc, r, y, lang)
+		y += 44
+	}
+	y += 20
+	dc.SetFontFace(boldFace(12))
+	dc.DrawString(lang.T("air.pollen"), 290, y-18)
+	dc.SetFontFace(boldFace(14))
+	for _, r := range v.Pollen {
+		drawAirRow(dc, r, y, lang)
+		y += 44
+	}
+
+	return dc
+}
+
+func drawAirRow(dc *gg.Context, r AirReading, y float64, lang Lang) {
+	dc.DrawString(lang.T(r.Label), 290, y+16)
+	dc.DrawStringAnchored(fmt.Sprintf("%.0f %s", r.Value, r.Unit), 600, y+16, 1, 0)
+	drawLevelBar(dc, 624, y, 140, r.Level)
+}
+
+// drawLevelBar — четыре сегмента, закрашенных до текущего уровня.
+func drawLevelBar(dc *gg.Context, x, y, w float64, level airLevel) {
+	const gap, h = 4.0, 20.0
+	seg := (w - 3*gap) / 4
+	dc.SetLineWidth(2)
+	for i := 0; i < 4; i++ {
+		sx := x + float64(i)*(seg+gap)
+		dc.DrawRectangle(sx, y, seg, h)
+		if airLevel(i) <= level {
+			dc.Fill()
+		} else {
+			dc.Stroke()
+		}
+	}
+}
+
+func renderAirQualityBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	view, err := cycleLoad(ctx, "airquality", func() (*AirQualityView, error) {
+		return loadAirQuality(ctx)
+	})
+	if err != nil {
+		log.Println("error fetching:", err)
+		return nil, fmt.Errorf("unable to fetch air quality: %w", err)
+	}
+
+	airQualityMu.Lock()
+	latestAirQuality = view
+	airQualityMu.Unlock()
+
+	return encode1bppBMP(withWarningBanner(renderAirQuality(view, lang).Image(), lang))
+}
+
+func handleAirQualityBMP(w http.ResponseWriter, r *http.Request) {
+	serveCachedImage(w, r, &airQualityCache)
+}
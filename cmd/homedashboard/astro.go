@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+//
+// ---------- SUNRISE / SUNSET ----------
+//
+
+// sunTimes считает восход и закат по упрощённому уравнению восхода
+// (точность — пара минут, для экрана хватает). Используется, когда
+// Open-Meteo не прислал sunrise/sunset. ok = false для полярного дня или ночи.
+func sunTimes(day time.Time, lat, lon float64) (rise, set time.Time, ok bool) {
+	const rad = math.Pi / 180
+
+	y, m, d := day.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	jd := float64(noon.Unix())/86400 + 2440587.5
+
+	n := math.Round(jd - 2451545.0 + 0.0008)
+	jStar := n - lon/360
+	meanAnomaly := math.Mod(357.5291+0.98560028*jStar, 360) * rad
+	center := 1.9148*math.Sin(meanAnomaly) + 0.02*math.Sin(2*meanAnomaly) + 0.0003*math.Sin(3*meanAnomaly)
+	lambda := math.Mod(meanAnomaly/rad+center+180+102.9372, 360) * rad
+	transit := 2451545.0 + jStar + 0.0053*math.Sin(meanAnomaly) - 0.0069*math.Sin(2*lambda)
+
+	sinDecl := math.Sin(lambda) * math.Sin(23.4397*rad)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (math.Sin(-0.833*rad) - math.Sin(lat*rad)*sinDecl) / (math.Cos(lat*rad) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHour) / rad
+
+	julianToTime := func(j float64) time.Time {
+		return time.Unix(int64(math.Round((j-2440587.5)*86400)), 0).In(day.Location())
+	}
+	return julianToTime(transit - hourAngle/360), julianToTime(transit + hourAngle/360), true
+}
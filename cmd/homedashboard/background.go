@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -23,47 +24,123 @@ func startBackgroundRenderer(ctx context.Context) {
 }
 
 func loadAllImages(ctx context.Context) {
+	// Данные из сети грузятся один раз за проход, а рендерятся на каждом языке
+	ctx = withRenderCycle(ctx)
+	for _, lang := range activeLangs() {
+		loadLangImages(ctx, lang)
+	}
+
+	// Photo — без текста, один раз на все языки
+	if data, _ := photoCache.Get(defaultLang); data == nil {
+		if bmp, err := renderPhotoBMP(ctx); err == nil {
+			photoCache.Set(defaultLang, bmp)
+		} else {
+			log.Println("renderPhotoBMP error:", err)
+		}
+	}
+}
+
+// loadLangImages рендерит все страницы с текстом на одном языке.
+func loadLangImages(ctx context.Context, lang Lang) {
+	// Warnings — первыми, их баннер рисуется на всех остальных страницах
+	if bmp, err := renderWarningsBMP(ctx, lang); err == nil {
+		warningsCache.Set(lang, bmp)
+	} else {
+		log.Println("renderWarningsBMP error:", err)
+	}
+
 	// Transport
-	if bmp, err := renderTransportBMP(ctx); err == nil {
-		transportCache.Set(bmp)
+	if bmp, err := renderTransportBMP(ctx, lang); err == nil {
+		transportCache.Set(lang, bmp)
 	} else {
 		log.Println("renderCalendarBMP error:", err)
 	}
 
+	// Air quality — до погоды, чтобы баннер на ней был свежим
+	if bmp, err := renderAirQualityBMP(ctx, lang); err == nil {
+		airQualityCache.Set(lang, bmp)
+	} else {
+		log.Println("renderAirQualityBMP error:", err)
+	}
+
 	// Weather
-	if bmp, err := renderWeatherBMP(ctx); err == nil {
-		weatherCache.Set(bmp)
+	if pages, err := renderWeatherPages(ctx, lang); err == nil {
+		weatherPages.Set(lang, pages)
 	} else {
-		log.Println("renderWeatherBMP error:", err)
+		log.Println("renderWeatherPages error:", err)
 	}
 
 	// Quote
-	if bmp, err := renderQuoteBMP(ctx); err == nil {
-		quoteCache.Set(bmp)
+	if bmp, err := renderQuoteBMP(ctx, lang); err == nil {
+		quoteCache.Set(lang, bmp)
 	} else {
 		log.Println("renderQuoteBMP error:", err)
 	}
 
-	// Photo
-	if len(photoCache.data) == 0 {
-		if bmp, err := renderPhotoBMP(ctx); err == nil {
-			photoCache.Set(bmp)
-		} else {
-			log.Println("renderPhotoBMP error:", err)
-		}
-	}
-
 	// Stocks
-	if bmp, err := renderVWCEBMP(ctx); err == nil {
-		stocksCache.Set(bmp)
+	if bmp, err := renderVWCEBMP(ctx, lang); err == nil {
+		stocksCache.Set(lang, bmp)
 	} else {
 		log.Println("renderStocksBMP error:", err)
 	}
 
 	// Calendar
-	if bmp, err := renderCalendarBMP(ctx); err == nil {
-		calendarCache.Set(bmp)
+	if bmp, err := renderCalendarBMP(ctx, lang); err == nil {
+		calendarCache.Set(lang, bmp)
 	} else {
 		log.Println("renderCalendarBMP error:", err)
 	}
+
+	// Waste
+	if wasteConfigured() {
+		if bmp, err := renderWasteBMP(ctx, lang); err == nil {
+			wasteCache.Set(lang, bmp)
+		} else {
+			log.Println("renderWasteBMP error:", err)
+		}
+	}
+}
+
+//
+// ---------- RENDER CYCLE ----------
+//
+
+// renderCycle — загрузки одного прохода фонового рендера. Всё, что не
+// зависит от языка, грузится на первом языке, а остальные берут готовое —
+// вместе с ошибкой: упавший источник за проход спрашиваем один раз.
+type renderCycle struct {
+	mu      sync.Mutex
+	results map[string]*cycleResult
+}
+
+type cycleResult struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+type renderCycleKey struct{}
+
+func withRenderCycle(ctx context.Context) context.Context {
+	return context.WithValue(ctx, renderCycleKey{}, &renderCycle{results: map[string]*cycleResult{}})
+}
+
+// cycleLoad вызывает load один раз на ключ за проход. Вне прохода (рендер
+// из обработчика запроса) — просто вызывает load.
+func cycleLoad[T any](ctx context.Context, key string, load func() (T, error)) (T, error) {
+	c, _ := ctx.Value(renderCycleKey{}).(*renderCycle)
+	if c == nil {
+		return load()
+	}
+	c.mu.Lock()
+	r, ok := c.results[key]
+	if !ok {
+		r = &cycleResult{}
+		c.results[key] = r
+	}
+	c.mu.Unlock()
+
+	r.once.Do(func() { r.value, r.err = load() })
+	v, _ := r.value.(T)
+	return v, r.err
 }
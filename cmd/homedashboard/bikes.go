@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ---------- CONFIG ----------
+//
+
+// Прокат велосипедов по GBFS: DASHBOARD_BIKES_GBFS — адрес gbfs.json
+// (оттуда берутся station_information, station_status и vehicle_types).
+// Станции через «;»: подпись и station_id или название станции у оператора:
+//
+//	DASHBOARD_BIKES_GBFS="https://gbfs.nextbike.net/maps/gbfs/v2/nextbike_dr/gbfs.json"
+//	DASHBOARD_BIKES_STATIONS="Am Bahnhof=12345; Uni=Universitätsstraße"
+//
+// Строка станций встаёт на табло отправлений над остановками.
+var (
+	bikesGBFS     = envString("DASHBOARD_BIKES_GBFS", "")
+	bikesStations = parseBikeStations(envString("DASHBOARD_BIKES_STATIONS", ""))
+)
+
+const (
+	bikesStatusMaxAge = time.Minute // GBFS обычно обновляет статус раз в минуту
+	bikesInfoMaxAge   = time.Hour   // список станций почти не меняется
+)
+
+type bikeStation struct {
+	Label string
+	ID    string // station_id или название, пока станция не найдена
+}
+
+func parseBikeStations(s string) []bikeStation {
+	var stations []bikeStation
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		label, id, ok := strings.Cut(item, "=")
+		if !ok {
+			label, id = "", label
+		}
+		st := bikeStation{Label: strings.TrimSpace(label), ID: strings.TrimSpace(id)}
+		if st.ID == "" {
+			log.Printf("config: bike station %q has no id", item)
+			continue
+		}
+		stations = append(stations, st)
+	}
+	return stations
+}
+
+func bikesConfigured() bool {
+	return bikesGBFS != "" && len(bikesStations) > 0
+}
+
+//
+// ---------- GBFS ----------
+//
+
+// gbfsText — в GBFS 2 название строкой, в GBFS 3 списком переводов.
+type gbfsText string
+
+func (t *gbfsText) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = gbfsText(s)
+		return nil
+	}
+	var list []struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	if len(list) > 0 {
+		*t = gbfsText(list[0].Text)
+	}
+	return nil
+}
+
+type gbfsDiscovery struct {
+	Data json.RawMessage `json:"data"`
+}
+
+type gbfsFeed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// feeds — адреса фидов: в GBFS 3 список прямо в data, в GBFS 2 — по языкам.
+func (d gbfsDiscovery) feeds() map[string]string {
+	out := map[string]string{}
+	var v3 struct {
+		Feeds []gbfsFeed `json:"feeds"`
+	}
+	if json.Unmarshal(d.Data, &v3) == nil && len(v3.Feeds) > 0 {
+		for _, f := range v3.Feeds {
+			out[f.Name] = f.URL
+		}
+		return out
+	}
+	var v2 map[string]struct {
+		Feeds []gbfsFeed `json:"feeds"`
+	}
+	if json.Unmarshal(d.Data, &v2) != nil {
+		return out
+	}
+	// Любой язык годится: адреса фидов от языка не зависят
+	for _, l := range v2 {
+		for _, f := range l.Feeds {
+			out[f.Name] = f.URL
+		}
+		if len(out) > 0 {
+			break
+		}
+	}
+	return out
+}
+
+type gbfsStationInformation struct {
+	Data struct {
+		Stations []struct {
+			StationID string   `json:"station_id"`
+			Name      gbfsText `json:"name"`
+		} `json:"stations"`
+	} `json:"data"`
+}
+
+type gbfsStationStatus struct {
+	Data struct {
+		Stations []struct {
+			StationID string `json:"station_id"`
+			// GBFS 2 и 3 называют число по-разному
+			NumBikes    *int  `json:"num_bikes_available"`
+			NumVehicles *int  `json:"num_vehicles_available"`
+			NumEBikes   *int  `json:"num_ebikes_available"` // расширение nextbike и др.
+			NumDocks    *int  `json:"num_docks_available"`
+			IsRenting   *bool `json:"is_renting"`
+			Types       []struct {
+				VehicleTypeID string `json:"vehicle_type_id"`
+				Count         int    `json:"count"`
+			} `json:"vehicle_types_available"`
+		} `json:"stations"`
+	} `json:"data"`
+}
+
+type gbfsVehicleTypes struct {
+	Data struct {
+		VehicleTypes []struct {
+			VehicleTypeID  string `json:"vehicle_type_id"`
+			PropulsionType string `json:"propulsion_type"`
+		} `json:"vehicle_types"`
+	} `json:"data"`
+}
+
+// gbfsJSON читает фид по имени по адресу из gbfs.json.
+func gbfsJSON(ctx context.Context, feeds map[string]string, name string, out any) error {
+	u, ok := feeds[name]
+	if !ok {
+		return fmt.Errorf("gbfs: no %s feed", name)
+	}
+	return getJSON(ctx, u, nil, out)
+}
+
+//
+// ---------- STATE ----------
+//
+
+// BikeStationStatus — строка станции на табло.
+type BikeStationStatus struct {
+	Label    string
+	Bikes    int // все свободные, включая электро
+	EBikes   int
+	Docks    int
+	HasDocks bool // у станций без доков (free-floating зоны) числа нет
+	Closed   bool // станция не выдаёт велосипеды
+	Err      bool
+}
+
+var (
+	bikesMu        sync.Mutex
+	bikesFeeds     map[string]string
+	bikesInfo      map[string]string // station_id → название
+	bikesElectric  map[string]bool   // vehicle_type_id с мотором
+	bikesInfoAt    time.Time
+	bikesLast      []BikeStationStatus
+	bikesLastFetch time.Time
+)
+
+// refreshBikesInfo — gbfs.json, станции и типы велосипедов; под bikesMu.
+func refreshBikesInfo(ctx context.Context) error {
+	now := clock.Now()
+	if bikesInfo != nil && now.Sub(bikesInfoAt) < bikesInfoMaxAge {
+		return nil
+	}
+
+	var disc gbfsDiscovery
+	if err := getJSON(ctx, bikesGBFS, nil, &disc); err != nil {
+		return fmt.Errorf("gbfs.json: %w", err)
+	}
+	feeds := disc.feeds()
+
+	var info gbfsStationInformation
+	if err := gbfsJSON(ctx, feeds, "station_information", &info); err != nil {
+		return fmt.Errorf("station_information: %w", err)
+	}
+	stations := map[string]string{}
+	for _, s := range info.Data.Stations {
+		stations[s.StationID] = string(s.Name)
+	}
+
+	// vehicle_types необязателен: без него электро считаем только по
+	// num_ebikes_available
+	electric := map[string]bool{}
+	var types gbfsVehicleTypes
+	if _, ok := feeds["vehicle_types"]; ok {
+		if err := gbfsJSON(ctx, feeds, "vehicle_types", &types); err == nil {
+			for _, t := range types.Data.VehicleTypes {
+				electric[t.VehicleTypeID] = strings.HasPrefix(t.PropulsionType, "electric")
+			}
+		} else {
+			log.Println("gbfs: vehicle_types:", err)
+		}
+	}
+
+	bikesFeeds, bikesInfo, bikesElectric, bikesInfoAt = feeds, stations, electric, now
+	return nil
+}
+
+// stationID — station_id по id или названию станции.
+func (s bikeStation) stationID() (string, bool) {
+	if _, ok := bikesInfo[s.ID]; ok {
+		return s.ID, true
+	}
+	for id, name := range bikesInfo {
+		if strings.EqualFold(name, s.ID) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func loadBikes(ctx context.Context) ([]BikeStationStatus, error) {
+	bikesMu.Lock()
+	defer bikesMu.Unlock()
+
+	now := clock.Now()
+	if bikesLast != nil && now.Sub(bikesLastFetch) < bikesStatusMaxAge {
+		return bikesLast, nil
+	}
+
+	if err := refreshBikesInfo(ctx); err != nil {
+		return nil, err
+	}
+	var status gbfsStationStatus
+	if err := gbfsJSON(ctx, bikesFeeds, "station_status", &status); err != nil {
+		return nil, fmt.Errorf("station_status: %w", err)
+	}
+	byID := map[string]int{}
+	for i, s := range status.Data.Stations {
+		byID[s.StationID] = i
+	}
+
+	var out []BikeStationStatus
+	for _, st := range bikesStations {
+		row := BikeStationStatus{Label: st.Label}
+		id, ok := st.stationID()
+		if row.Label == "" {
+			row.Label = st.ID
+			if ok {
+				row.Label = bikesInfo[id]
+			}
+		}
+		i, found := byID[id]
+		if !ok || !found {
+			log.Printf("gbfs: station %q not found", st.ID)
+			row.Err = true
+			out = append(out, row)
+			continue
+		}
+		s := status.Data.Stations[i]
+		switch {
+		case s.NumBikes != nil:
+			row.Bikes = *s.NumBikes
+		case s.NumVehicles != nil:
+			row.Bikes = *s.NumVehicles
+		}
+		if s.NumEBikes != nil {
+			row.EBikes = *s.NumEBikes
+		} else {
+			for _, t := range s.Types {
+				if bikesElectric[t.VehicleTypeID] {
+					row.EBikes += t.Count
+				}
+			}
+		}
+		if s.NumDocks != nil {
+			row.Docks, row.HasDocks = *s.NumDocks, true
+		}
+		row.Closed = s.IsRenting != nil && !*s.IsRenting
+		out = append(out, row)
+	}
+
+	bikesLast, bikesLastFetch = out, now
+	return out, nil
+}
+
+// bikesForTransport — строки станций для табло отправлений; сбой GBFS
+// табло не ломает, станции просто помечаются.
+func bikesForTransport(ctx context.Context) []BikeStationStatus {
+	if !bikesConfigured() {
+		return nil
+	}
+	rows, err := loadBikes(ctx)
+	if err != nil {
+		log.Println("bikes:", err)
+		rows = nil
+		for _, st := range bikesStations {
+			label := st.Label
+			if label == "" {
+				label = st.ID
+			}
+			rows = append(rows, BikeStationStatus{Label: label, Err: true})
+		}
+	}
+	return rows
+}
+
+// Regular — свободные велосипеды без мотора.
+func (s BikeStationStatus) Regular() int {
+	return max(0, s.Bikes-s.EBikes)
+}
+
+//
+// ---------- ICONS ----------
+//
+
+// bikeIcon — велосипед; электро — с молнией в раме.
+func bikeIcon(electric bool) iconBitmap {
+	var b iconBitmap
+	wheel := func(cx, cy float64) {
+		b.plot(func(x, y float64) bool {
+			d := math.Hypot(x-cx, y-cy)
+			return d <= 5 && d >= 3.5
+		}, true)
+	}
+	wheel(5.5, 17)
+	wheel(18.5, 17)
+	// Рама: задние перья, подседельная, верхняя и нижняя трубы, вилка
+	b.line(5.5, 17, 11, 17, 1.5)
+	b.line(5.5, 17, 9, 10, 1.5)
+	b.line(11, 17, 9, 10, 1.5)
+	b.line(9, 10, 16, 10, 1.5)
+	b.line(11, 17, 16, 10, 1.5)
+	b.line(16, 10, 18.5, 17, 1.5)
+	// Седло и руль
+	b.line(9, 10, 8.5, 7.5, 1.5)
+	b.rect(6, 6, 11, 7.5)
+	b.line(16, 10, 15, 6.5, 1.5)
+	b.rect(13, 5, 17, 6.5)
+	if electric {
+		// Молния в правом верхнем углу
+		var bolt iconBitmap
+		bolt.polygon([2]float64{20, 0}, [2]float64{17, 5}, [2]float64{19.5, 5},
+			[2]float64{18.5, 9}, [2]float64{23, 3.5}, [2]float64{20.5, 3.5}, [2]float64{22, 0})
+		b.overlay(bolt)
+	}
+	return b
+}
+
+// dockIcon — стойка с замком.
+func dockIcon() iconBitmap {
+	var b iconBitmap
+	b.rect(3, 21, 21, 23)
+	b.rect(10, 6, 14, 21)
+	b.rect(7, 3, 17, 7)
+	b.plot(func(x, y float64) bool { return x >= 11 && x < 13 && y >= 4 && y < 6 }, false)
+	return b
+}
+
+// bikeIcons — иконки для шаблона табло.
+type bikeIcons struct {
+	Bike, EBike, Dock template.HTML
+}
+
+func bikeIconsSVG() bikeIcons {
+	return bikeIcons{
+		Bike:  bikeIcon(false).SVG(1),
+		EBike: bikeIcon(true).SVG(1),
+		Dock:  dockIcon().SVG(1),
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gbfsServer отдаёт testdata/gbfs/<версия>/<фид>.json; адреса фидов в
+// gbfs.json записаны с хостом gbfs.test и подменяются на адрес сервера.
+func gbfsServer(t *testing.T, requests *atomic.Int32) string {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			requests.Add(1)
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "gbfs", filepath.FromSlash(r.URL.Path)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.ReplaceAll(string(data), "http://gbfs.test", srv.URL)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// useBikes начинает тест без закэшированных станций.
+func useBikes(t *testing.T, gbfs, stations string) {
+	t.Helper()
+	swap(t, &bikesGBFS, gbfs)
+	swap(t, &bikesStations, parseBikeStations(stations))
+	swap(t, &bikesFeeds, nil)
+	swap(t, &bikesInfo, nil)
+	swap(t, &bikesElectric, nil)
+	swap(t, &bikesInfoAt, time.Time{})
+	swap(t, &bikesLast, nil)
+	swap(t, &bikesLastFetch, time.Time{})
+}
+
+func TestLoadBikes(t *testing.T) {
+	useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	srv := gbfsServer(t, nil)
+
+	tests := []struct {
+		name     string
+		gbfs     string
+		stations string
+		want     []BikeStationStatus
+	}{
+		{
+			name:     "v2",
+			gbfs:     srv + "/v2/gbfs.json",
+			stations: "Bahnhof=1001; Universitätsstraße; Rathaus=1003; Nirgendwo=9999",
+			want: []BikeStationStatus{
+				// num_ebikes_available — расширение nextbike
+				{Label: "Bahnhof", Bikes: 5, EBikes: 2, Docks: 7, HasDocks: true},
+				// Найдена по названию, подпись — название станции
+				{Label: "Universitätsstraße", Docks: 12, HasDocks: true, Closed: true},
+				// Есть в station_information, нет в station_status
+				{Label: "Rathaus", Err: true},
+				{Label: "Nirgendwo", Err: true},
+			},
+		},
+		{
+			name:     "v3",
+			gbfs:     srv + "/v3/gbfs.json",
+			stations: "st-1; Dom=domplatz",
+			want: []BikeStationStatus{
+				// Название из списка переводов, электро — по vehicle_types
+				{Label: "Hauptbahnhof", Bikes: 7, EBikes: 4, Docks: 5, HasDocks: true},
+				// Виртуальная станция без доков
+				{Label: "Dom", Bikes: 3, EBikes: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBikes(t, tt.gbfs, tt.stations)
+			got, err := loadBikes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("row %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// Статус живёт минуту, список станций — час.
+func TestLoadBikesCache(t *testing.T) {
+	fc := useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	var requests atomic.Int32
+	srv := gbfsServer(t, &requests)
+	useBikes(t, srv+"/v3/gbfs.json", "st-1")
+
+	steps := []struct {
+		advance time.Duration
+		want    int32 // запросов всего
+	}{
+		{0, 4},                // gbfs.json, vehicle_types, station_information, station_status
+		{30 * time.Second, 4}, // из кэша
+		{time.Minute, 5},      // только station_status
+		{time.Hour, 9},        // всё заново
+	}
+	for _, s := range steps {
+		fc.Advance(s.advance)
+		if _, err := loadBikes(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := requests.Load(); got != s.want {
+			t.Errorf("after +%s: %d requests, want %d", s.advance, got, s.want)
+		}
+	}
+}
+
+func TestLoadBikesErrors(t *testing.T) {
+	useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	srv := gbfsServer(t, nil)
+
+	tests := []struct {
+		gbfs    string
+		wantErr string
+	}{
+		{srv + "/v4/gbfs.json", "gbfs.json: status: 404 Not Found"},
+		// В discovery нет station_information
+		{srv + "/v2/station_status.json", "station_information: gbfs: no station_information feed"},
+	}
+	for _, tt := range tests {
+		useBikes(t, tt.gbfs, "1001")
+		_, err := loadBikes(context.Background())
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: err = %v, want %q", tt.gbfs, err, tt.wantErr)
+		}
+	}
+}
@@ -6,29 +6,90 @@ import (
 )
 
 var (
-	transportCache CachedImage
-	quoteCache     CachedImage
-	weatherCache   CachedImage
-	photoCache     CachedImage
-	stocksCache    CachedImage
-	calendarCache  CachedImage
+	transportCache  CachedImage
+	quoteCache      CachedImage
+	photoCache      CachedImage
+	stocksCache     CachedImage
+	calendarCache   CachedImage
+	airQualityCache CachedImage
+	warningsCache   CachedImage
+	wasteCache      CachedImage
+
+	weatherPages CachedPages
 )
 
-type CachedImage struct {
-	mu        sync.RWMutex
+// Картинки хранятся по языкам. Если на языке запроса картинки нет
+// (фото рендерится один раз), отдаётся вариант на языке по умолчанию.
+
+type cachedEntry struct {
 	data      []byte
 	updatedAt time.Time
 }
 
-func (c *CachedImage) Set(data []byte) {
+type CachedImage struct {
+	mu     sync.RWMutex
+	byLang map[Lang]cachedEntry
+}
+
+func (c *CachedImage) Set(lang Lang, data []byte) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.data = data
-	c.updatedAt = time.Now()
+	if c.byLang == nil {
+		c.byLang = map[Lang]cachedEntry{}
+	}
+	c.byLang[lang] = cachedEntry{data: data, updatedAt: clock.Now()}
 }
 
-func (c *CachedImage) Get() ([]byte, time.Time) {
+func (c *CachedImage) Get(lang Lang) ([]byte, time.Time) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.data, c.updatedAt
+	e, ok := c.byLang[lang]
+	if !ok {
+		e = c.byLang[defaultLang]
+	}
+	return e.data, e.updatedAt
+}
+
+// CachedPages — несколько страниц одного виджета, например погода по местам.
+type CachedPages struct {
+	mu     sync.RWMutex
+	byLang map[Lang]cachedPages
+}
+
+type cachedPages struct {
+	pages     [][]byte
+	updatedAt time.Time
+}
+
+func (c *CachedPages) Set(lang Lang, pages [][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byLang == nil {
+		c.byLang = map[Lang]cachedPages{}
+	}
+	c.byLang[lang] = cachedPages{pages: pages, updatedAt: clock.Now()}
+}
+
+func (c *CachedPages) Get(lang Lang, i int) ([]byte, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p := c.pagesFor(lang)
+	if i < 0 || i >= len(p.pages) {
+		return nil, p.updatedAt
+	}
+	return p.pages[i], p.updatedAt
+}
+
+func (c *CachedPages) Len(lang Lang) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.pagesFor(lang).pages)
+}
+
+func (c *CachedPages) pagesFor(lang Lang) cachedPages {
+	p, ok := c.byLang[lang]
+	if !ok {
+		p = c.byLang[defaultLang]
+	}
+	return p
 }
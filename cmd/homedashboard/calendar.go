@@ -4,124 +4,320 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	ics "github.com/arran4/golang-ical"
 )
 
 type CalendarEvent struct {
+	UID      string
+	Calendar string // подпись календаря-источника
+	Style    calendarStyle
 	Start    time.Time
 	End      time.Time
 	Summary  string
 	Location string
 	AllDay   bool
+	// Кусок многодневного события в режиме "daily": день Part из Parts
+	Part, Parts int
+	// День рождения или годовщина из контактов; Years — сколько исполняется
+	Anniversary anniversaryKind
+	Years       int
 }
 
+// Многодневные события: "range" — одной строкой с датами начала и конца,
+// "daily" — отдельной строкой в каждом дне.
+var calendarMultiDay = envString("DASHBOARD_CALENDAR_MULTIDAY", "range")
+
 type calendarPageData struct {
-	Range   string
-	Columns [][]CalendarEvent
+	Lang      Lang
+	Range     string
+	Calendars []calendar // легенда, если календарей больше одного
+	Columns   [][]CalendarEvent
+	More      int             // сколько событий не влезло в список
+	Birthdays []CalendarEvent // дни рождения и годовщины на неделю вперёд
+	Days      []calendarDay
+	Hours     []int // подписи сетки недели
+	Weeks     [][]calendarDay
 }
 
-func loadWeekEventsFromICS(periodStart time.Time, periodEnd time.Time) ([]CalendarEvent, error) {
-	resp, err := http.Get("https://calendar.google.com/calendar/ical/family12077548807296936472%40group.calendar.google.com/private-3e7a703aaa3f1462d0d9c2bf7faa0a9a/basic.ics")
-	if err != nil {
-		return nil, err
+// eventsFromICS — события ленты, пересекающиеся с окном [from, to), с
+// развёрнутыми повторами. Изменённые повторы (VEVENT с RECURRENCE-ID)
+// заменяют свой исходный экземпляр, отменённые — убирают его.
+func eventsFromICS(cal *ics.Calendar, from, to time.Time) []CalendarEvent {
+	var masters []*ics.VEvent
+	overrides := map[string]map[int64]*ics.VEvent{} // UID → исходное начало → замена
+	for _, e := range cal.Events() {
+		p := e.GetProperty(ics.ComponentPropertyRecurrenceId)
+		if p == nil {
+			masters = append(masters, e)
+			continue
+		}
+		ts, _, err := icsTimes(p)
+		if err != nil || len(ts) == 0 {
+			log.Printf("calendar %s: RECURRENCE-ID: %v", e.Id(), err)
+			continue
+		}
+		if overrides[e.Id()] == nil {
+			overrides[e.Id()] = map[int64]*ics.VEvent{}
+		}
+		overrides[e.Id()][ts[0].Unix()] = e
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var events []CalendarEvent
+	for _, e := range masters {
+		events = append(events, expandEvent(e, overrides[e.Id()], from, to)...)
 	}
-
-	cal, err := ics.ParseCalendar(bytes.NewReader(body))
-	if err != nil {
-		return nil, err
+	// Замены показываем по их собственному времени: перенесённый повтор
+	// может попасть в окно, даже если исходный был вне его.
+	for _, byStart := range overrides {
+		for _, e := range byStart {
+			if ev, ok := eventFromVEvent(e); ok && ev.overlaps(from, to) {
+				events = append(events, ev.inDisplayZone())
+			}
+		}
 	}
+	return events
+}
 
-	var events []CalendarEvent
-	loc, _ := time.LoadLocation("Europe/Berlin")
-	for _, e := range cal.Events() {
-		start, _ := e.GetStartAt()
-		end, _ := e.GetEndAt()
+// eventFromVEvent — одно событие как есть, без повторов. Время остаётся в
+// поясе TZID: повторы считаются по его настенным часам.
+func eventFromVEvent(e *ics.VEvent) (CalendarEvent, bool) {
+	if p := e.GetProperty(ics.ComponentPropertyStatus); p != nil && strings.EqualFold(p.Value, "CANCELLED") {
+		return CalendarEvent{}, false
+	}
+	p := e.GetProperty(ics.ComponentPropertyDtStart)
+	if p == nil {
+		return CalendarEvent{}, false
+	}
+	starts, _, err := icsTimes(p)
+	if err != nil || len(starts) == 0 {
+		log.Printf("calendar %s: DTSTART: %v", e.Id(), err)
+		return CalendarEvent{}, false
+	}
 
-		if start.IsZero() {
-			continue
+	ev := CalendarEvent{
+		UID:    e.Id(),
+		Start:  starts[0],
+		End:    starts[0],
+		AllDay: isAllDay(p),
+	}
+	if p := e.GetProperty(ics.ComponentPropertyDtEnd); p != nil {
+		if ends, _, err := icsTimes(p); err == nil && len(ends) > 0 {
+			ev.End = ends[0]
 		}
-
-		// фильтруем только события в ближайшую неделю
-		if end.Before(periodStart) || start.After(periodEnd) {
-			continue
+	} else if p := e.GetProperty(ics.ComponentPropertyDuration); p != nil {
+		if days, d, err := parseICSDuration(p.Value); err == nil {
+			ev.End = ev.Start.AddDate(0, 0, days).Add(d)
 		}
+	} else if ev.AllDay {
+		ev.End = ev.Start.AddDate(0, 0, 1) // RFC 5545: дата без конца — один день
+	}
 
-		summary := e.GetProperty(ics.ComponentPropertySummary)
-		location := e.GetProperty(ics.ComponentPropertyLocation)
+	if p := e.GetProperty(ics.ComponentPropertySummary); p != nil {
+		ev.Summary = p.Value
+	}
+	if p := e.GetProperty(ics.ComponentPropertyLocation); p != nil {
+		ev.Location = p.Value
+	}
+	return ev, true
+}
+
+// expandEvent разворачивает RRULE/RDATE минус EXDATE и экземпляры, у
+// которых есть замена.
+func expandEvent(e *ics.VEvent, overrides map[int64]*ics.VEvent, from, to time.Time) []CalendarEvent {
+	base, ok := eventFromVEvent(e)
+	if !ok {
+		return nil
+	}
 
-		ev := CalendarEvent{
-			Start:    start.In(loc),
-			End:      end.In(loc),
-			Summary:  "",
-			Location: "",
-			AllDay:   isAllDay(e),
+	starts := []time.Time{base.Start}
+	if p := e.GetProperty(ics.ComponentPropertyRrule); p != nil {
+		rule, err := parseRRule(p.Value, base.Start.Location())
+		if err != nil {
+			log.Printf("calendar %s: %v", base.UID, err)
+		} else {
+			starts = rule.occurrences(base.Start, to)
 		}
-		if summary != nil {
-			ev.Summary = summary.Value
+	}
+	for _, p := range e.GetProperties(ics.ComponentPropertyRdate) {
+		if ts, _, err := icsTimes(p); err == nil {
+			starts = append(starts, ts...)
+		}
+	}
+
+	skip := map[int64]bool{}
+	for _, p := range e.GetProperties(ics.ComponentPropertyExdate) {
+		if ts, _, err := icsTimes(p); err == nil {
+			for _, t := range ts {
+				skip[t.Unix()] = true
+			}
 		}
-		if location != nil {
-			ev.Location = location.Value
+	}
+	for t := range overrides {
+		skip[t] = true
+	}
+
+	// Длительность — в календарных днях плюс остаток, чтобы многодневные
+	// события через перевод часов заканчивались в то же время суток.
+	days := int(dayOf(base.End, base.End.Location()).Sub(dayOf(base.Start, base.Start.Location())).Hours()+12) / 24
+	rest := base.End.Sub(base.Start.AddDate(0, 0, days))
+
+	var events []CalendarEvent
+	for _, start := range starts {
+		if skip[start.Unix()] {
+			continue
 		}
+		skip[start.Unix()] = true // RDATE может повторять дату из RRULE
 
-		events = append(events, ev)
+		ev := base
+		ev.Start = start
+		ev.End = start.AddDate(0, 0, days).Add(rest)
+		if ev.overlaps(from, to) {
+			events = append(events, ev.inDisplayZone())
+		}
 	}
+	return events
+}
 
-	// сортируем по времени начала
-	sort.Slice(events, func(i, j int) bool {
-		return events[i].Start.Before(events[j].Start)
-	})
+// overlaps — пересекается ли событие с окном [from, to). Событие без
+// длительности попадает в окно своим началом.
+func (e CalendarEvent) overlaps(from, to time.Time) bool {
+	return e.Start.Before(to) && (e.End.After(from) || !e.Start.Before(from))
+}
 
-	return events, nil
+func (e CalendarEvent) inDisplayZone() CalendarEvent {
+	e.Start, e.End = e.Start.In(displayZone), e.End.In(displayZone)
+	return e
 }
 
-func isAllDay(e *ics.VEvent) bool {
-	p := e.GetProperty(ics.ComponentPropertyDtStart)
-	if p == nil {
-		return false
+// isAllDay — DTSTART задан датой без времени: DTSTART;VALUE=DATE:20261020.
+func isAllDay(p *ics.IANAProperty) bool {
+	if v := p.ICalParameters["VALUE"]; len(v) > 0 {
+		return strings.EqualFold(v[0], "DATE")
 	}
+	return len(strings.TrimSpace(p.Value)) == 8
+}
 
-	v := p.Value
-	if v == "DATE" {
-		return true
+// MultiDay — событие заходит на следующий день. Конец целодневного
+// события в ICS не включается: DTEND следующего дня — это один день.
+func (e CalendarEvent) MultiDay() bool {
+	return dayOf(e.LastDay(), displayZone).After(dayOf(e.Start, displayZone))
+}
+
+// LastDay — последний день, который событие занимает.
+func (e CalendarEvent) LastDay() time.Time {
+	if e.AllDay {
+		return e.End.AddDate(0, 0, -1)
 	}
+	if e.End.After(e.Start) {
+		return e.End.Add(-time.Nanosecond)
+	}
+	return e.Start
+}
 
-	return false
+// splitMultiDay режет многодневные события на куски по дням внутри окна —
+// для режима, где событие видно в каждом своём дне. День, занятый
+// целиком, становится целодневным.
+func splitMultiDay(events []CalendarEvent, from, to time.Time) []CalendarEvent {
+	var out []CalendarEvent
+	for _, ev := range events {
+		if !ev.MultiDay() {
+			out = append(out, ev)
+			continue
+		}
+
+		first := dayOf(ev.Start, displayZone)
+		last := dayOf(ev.LastDay(), displayZone)
+		parts := int(last.Sub(first).Hours()+12)/24 + 1
+		for i := 0; i < parts; i++ {
+			day := first.AddDate(0, 0, i)
+			next := day.AddDate(0, 0, 1)
+			if !day.Before(to) || !next.After(from) {
+				continue
+			}
+
+			piece := ev
+			piece.Part, piece.Parts = i+1, parts
+			if piece.Start.Before(day) {
+				piece.Start = day
+			}
+			if piece.End.After(next) {
+				piece.End = next
+			}
+			piece.AllDay = ev.AllDay || piece.Start.Equal(day) && piece.End.Equal(next)
+			if piece.AllDay {
+				piece.Start, piece.End = day, next
+			}
+			out = append(out, piece)
+		}
+	}
+	return out
 }
 
-func renderCalendarBMP(ctx context.Context) ([]byte, error) {
-	now := time.Now()
-	loc := now.Location()
-	periodStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-	periodEnd := periodStart.AddDate(0, 1, 0)
+func renderCalendarBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	layout := calendarSchedule.at(nowInMinutes())
+	periodStart, periodEnd := layout.period(displayToday())
 
-	events, err := loadWeekEventsFromICS(periodStart, periodEnd)
+	key := "calendar " + periodStart.Format(time.DateOnly) + " " + periodEnd.Format(time.DateOnly)
+	events, err := cycleLoad(ctx, key, func() ([]CalendarEvent, error) {
+		return loadCalendarEvents(ctx, periodStart, periodEnd)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("unable load events: %w", err)
 	}
+	// Дни рождения не занимают места в списке: у них своя строка внизу
+	events = slices.DeleteFunc(slices.Clone(events), func(ev CalendarEvent) bool {
+		return ev.Anniversary != ""
+	})
+	today := displayToday()
+	birthdays, _ := cycleLoad(ctx, "anniversaries "+today.Format(time.DateOnly), func() ([]CalendarEvent, error) {
+		return loadAnniversaries(ctx, today, today.AddDate(0, 0, anniversaryDays)), nil
+	})
+	// Общий на все языки список не трогаем: подписи у каждого свои
+	birthdays = slices.Clone(birthdays)
+	localizeAnniversaries(birthdays, lang)
 
 	data := calendarPageData{
-		Range:   periodStart.Format("02.01.") + " – " + periodEnd.AddDate(0, 0, -1).Format("02.01."),
-		Columns: buildColumnsFixed(events, 2, 5),
+		Lang:      lang,
+		Range:     lang.Date(periodStart, "day") + " – " + lang.Date(periodEnd.AddDate(0, 0, -1), "day"),
+		Birthdays: birthdays,
+	}
+	if len(calendarSources) > 1 {
+		data.Calendars = calendarSources
+	}
+	switch layout {
+	case layoutAgenda:
+		data.Days = agendaDays(events, periodStart, periodEnd)
+	case layoutWeek:
+		data.Days, data.Hours = weekGrid(events, periodStart, periodEnd)
+	case layoutMonth:
+		data.Range = lang.Date(periodStart, "month_year")
+		data.Weeks = monthGrid(events, periodStart, periodEnd)
+	default:
+		if calendarMultiDay == "daily" {
+			events = splitMultiDay(events, periodStart, periodEnd)
+			sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+		}
+		perCol := 5
+		if len(birthdays) > 0 {
+			perCol = 4 // одна строка уходит под дни рождения
+		}
+		data.Columns, data.More = buildColumnsFixed(events, 2, perCol)
 	}
 
 	var buf bytes.Buffer
-	if err := calendarTpl.Execute(&buf, data); err != nil {
+	if err := calendarTpls[layout].Execute(&buf, data); err != nil {
 		return nil, fmt.Errorf("unable render calendar template: %w", err)
 	}
 	htmlStr := buf.String()
 
-	png, err := htmlToBMP(htmlStr)
+	png, err := htmlToBMP(htmlStr, lang)
 	if err != nil {
 		return nil, fmt.Errorf("unable render calendar bmp: %w", err)
 	}
@@ -129,15 +325,19 @@ func renderCalendarBMP(ctx context.Context) ([]byte, error) {
 	return png, nil
 }
 
-func buildColumnsFixed(events []CalendarEvent, colCount, perCol int) [][]CalendarEvent {
+// buildColumnsFixed раскладывает события по колонкам. Что не влезло, не
+// пропадает молча: последнее место занимает строка «+N», а N возвращается.
+func buildColumnsFixed(events []CalendarEvent, colCount, perCol int) ([][]CalendarEvent, int) {
 	cols := make([][]CalendarEvent, colCount)
 	if len(events) == 0 {
-		return cols
+		return cols, 0
 	}
 
+	more := 0
 	maximum := colCount * perCol
 	if len(events) > maximum {
-		events = events[:maximum]
+		more = len(events) - (maximum - 1)
+		events = events[:maximum-1]
 	}
 
 	colIdx := 0
@@ -158,7 +358,7 @@ func buildColumnsFixed(events []CalendarEvent, colCount, perCol int) [][]Calenda
 		}
 	}
 
-	return cols
+	return cols, more
 }
 
 func handleCalendarBMP(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCalDAV — коллекция в духе Nextcloud/SabreDAV: Digest с qop=auth,
+// sync-token на PROPFIND, calendar-query, sync-collection и multiget.
+type fakeCalDAV struct {
+	t          *testing.T
+	user, pass string
+	realm      string
+
+	mu       sync.Mutex
+	nonce    string
+	token    int
+	objects  map[string]fakeObject // href → объект
+	changed  map[string]int        // href → токен, с которого изменён/удалён
+	requests []string              // "PROPFIND", "REPORT calendar-query" и т.п.
+	refused  int                   // сколько раз ответили 401
+}
+
+type fakeObject struct {
+	etag, ics string
+}
+
+func newFakeCalDAV(t *testing.T) *fakeCalDAV {
+	return &fakeCalDAV{
+		t: t, user: "anna", pass: "app-passwort", realm: "SabreDAV",
+		nonce: "nonce-1", token: 1,
+		objects: map[string]fakeObject{}, changed: map[string]int{},
+	}
+}
+
+const fakeCollection = "/remote.php/dav/calendars/anna/familie/"
+
+func (s *fakeCalDAV) put(name, etag, summary, start string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	href := fakeCollection + name
+	s.objects[href] = fakeObject{etag: etag, ics: strings.Join([]string{
+		"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Sabre//Sabre VObject 4.5.4//EN",
+		"BEGIN:VEVENT", "UID:" + name, "DTSTAMP:20261001T000000Z",
+		"DTSTART;TZID=Europe/Berlin:" + start, "SUMMARY:" + summary,
+		"END:VEVENT", "END:VCALENDAR", "",
+	}, "\r\n")}
+	s.token++
+	s.changed[href] = s.token
+}
+
+func (s *fakeCalDAV) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	href := fakeCollection + name
+	delete(s.objects, href)
+	s.token++
+	s.changed[href] = s.token
+}
+
+func (s *fakeCalDAV) log() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.requests
+	s.requests = nil
+	return out
+}
+
+func (s *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if stale, ok := s.authorized(r); !ok {
+		s.refused++
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Digest realm="%s", qop="auth,auth-int", nonce="%s", opaque="op4qu3", algorithm=MD5, stale=%v`,
+			s.realm, s.nonce, stale))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != fakeCollection {
+		http.NotFound(w, r)
+		return
+	}
+
+	kind := r.Method
+	if r.Method == "REPORT" {
+		for _, k := range []string{"calendar-query", "sync-collection", "calendar-multiget"} {
+			if strings.Contains(string(body), "<c:"+k) || strings.Contains(string(body), "<d:"+k) {
+				kind += " " + k
+			}
+		}
+	}
+	s.requests = append(s.requests, kind)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>` + "\n" +
+		`<d:multistatus xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns" xmlns:cal="urn:ietf:params:xml:ns:caldav">`)
+	switch kind {
+	case "PROPFIND":
+		if r.Header.Get("Depth") != "0" {
+			s.t.Errorf("PROPFIND with Depth %q", r.Header.Get("Depth"))
+		}
+		fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop>`+
+			`<d:sync-token>http://sabre.io/ns/sync/%d</d:sync-token>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, fakeCollection, s.token)
+	case "REPORT calendar-query":
+		for _, href := range slices.Sorted(maps.Keys(s.objects)) {
+			s.writeObject(&b, href)
+		}
+	case "REPORT calendar-multiget":
+		for _, part := range strings.Split(string(body), "<d:href>")[1:] {
+			href, _, _ := strings.Cut(part, "</d:href>")
+			if _, ok := s.objects[href]; ok {
+				s.writeObject(&b, href)
+			} else {
+				fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, href)
+			}
+		}
+	case "REPORT sync-collection":
+		_, rest, _ := strings.Cut(string(body), "<d:sync-token>http://sabre.io/ns/sync/")
+		var since int
+		fmt.Sscanf(rest, "%d", &since)
+		if since < 1 || since > s.token {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<d:error xmlns:d="DAV:"><d:valid-sync-token/></d:error>`)
+			return
+		}
+		for _, href := range slices.Sorted(maps.Keys(s.changed)) {
+			if s.changed[href] <= since {
+				continue
+			}
+			if obj, ok := s.objects[href]; ok {
+				fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop>`+
+					`<d:getetag>%s</d:getetag></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+					href, obj.etag)
+			} else {
+				fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, href)
+			}
+		}
+		fmt.Fprintf(&b, `<d:sync-token>http://sabre.io/ns/sync/%d</d:sync-token>`, s.token)
+	default:
+		s.t.Errorf("unexpected request %s", kind)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	b.WriteString(`</d:multistatus>`)
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+func (s *fakeCalDAV) writeObject(b *strings.Builder, href string) {
+	obj := s.objects[href]
+	fmt.Fprintf(b, `<d:response><d:href>%s</d:href><d:propstat><d:prop>`+
+		`<d:getetag>%s</d:getetag><cal:calendar-data>%s</cal:calendar-data>`+
+		`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+		href, obj.etag, xmlEscape(obj.ics))
+}
+
+// authorized проверяет Digest по RFC 7616 (MD5, qop=auth). stale — ответ
+// посчитан верно, но по старому nonce.
+func (s *fakeCalDAV) authorized(r *http.Request) (stale, ok bool) {
+	scheme, params, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if scheme != "Digest" {
+		return false, false
+	}
+	p := map[string]string{}
+	for _, kv := range strings.Split(params, ", ") {
+		k, v, _ := strings.Cut(kv, "=")
+		p[k] = strings.Trim(v, `"`)
+	}
+	md := func(parts ...string) string {
+		sum := md5.Sum([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(sum[:])
+	}
+	ha1 := md(s.user, s.realm, s.pass)
+	ha2 := md(r.Method, p["uri"])
+	want := md(ha1, p["nonce"], p["nc"], p["cnonce"], p["qop"], ha2)
+	if p["username"] != s.user || p["qop"] != "auth" || p["opaque"] != "op4qu3" ||
+		p["uri"] != r.URL.RequestURI() || p["response"] != want {
+		return false, false
+	}
+	if p["nonce"] != s.nonce {
+		return true, false
+	}
+	return false, true
+}
+
+func TestCalDAVSync(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &displayZone, berlinLoc)
+
+	dav := newFakeCalDAV(t)
+	dav.put("arzt.ics", `"e1"`, "Zahnarzt", "20261020T090000")
+	dav.put("turnen.ics", `"e2"`, "Kinderturnen", "20261021T160000")
+	srv := httptest.NewServer(dav)
+	defer srv.Close()
+
+	src, err := newCalDAVSource("caldav+" + strings.Replace(srv.URL, "http://", "http://anna:app-passwort@", 1) + fakeCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(src.Name(), "app-passwort") {
+		t.Errorf("password leaks into name %q", src.Name())
+	}
+
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, berlinLoc)
+	to := from.AddDate(0, 0, 7)
+	summaries := func() []string {
+		t.Helper()
+		evs, err := src.Events(context.Background(), from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, ev := range evs {
+			out = append(out, ev.Start.Format("01-02 15:04")+" "+ev.Summary)
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	steps := []struct {
+		name     string
+		change   func()
+		want     []string
+		requests []string
+		refused  int
+	}{
+		{
+			// Basic отвергнут, дальше Digest; токен — до calendar-query
+			name:     "initial load",
+			want:     []string{"10-20 09:00 Zahnarzt", "10-21 16:00 Kinderturnen"},
+			requests: []string{"PROPFIND", "REPORT calendar-query"},
+			refused:  1,
+		},
+		{
+			name:     "nothing changed",
+			want:     []string{"10-20 09:00 Zahnarzt", "10-21 16:00 Kinderturnen"},
+			requests: []string{"REPORT sync-collection"},
+		},
+		{
+			name: "changed and deleted",
+			change: func() {
+				dav.put("arzt.ics", `"e3"`, "Zahnarzt (verschoben)", "20261022T110000")
+				dav.remove("turnen.ics")
+				dav.put("elternabend.ics", `"e4"`, "Elternabend", "20261023T193000")
+			},
+			want:     []string{"10-22 11:00 Zahnarzt (verschoben)", "10-23 19:30 Elternabend"},
+			requests: []string{"REPORT sync-collection", "REPORT calendar-multiget"},
+		},
+		{
+			// Новый токен из ответа: старые изменения второй раз не приходят
+			name:     "token reused",
+			want:     []string{"10-22 11:00 Zahnarzt (verschoben)", "10-23 19:30 Elternabend"},
+			requests: []string{"REPORT sync-collection"},
+		},
+		{
+			// Сервер сменил nonce — повтор с новым без ошибки
+			name:     "stale nonce",
+			change:   func() { dav.mu.Lock(); dav.nonce = "nonce-2"; dav.mu.Unlock() },
+			want:     []string{"10-22 11:00 Zahnarzt (verschoben)", "10-23 19:30 Elternabend"},
+			requests: []string{"REPORT sync-collection"},
+			refused:  1,
+		},
+		{
+			// Токен больше не действует — полная перезагрузка окна
+			name: "token invalidated",
+			change: func() {
+				dav.mu.Lock()
+				dav.token = 0
+				dav.mu.Unlock()
+				dav.put("arzt.ics", `"e5"`, "Zahnarzt", "20261022T110000")
+			},
+			want:     []string{"10-22 11:00 Zahnarzt", "10-23 19:30 Elternabend"},
+			requests: []string{"REPORT sync-collection", "PROPFIND", "REPORT calendar-query"},
+		},
+	}
+
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+		dav.mu.Lock()
+		dav.refused = 0
+		dav.mu.Unlock()
+
+		if got := summaries(); !slices.Equal(got, step.want) {
+			t.Errorf("%s: events %q, want %q", step.name, got, step.want)
+		}
+		if got := dav.log(); !slices.Equal(got, step.requests) {
+			t.Errorf("%s: requests %q, want %q", step.name, got, step.requests)
+		}
+		if dav.refused != step.refused {
+			t.Errorf("%s: %d unauthorized responses, want %d", step.name, dav.refused, step.refused)
+		}
+	}
+}
+
+func TestDAVClientWrongPassword(t *testing.T) {
+	dav := newFakeCalDAV(t)
+	srv := httptest.NewServer(dav)
+	defer srv.Close()
+
+	_, client, err := newDAVClient(strings.Replace(srv.URL, "http://", "http://anna:falsch@", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.do(context.Background(), "PROPFIND", srv.URL+fakeCollection, "0", caldavTokenBody)
+	if err == nil || !strings.Contains(err.Error(), "status 401") && !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("err = %v, want unauthorized", err)
+	}
+	// Basic и один Digest — без бесконечных повторов
+	if dav.refused != 2 {
+		t.Errorf("%d unauthorized responses, want 2", dav.refused)
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	tests := []struct {
+		header string
+		want   digestChallenge
+	}{
+		{
+			`realm="SabreDAV", qop="auth,auth-int", nonce="abc", opaque="xyz", algorithm=MD5`,
+			digestChallenge{Realm: "SabreDAV", Nonce: "abc", Opaque: "xyz", Algorithm: "MD5", QOP: "auth"},
+		},
+		{
+			`realm="Radicale", nonce="n, with comma", algorithm=SHA-256, stale=TRUE`,
+			digestChallenge{Realm: "Radicale", Nonce: "n, with comma", Algorithm: "SHA-256", Stale: true},
+		},
+		{
+			`realm="x", qop="auth-int", nonce="n"`,
+			digestChallenge{Realm: "x", Nonce: "n"},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseDigestChallenge(tt.header); *got != tt.want {
+			t.Errorf("parseDigestChallenge(%q) = %+v, want %+v", tt.header, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVCards(t *testing.T) {
+	got := parseVCards(readTestdata(t, "contacts/family.vcf"))
+	want := []contactDate{
+		{Name: "Anna Schmidt", Kind: kindBirthday, Year: 1986, Month: time.October, Day: 21},
+		{Name: "Ben Becker", Kind: kindAnniversary, Year: 2016, Month: time.October, Day: 24},
+		{Name: "Ben Becker", Kind: kindBirthday, Month: time.February, Day: 29},
+		{Name: "Carla, die Katze", Kind: kindBirthday, Month: time.October, Day: 19},
+		{Name: "Dora Müller", Kind: kindBirthday, Year: 1990, Month: time.March, Day: 15},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func useContactsZone(t *testing.T) *time.Location {
+	t.Helper()
+	loc := berlin(t, 2026, 1, 1, 0, 0).Location()
+	swap(t, &displayZone, loc)
+	return loc
+}
+
+func TestContactsEvents(t *testing.T) {
+	loc := useContactsZone(t)
+	useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, loc))
+	src, err := newContactsSource(filepath.Join("testdata", "contacts", "family.vcf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{
+			name: "week",
+			from: time.Date(2026, 10, 18, 0, 0, 0, 0, loc),
+			to:   time.Date(2026, 10, 25, 0, 0, 0, 0, loc),
+			want: []string{
+				"birthday Anna Schmidt 2026-10-21 40",
+				"anniversary Ben Becker 2026-10-24 10",
+				"birthday Carla, die Katze 2026-10-19 0",
+			},
+		},
+		{
+			// 29 февраля в невисокосный год — 28-го
+			name: "leap day",
+			from: time.Date(2027, 2, 1, 0, 0, 0, 0, loc),
+			to:   time.Date(2027, 3, 1, 0, 0, 0, 0, loc),
+			want: []string{"birthday Ben Becker 2027-02-28 0"},
+		},
+		{
+			// До года рождения праздновать нечего
+			name: "before birth",
+			from: time.Date(1985, 10, 1, 0, 0, 0, 0, loc),
+			to:   time.Date(1985, 11, 1, 0, 0, 0, 0, loc),
+			want: []string{"birthday Carla, die Katze 1985-10-19 0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := src.Events(context.Background(), tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ev := range events {
+				if !ev.AllDay || !ev.End.Equal(ev.Start.AddDate(0, 0, 1)) {
+					t.Errorf("%s: not a single all-day event: %v – %v", ev.Summary, ev.Start, ev.End)
+				}
+				got = append(got, fmt.Sprintf("%s %s %s %d", ev.Anniversary, ev.Summary, ev.Start.Format(time.DateOnly), ev.Years))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// Дни рождения берутся только из контактов, подписаны календарём и
+// отсортированы; недоступные контакты не мешают остальным.
+func TestLoadAnniversaries(t *testing.T) {
+	loc := useContactsZone(t)
+	useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, loc))
+	swap(t, &calendarSources, parseCalendarSpecs(
+		"Familie=testdata/calendar/weekly_byday_count.ics; "+
+			"Geburtstage=testdata/contacts/family.vcf|outlined; "+
+			"Alt=testdata/contacts/missing.vcf"))
+
+	from := time.Date(2026, 10, 18, 0, 0, 0, 0, loc)
+	events := loadAnniversaries(context.Background(), from, from.AddDate(0, 0, anniversaryDays))
+	localizeAnniversaries(events, langDE)
+
+	var got []string
+	for _, ev := range events {
+		got = append(got, fmt.Sprintf("%s %s %s %s", ev.Start.Format(time.DateOnly), ev.Calendar, ev.Style, ev.Summary))
+	}
+	want := []string{
+		"2026-10-19 Geburtstage outlined Geburtstag Carla, die Katze",
+		"2026-10-21 Geburtstage outlined Geburtstag Anna Schmidt (40)",
+		"2026-10-24 Geburtstage outlined Jahrestag Ben Becker (10.)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// Строка дней рождения есть во всех раскладках календаря.
+func TestCalendarTemplatesBirthdays(t *testing.T) {
+	loc := useContactsZone(t)
+	useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, loc))
+	from := time.Date(2026, 10, 18, 0, 0, 0, 0, loc)
+	birthdays := []CalendarEvent{{
+		Start: time.Date(2026, 10, 21, 0, 0, 0, 0, loc), End: time.Date(2026, 10, 22, 0, 0, 0, 0, loc),
+		AllDay: true, Summary: "Anna Schmidt", Anniversary: kindBirthday, Years: 40,
+	}}
+	localizeAnniversaries(birthdays, langDE)
+
+	layouts := map[calendarLayout]string{
+		layoutList:   "templates/calendar.html",
+		layoutAgenda: "templates/calendar_agenda.html",
+		layoutWeek:   "templates/calendar_week.html",
+		layoutMonth:  "templates/calendar_month.html",
+	}
+	for layout, file := range layouts {
+		t.Run(string(layout), func(t *testing.T) {
+			data := calendarPageData{Lang: langDE, Birthdays: birthdays}
+			data.Columns, data.More = buildColumnsFixed(nil, 2, 4)
+			data.Days = agendaDays(nil, from, from.AddDate(0, 0, 2))
+			data.Weeks = monthGrid(nil, time.Date(2026, 10, 1, 0, 0, 0, 0, loc), time.Date(2026, 11, 1, 0, 0, 0, 0, loc))
+
+			var buf bytes.Buffer
+			if err := template.Must(template.ParseFS(templateFS, file)).Execute(&buf, data); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), "Diese Woche:") || !strings.Contains(buf.String(), "Geburtstag Anna Schmidt (40)") {
+				t.Errorf("no birthdays line in %s", file)
+			}
+		})
+	}
+}
+
+// Адресная книга, которая не отвечает, держит рендер не дольше бюджета
+// ленты ICS.
+func TestContactsRenderBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for icsRenderBudget")
+	}
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	src, err := newContactsSource(srv.URL + "/family.vcf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = src.Events(context.Background(), time.Now(), time.Now().AddDate(0, 0, 7))
+	if err == nil {
+		t.Fatal("want timeout error")
+	}
+	if took := time.Since(start); took > icsRenderBudget+2*time.Second {
+		t.Errorf("took %v, budget %v", took, icsRenderBudget)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// ---------- LAYOUTS ----------
+//
+
+type calendarLayout string
+
+const (
+	layoutList   calendarLayout = "list"   // месяц списком в две колонки
+	layoutAgenda calendarLayout = "agenda" // сегодня и завтра
+	layoutWeek   calendarLayout = "week"   // 7 дней сеткой по часам
+	layoutMonth  calendarLayout = "month"  // месяц сеткой с точками
+)
+
+// Вид календаря — один или по расписанию, через запятую:
+//
+//	DASHBOARD_CALENDAR_LAYOUT="06:00-09:00=agenda, 18:00-22:00=week, month"
+//
+// Элемент без времени — вид на всё остальное время.
+var calendarSchedule = parseLayoutSchedule(envList("DASHBOARD_CALENDAR_LAYOUT", "list"))
+
+type layoutSlot struct {
+	From, To TimeOfDay
+	Layout   calendarLayout
+}
+
+type layoutSchedule struct {
+	Slots   []layoutSlot
+	Default calendarLayout
+}
+
+func parseCalendarLayout(s string) (calendarLayout, bool) {
+	switch l := calendarLayout(strings.ToLower(strings.TrimSpace(s))); l {
+	case layoutList, layoutAgenda, layoutWeek, layoutMonth:
+		return l, true
+	}
+	return "", false
+}
+
+func parseLayoutSchedule(items []string) layoutSchedule {
+	sched := layoutSchedule{Default: layoutList}
+	for _, item := range items {
+		span, name, timed := strings.Cut(item, "=")
+		if !timed {
+			name = span
+		}
+		layout, ok := parseCalendarLayout(name)
+		if !ok {
+			log.Printf("config: unknown calendar layout %q", name)
+			continue
+		}
+		if !timed {
+			sched.Default = layout
+			continue
+		}
+
+		from, to, err := parseClockSpan(span)
+		if err != nil {
+			log.Printf("config: calendar layout slot %q must be HH:MM-HH:MM=layout", item)
+			continue
+		}
+		sched.Slots = append(sched.Slots, layoutSlot{From: from, To: to, Layout: layout})
+	}
+	return sched
+}
+
+func (s layoutSchedule) at(now TimeOfDay) calendarLayout {
+	for _, slot := range s.Slots {
+		if now.Between(slot.From, slot.To) {
+			return slot.Layout
+		}
+	}
+	return s.Default
+}
+
+// period — окно событий для вида.
+func (l calendarLayout) period(today time.Time) (time.Time, time.Time) {
+	switch l {
+	case layoutAgenda:
+		return today, today.AddDate(0, 0, 2)
+	case layoutWeek:
+		return today, today.AddDate(0, 0, 7)
+	case layoutMonth:
+		first := today.AddDate(0, 0, 1-today.Day())
+		return first, first.AddDate(0, 1, 0)
+	}
+	return today, today.AddDate(0, 1, 0)
+}
+
+//
+// ---------- DAYS ----------
+//
+
+// calendarDay — один день в повестке, неделе или месяце.
+type calendarDay struct {
+	Date    time.Time
+	Today   bool
+	InMonth bool            // для месяца: день не из соседнего месяца
+	AllDay  []CalendarEvent // целодневные
+	Events  []CalendarEvent // со временем
+	Blocks  []calendarBlock // для недели: события, разложенные по часам
+	More    int             // сколько не поместилось
+}
+
+// calendarBlock — событие в сетке недели; координаты в процентах колонки.
+type calendarBlock struct {
+	Event       CalendarEvent
+	Top, Height float64
+	Left, Width float64
+}
+
+const (
+	agendaPerDay     = 6 // строк в колонке «сегодня/завтра»
+	weekAllDayPerDay = 2 // целодневных над сеткой недели
+	monthDotsPerDay  = 4
+	weekFirstHour    = 7 // сетка недели, если события не шире
+	weekLastHour     = 21
+)
+
+// groupByDays раскладывает события по дням [from, to): многодневные режутся
+// на дни, как в режиме "daily".
+func groupByDays(events []CalendarEvent, from, to time.Time) []calendarDay {
+	today := displayToday()
+	var days []calendarDay
+	index := map[string]int{}
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		index[d.Format(time.DateOnly)] = len(days)
+		days = append(days, calendarDay{Date: d, Today: d.Equal(today), InMonth: true})
+	}
+
+	for _, ev := range splitMultiDay(events, from, to) {
+		i, ok := index[ev.Start.In(displayZone).Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		if ev.AllDay {
+			days[i].AllDay = append(days[i].AllDay, ev)
+		} else {
+			days[i].Events = append(days[i].Events, ev)
+		}
+	}
+	for i := range days {
+		sort.SliceStable(days[i].Events, func(a, b int) bool {
+			return days[i].Events[a].Start.Before(days[i].Events[b].Start)
+		})
+	}
+	return days
+}
+
+// agendaDays — сегодня и завтра, лишнее уходит в счётчик «+N».
+func agendaDays(events []CalendarEvent, from, to time.Time) []calendarDay {
+	days := groupByDays(events, from, to)
+	for i := range days {
+		d := &days[i]
+		total := len(d.AllDay) + len(d.Events)
+		if total <= agendaPerDay {
+			continue
+		}
+		d.More = total - (agendaPerDay - 1) // одна строка уходит под «+N»
+		keep := agendaPerDay - 1
+		if len(d.AllDay) > keep {
+			d.AllDay, d.Events = d.AllDay[:keep], nil
+		} else {
+			d.Events = d.Events[:keep-len(d.AllDay)]
+		}
+	}
+	return days
+}
+
+// weekGrid — дни недели с блоками событий и часы для подписей. Сетка
+// растягивается, если события выходят за обычные часы.
+func weekGrid(events []CalendarEvent, from, to time.Time) ([]calendarDay, []int) {
+	days := groupByDays(events, from, to)
+
+	first, last := weekFirstHour, weekLastHour
+	for _, d := range days {
+		for _, ev := range d.Events {
+			if ev.Parts > 0 {
+				continue // продолжения многодневных режутся по краю сетки
+			}
+			first = min(first, minuteOfDay(ev.Start, d.Date)/60)
+			last = max(last, (minuteOfDay(ev.End, d.Date)+59)/60)
+		}
+	}
+	var hours []int
+	for h := first; h < last; h++ {
+		hours = append(hours, h)
+	}
+
+	span := float64(last-first) * 60
+	for i := range days {
+		d := &days[i]
+		if len(d.AllDay) > weekAllDayPerDay {
+			d.More = len(d.AllDay) - (weekAllDayPerDay - 1)
+			d.AllDay = d.AllDay[:weekAllDayPerDay-1]
+		}
+		for _, lane := range assignLanes(d.Events) {
+			ev := lane.Event
+			// Куски многодневных за краем сетки прижимаются к краю, а не
+			// пропадают
+			top := min(max(0, float64(minuteOfDay(ev.Start, d.Date)-first*60)), span-30)
+			bottom := min(span, float64(minuteOfDay(ev.End, d.Date)-first*60))
+			if bottom <= top {
+				bottom = top + 30 // без длительности — полчаса, чтобы было видно
+			}
+			lane.Top = top / span * 100
+			lane.Height = (bottom - top) / span * 100
+			d.Blocks = append(d.Blocks, lane)
+		}
+	}
+	return days, hours
+}
+
+// minuteOfDay — минуты с полуночи дня по настенным часам (в дни перевода
+// часов сетка остаётся ровной); конец в следующих сутках — 24:00.
+func minuteOfDay(t, day time.Time) int {
+	if !t.Before(day.AddDate(0, 0, 1)) {
+		return 24 * 60
+	}
+	t = t.In(displayZone)
+	return t.Hour()*60 + t.Minute()
+}
+
+// assignLanes раскладывает пересекающиеся события по дорожкам: каждое
+// получает ширину 1/N своей группы пересечений.
+func assignLanes(events []CalendarEvent) []calendarBlock {
+	var blocks []calendarBlock
+	var group []int // индексы блоков текущей группы
+	var laneEnds []time.Time
+	var groupEnd time.Time
+
+	flush := func() {
+		for _, i := range group {
+			blocks[i].Width = 100 / float64(len(laneEnds))
+			blocks[i].Left *= blocks[i].Width
+		}
+		group, laneEnds = nil, nil
+	}
+
+	for _, ev := range events {
+		end := ev.End
+		if !end.After(ev.Start) {
+			end = ev.Start.Add(30 * time.Minute)
+		}
+		if len(group) > 0 && !ev.Start.Before(groupEnd) {
+			flush()
+		}
+
+		lane := -1
+		for l, le := range laneEnds {
+			if !ev.Start.Before(le) {
+				lane = l
+				break
+			}
+		}
+		if lane < 0 {
+			lane = len(laneEnds)
+			laneEnds = append(laneEnds, end)
+		}
+		laneEnds[lane] = end
+		if end.After(groupEnd) || len(group) == 0 {
+			groupEnd = end
+		}
+
+		group = append(group, len(blocks))
+		blocks = append(blocks, calendarBlock{Event: ev, Left: float64(lane)})
+	}
+	if len(group) > 0 {
+		flush()
+	}
+	return blocks
+}
+
+// monthGrid — недели с понедельника, покрывающие месяц [from, to).
+func monthGrid(events []CalendarEvent, from, to time.Time) [][]calendarDay {
+	gridFrom := from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	gridTo := to.AddDate(0, 0, (7-(int(to.Weekday())+6)%7)%7)
+
+	var weeks [][]calendarDay
+	days := groupByDays(events, gridFrom, gridTo)
+	for i := range days {
+		d := &days[i]
+		d.InMonth = !d.Date.Before(from) && d.Date.Before(to)
+		if n := len(d.AllDay) + len(d.Events); n > monthDotsPerDay {
+			d.More = n - monthDotsPerDay
+		}
+		if i%7 == 0 {
+			weeks = append(weeks, nil)
+		}
+		weeks[len(weeks)-1] = append(weeks[len(weeks)-1], *d)
+	}
+	return weeks
+}
+
+// Dots — события дня для точек месяца: сначала целодневные.
+func (d calendarDay) Dots() []CalendarEvent {
+	dots := append(append([]CalendarEvent{}, d.AllDay...), d.Events...)
+	if len(dots) > monthDotsPerDay {
+		dots = dots[:monthDotsPerDay]
+	}
+	return dots
+}
+
+// Empty — в дне ничего нет (для «Keine Termine» в повестке).
+func (d calendarDay) Empty() bool {
+	return len(d.AllDay) == 0 && len(d.Events) == 0 && d.More == 0
+}
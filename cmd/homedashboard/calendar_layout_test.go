@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// layoutZone — пояс дисплея и часы на понедельник 19 октября 2026.
+func layoutZone(t *testing.T) *time.Location {
+	t.Helper()
+	loc := berlin(t, 2026, 1, 1, 0, 0).Location()
+	swap(t, &displayZone, loc)
+	useFakeClock(t, time.Date(2026, 10, 19, 8, 0, 0, 0, loc))
+	return loc
+}
+
+// timed — событие со временем; день и часы в поясе дисплея.
+func timed(summary string, day, fromH, fromM int, dur time.Duration) CalendarEvent {
+	start := time.Date(2026, 10, day, fromH, fromM, 0, 0, displayZone)
+	return CalendarEvent{Summary: summary, Start: start, End: start.Add(dur)}
+}
+
+func allDay(summary string, day int) CalendarEvent {
+	start := time.Date(2026, 10, day, 0, 0, 0, 0, displayZone)
+	return CalendarEvent{Summary: summary, Start: start, End: start.AddDate(0, 0, 1), AllDay: true}
+}
+
+func blockString(b calendarBlock) string {
+	return fmt.Sprintf("%s left=%.0f width=%.0f", b.Event.Summary, b.Left, b.Width)
+}
+
+func TestAssignLanes(t *testing.T) {
+	layoutZone(t)
+	tests := []struct {
+		name   string
+		events []CalendarEvent
+		want   []string
+	}{
+		{
+			name:   "single",
+			events: []CalendarEvent{timed("A", 19, 9, 0, time.Hour)},
+			want:   []string{"A left=0 width=100"},
+		},
+		{
+			name: "overlapping",
+			events: []CalendarEvent{
+				timed("A", 19, 9, 0, time.Hour),
+				timed("B", 19, 9, 30, time.Hour),
+			},
+			want: []string{"A left=0 width=50", "B left=50 width=50"},
+		},
+		{
+			// C пересекается только с B и занимает освободившуюся дорожку A,
+			// но ширина общая на всю цепочку
+			name: "chain",
+			events: []CalendarEvent{
+				timed("A", 19, 9, 0, time.Hour),
+				timed("B", 19, 9, 30, time.Hour),
+				timed("C", 19, 10, 0, time.Hour),
+			},
+			want: []string{"A left=0 width=50", "B left=50 width=50", "C left=0 width=50"},
+		},
+		{
+			name: "three at once",
+			events: []CalendarEvent{
+				timed("A", 19, 9, 0, 2*time.Hour),
+				timed("B", 19, 9, 0, time.Hour),
+				timed("C", 19, 10, 30, time.Hour),
+				timed("D", 19, 10, 45, 15*time.Minute),
+			},
+			want: []string{"A left=0 width=33", "B left=33 width=33", "C left=33 width=33", "D left=67 width=33"},
+		},
+		{
+			// Конец одного — начало другого: не пересекаются
+			name: "back to back",
+			events: []CalendarEvent{
+				timed("A", 19, 9, 0, time.Hour),
+				timed("B", 19, 10, 0, time.Hour),
+			},
+			want: []string{"A left=0 width=100", "B left=0 width=100"},
+		},
+		{
+			// Без длительности событие занимает полчаса
+			name: "zero length",
+			events: []CalendarEvent{
+				timed("A", 19, 10, 0, 0),
+				timed("B", 19, 10, 15, time.Hour),
+				timed("C", 19, 12, 0, 0),
+				timed("D", 19, 12, 30, 0),
+			},
+			want: []string{"A left=0 width=50", "B left=50 width=50", "C left=0 width=100", "D left=0 width=100"},
+		},
+		{
+			name: "gap starts new group",
+			events: []CalendarEvent{
+				timed("A", 19, 9, 0, time.Hour),
+				timed("B", 19, 9, 0, time.Hour),
+				timed("C", 19, 14, 0, time.Hour),
+			},
+			want: []string{"A left=0 width=50", "B left=50 width=50", "C left=0 width=100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, b := range assignLanes(tt.events) {
+				got = append(got, blockString(b))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Сетка недели растягивается под ранние и поздние события, но не под
+// продолжения ночных; куски за краем прижимаются к краю.
+func TestWeekGrid(t *testing.T) {
+	loc := layoutZone(t)
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, loc)
+	events := []CalendarEvent{
+		timed("Zahnarzt", 19, 9, 0, time.Hour),
+		timed("Telefonat", 19, 9, 30, time.Hour),
+		timed("Frühschicht", 20, 6, 30, 30*time.Minute),
+		timed("Kino", 21, 21, 0, 75*time.Minute),
+		timed("Erinnerung", 22, 12, 0, 0),
+		timed("Nachtzug", 23, 23, 30, 90*time.Minute),
+		allDay("Ferien", 24),
+		allDay("Flohmarkt", 24),
+		allDay("Geburtstag", 24),
+	}
+	days, hours := weekGrid(events, from, from.AddDate(0, 0, 7))
+
+	if hours[0] != 6 || hours[len(hours)-1] != 22 || len(hours) != 17 {
+		t.Errorf("hours %v, want 6…22", hours)
+	}
+	var got []string
+	for _, d := range days {
+		for _, b := range d.Blocks {
+			got = append(got, fmt.Sprintf("%s %s top=%.2f height=%.2f left=%.0f width=%.0f",
+				d.Date.Format("01-02"), b.Event.Summary, b.Top, b.Height, b.Left, b.Width))
+		}
+		if d.More > 0 || len(d.AllDay) > 0 {
+			got = append(got, fmt.Sprintf("%s all-day %d +%d", d.Date.Format("01-02"), len(d.AllDay), d.More))
+		}
+	}
+	// Сетка 6:00–23:00, 1020 минут
+	want := []string{
+		"10-19 Zahnarzt top=17.65 height=5.88 left=0 width=50",
+		"10-19 Telefonat top=20.59 height=5.88 left=50 width=50",
+		"10-20 Frühschicht top=2.94 height=2.94 left=0 width=100",
+		"10-21 Kino top=88.24 height=7.35 left=0 width=100",
+		"10-22 Erinnerung top=35.29 height=2.94 left=0 width=100",
+		"10-23 Nachtzug top=97.06 height=2.94 left=0 width=100", // 23:30 — у нижнего края
+		"10-24 Nachtzug top=0.00 height=2.94 left=0 width=100",  // до 1:00 — у верхнего
+		"10-24 all-day 1 +2",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !days[0].Today || days[1].Today {
+		t.Error("today is not marked on Monday")
+	}
+}
+
+func TestWeekGridDefaultHours(t *testing.T) {
+	loc := layoutZone(t)
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, loc)
+	_, hours := weekGrid([]CalendarEvent{timed("Zahnarzt", 19, 9, 0, time.Hour)}, from, from.AddDate(0, 0, 7))
+	if hours[0] != weekFirstHour || hours[len(hours)-1] != weekLastHour-1 {
+		t.Errorf("hours %v, want %d…%d", hours, weekFirstHour, weekLastHour-1)
+	}
+}
+
+// В повестке «+N» занимает последнюю строку; сначала остаются целодневные.
+func TestAgendaDays(t *testing.T) {
+	loc := layoutZone(t)
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, loc)
+
+	var busy []CalendarEvent
+	busy = append(busy, allDay("Ferien", 19), allDay("Müll", 19))
+	for h := range 5 {
+		busy = append(busy, timed(fmt.Sprintf("T%d", h), 19, 16-h, 0, 30*time.Minute))
+	}
+	var allDays []CalendarEvent
+	for i := range 6 {
+		allDays = append(allDays, allDay(fmt.Sprintf("A%d", i), 20))
+	}
+	allDays = append(allDays, timed("Spät", 20, 20, 0, time.Hour))
+
+	tests := []struct {
+		name   string
+		events []CalendarEvent
+		want   []string // по дням: целодневные | со временем | +N
+	}{
+		{
+			name:   "empty",
+			events: nil,
+			want:   []string{"||+0 empty", "||+0 empty"},
+		},
+		{
+			// Два целодневных и пять со временем — семь на шесть строк;
+			// события по времени, а не по порядку в ленте
+			name:   "too many",
+			events: busy,
+			want:   []string{"Ferien Müll|T4 T3 T2|+2", "||+0 empty"},
+		},
+		{
+			name:   "all-day first",
+			events: allDays,
+			want:   []string{"||+0 empty", "A0 A1 A2 A3 A4||+2"},
+		},
+		{
+			name:   "exactly six",
+			events: busy[1:],
+			want:   []string{"Müll|T4 T3 T2 T1 T0|+0", "||+0 empty"},
+		},
+		{
+			// Ночное событие — в обоих днях
+			name:   "past midnight",
+			events: []CalendarEvent{timed("Nachtzug", 19, 23, 0, 2*time.Hour)},
+			want:   []string{"|Nachtzug|+0", "|Nachtzug|+0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := agendaDays(tt.events, today, today.AddDate(0, 0, 2))
+			var got []string
+			for _, d := range days {
+				s := summaries(d.AllDay) + "|" + summaries(d.Events) + fmt.Sprintf("|+%d", d.More)
+				if d.Empty() {
+					s += " empty"
+				}
+				got = append(got, s)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func summaries(events []CalendarEvent) string {
+	var s []string
+	for _, ev := range events {
+		s = append(s, ev.Summary)
+	}
+	return strings.Join(s, " ")
+}
+
+// Месяц — целые недели с понедельника; дни соседних месяцев помечены.
+func TestMonthGrid(t *testing.T) {
+	loc := layoutZone(t)
+	tests := []struct {
+		name        string
+		month       time.Time
+		weeks       int
+		first, last string
+		outside     int
+	}{
+		// 1 октября 2026 — четверг, 31-е — суббота
+		{"october", time.Date(2026, 10, 1, 0, 0, 0, 0, loc), 5, "2026-09-28", "2026-11-01", 4},
+		// Февраль 2027 начинается в понедельник и кончается в воскресенье
+		{"february", time.Date(2027, 2, 1, 0, 0, 0, 0, loc), 4, "2027-02-01", "2027-02-28", 0},
+		// 1 ноября 2026 — воскресенье: неделя с 26 октября
+		{"november", time.Date(2026, 11, 1, 0, 0, 0, 0, loc), 6, "2026-10-26", "2026-12-06", 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weeks := monthGrid(nil, tt.month, tt.month.AddDate(0, 1, 0))
+			if len(weeks) != tt.weeks {
+				t.Fatalf("%d weeks, want %d", len(weeks), tt.weeks)
+			}
+			outside := 0
+			for _, w := range weeks {
+				if len(w) != 7 || w[0].Date.Weekday() != time.Monday {
+					t.Errorf("week from %s has %d days", w[0].Date.Format(time.DateOnly), len(w))
+				}
+				for _, d := range w {
+					if !d.InMonth {
+						outside++
+					}
+				}
+			}
+			first, last := weeks[0][0].Date, weeks[len(weeks)-1][6].Date
+			if first.Format(time.DateOnly) != tt.first || last.Format(time.DateOnly) != tt.last || outside != tt.outside {
+				t.Errorf("grid %s…%s with %d outside, want %s…%s with %d", first.Format(time.DateOnly),
+					last.Format(time.DateOnly), outside, tt.first, tt.last, tt.outside)
+			}
+		})
+	}
+}
+
+// Точки: не больше monthDotsPerDay, целодневные первыми, остальное — «+N».
+func TestMonthGridDots(t *testing.T) {
+	loc := layoutZone(t)
+	events := []CalendarEvent{
+		timed("T1", 21, 9, 0, time.Hour),
+		timed("T2", 21, 10, 0, time.Hour),
+		timed("T3", 21, 11, 0, time.Hour),
+		timed("T4", 21, 12, 0, time.Hour),
+		allDay("Ferien", 21),
+		timed("T5", 21, 13, 0, time.Hour),
+		timed("Nachtzug", 23, 23, 0, 2*time.Hour),
+	}
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, loc)
+	weeks := monthGrid(events, from, from.AddDate(0, 1, 0))
+
+	byDate := map[string]calendarDay{}
+	for _, w := range weeks {
+		for _, d := range w {
+			byDate[d.Date.Format("01-02")] = d
+		}
+	}
+	tests := []struct {
+		day  string
+		dots string
+		more int
+	}{
+		{"10-21", "Ferien T1 T2 T3", 2},
+		{"10-23", "Nachtzug", 0},
+		{"10-24", "Nachtzug", 0},
+		{"10-22", "", 0},
+	}
+	for _, tt := range tests {
+		d := byDate[tt.day]
+		if got := summaries(d.Dots()); got != tt.dots || d.More != tt.more {
+			t.Errorf("%s: dots %q +%d, want %q +%d", tt.day, got, d.More, tt.dots, tt.more)
+		}
+	}
+	if !byDate["10-19"].Today || byDate["10-20"].Today {
+		t.Error("today is not marked")
+	}
+}
+
+// В списке «+N» встаёт на место последнего события.
+func TestBuildColumnsFixed(t *testing.T) {
+	layoutZone(t)
+	tests := []struct {
+		events int
+		cols   string // событий по колонкам
+		more   int
+	}{
+		{0, "0 0", 0},
+		{3, "3 0", 0},
+		{7, "5 2", 0},
+		{10, "5 5", 0},
+		{11, "5 4", 2},
+		{25, "5 4", 16},
+	}
+	for _, tt := range tests {
+		var events []CalendarEvent
+		for i := range tt.events {
+			events = append(events, timed(fmt.Sprint(i), 19, 8, i, time.Minute))
+		}
+		cols, more := buildColumnsFixed(events, 2, 5)
+		if got := fmt.Sprintf("%d %d", len(cols[0]), len(cols[1])); got != tt.cols || more != tt.more {
+			t.Errorf("%d events: columns %s +%d, want %s +%d", tt.events, got, more, tt.cols, tt.more)
+		}
+	}
+}
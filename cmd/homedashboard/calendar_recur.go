@@ -0,0 +1,476 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+//
+// ---------- ICS DATES ----------
+//
+
+// icsTimes разбирает значение DTSTART/EXDATE/RDATE/RECURRENCE-ID: одно время
+// или список через запятую, с TZID, в UTC или «плавающее» (без пояса —
+// тогда по часам дисплея). dateOnly — значения без времени (VALUE=DATE),
+// они становятся полуночью в поясе дисплея.
+func icsTimes(p *ics.IANAProperty) (times []time.Time, dateOnly bool, err error) {
+	loc := displayZone
+	if tz := p.ICalParameters["TZID"]; len(tz) > 0 {
+		loc = icsLocation(tz[0])
+	}
+
+	for _, v := range strings.Split(p.Value, ",") {
+		v = strings.TrimSpace(v)
+		if before, _, ok := strings.Cut(v, "/"); ok {
+			v = before // RDATE;VALUE=PERIOD — берём только начало
+		}
+		t, isDate, err := parseICSValue(v, loc)
+		if err != nil {
+			return nil, false, err
+		}
+		times = append(times, t)
+		dateOnly = isDate
+	}
+	return times, dateOnly, nil
+}
+
+// Названия поясов, которые Outlook/Exchange пишут в TZID вместо IANA.
+var windowsZones = map[string]string{
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"GMT Standard Time":              "Europe/London",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Turkey Standard Time":           "Europe/Istanbul",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"UTC":                            "UTC",
+}
+
+var (
+	icsZonesMu sync.Mutex
+	icsZones   = map[string]*time.Location{}
+)
+
+// icsLocation находит пояс по TZID: IANA, имя Windows или путь вида
+// "/mozilla.org/20050126_1/Europe/Berlin". Непонятный пояс считаем поясом
+// дисплея — лучше час сдвига, чем пропавшее событие.
+func icsLocation(tzid string) *time.Location {
+	tzid = strings.Trim(strings.TrimSpace(tzid), `"`)
+
+	icsZonesMu.Lock()
+	defer icsZonesMu.Unlock()
+	if loc, ok := icsZones[tzid]; ok {
+		return loc
+	}
+
+	loc := displayZone
+	candidates := []string{tzid}
+	if name, ok := windowsZones[tzid]; ok {
+		candidates = []string{name}
+	}
+	for s := strings.Trim(tzid, "/"); strings.Contains(s, "/"); {
+		_, s, _ = strings.Cut(s, "/")
+		candidates = append(candidates, s)
+	}
+	found := false
+	for _, name := range candidates {
+		if l, err := time.LoadLocation(name); err == nil && name != "" {
+			loc, found = l, true
+			break
+		}
+	}
+	if !found {
+		log.Printf("calendar: unknown TZID %q, using %s", tzid, displayZone)
+	}
+	icsZones[tzid] = loc
+	return loc
+}
+
+func parseICSValue(v string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	switch {
+	case len(v) == 8:
+		t, err = time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	case strings.HasSuffix(v, "Z"):
+		t, err = time.Parse("20060102T150405Z", v)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", v, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("bad ics time %q", v)
+	}
+	return t, false, nil
+}
+
+var icsDurationRe = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration понимает DURATION вида P1W, P1D, PT1H30M. Дни и недели
+// возвращаются отдельно: их прибавляют по календарю, а не по 24 часа.
+func parseICSDuration(s string) (days int, d time.Duration, err error) {
+	m := icsDurationRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || s == "P" || s == "PT" {
+		return 0, 0, fmt.Errorf("bad ics duration %q", s)
+	}
+	n := func(i int) int {
+		v, _ := strconv.Atoi(m[i])
+		return v
+	}
+	days = n(2)*7 + n(3)
+	d = time.Duration(n(4))*time.Hour + time.Duration(n(5))*time.Minute + time.Duration(n(6))*time.Second
+	if m[1] == "-" {
+		days, d = -days, -d
+	}
+	return days, d, nil
+}
+
+//
+// ---------- RRULE ----------
+//
+
+// rrule — правило повторения из RFC 5545 в объёме, который встречается в
+// Google/Outlook/Nextcloud: DAILY…YEARLY с INTERVAL, COUNT, UNTIL, BYDAY
+// (в т.ч. «2MO», «-1FR»), BYMONTHDAY, BYMONTH, BYSETPOS и WKST.
+type rrule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []weekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// weekdayNum — элемент BYDAY: день недели и необязательный номер в месяце
+// или году (0 — каждый такой день).
+type weekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Предел на число перебираемых периодов — защита от правил, которые
+// никогда не дают дат (BYMONTH=2;BYMONTHDAY=30).
+const maxRecurPeriods = 50000
+
+var errUnsupportedRRule = errors.New("unsupported rrule")
+
+func parseRRule(s string, loc *time.Location) (rrule, error) {
+	r := rrule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(s, ";") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			var dateOnly bool
+			r.Until, dateOnly, err = parseICSValue(val, loc)
+			if dateOnly {
+				r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Nanosecond) // весь последний день
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				var wd weekdayNum
+				if wd, err = parseWeekdayNum(d); err != nil {
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(val)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(val)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(val)
+		case "WKST":
+			wd, ok := icsWeekdays[strings.ToUpper(val)]
+			if !ok {
+				err = fmt.Errorf("bad WKST %q", val)
+			}
+			r.WeekStart = wd
+		case "BYHOUR", "BYMINUTE", "BYSECOND", "BYYEARDAY", "BYWEEKNO":
+			return r, fmt.Errorf("%w: %s", errUnsupportedRRule, key)
+		}
+		if err != nil {
+			return r, fmt.Errorf("rrule %s: %w", key, err)
+		}
+	}
+
+	switch r.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return r, fmt.Errorf("%w: FREQ=%s", errUnsupportedRRule, r.Freq)
+	}
+	if r.Interval < 1 {
+		r.Interval = 1
+	}
+	return r, nil
+}
+
+func parseWeekdayNum(s string) (weekdayNum, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return weekdayNum{}, fmt.Errorf("bad BYDAY %q", s)
+	}
+	day, ok := icsWeekdays[s[len(s)-2:]]
+	if !ok {
+		return weekdayNum{}, fmt.Errorf("bad BYDAY %q", s)
+	}
+	wd := weekdayNum{Day: day}
+	if num := s[:len(s)-2]; num != "" {
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return weekdayNum{}, fmt.Errorf("bad BYDAY %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// occurrences — начала всех повторов от start (включительно) до to
+// (не включая). Повторы считаются по настенным часам пояса start, поэтому
+// встреча «каждый вторник в 9:00» остаётся в 9:00 и после перевода часов.
+//
+// По RFC 5545 DTSTART — всегда первый экземпляр и входит в COUNT, даже
+// если правилу не подходит (DTSTART в четверг при BYDAY=MO).
+func (r rrule) occurrences(start, to time.Time) []time.Time {
+	if !start.Before(to) {
+		return nil
+	}
+	out := []time.Time{start}
+	n := 1
+	if r.Count == 1 {
+		return out
+	}
+	for i := 0; i < maxRecurPeriods; i++ {
+		first, days := r.period(start, i)
+		if !first.Before(to) {
+			break
+		}
+		for _, day := range days {
+			t := time.Date(day.Year(), day.Month(), day.Day(),
+				start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			if !t.After(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) || !t.Before(to) {
+				return out
+			}
+			out = append(out, t)
+			if n++; r.Count > 0 && n >= r.Count {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// period возвращает первый день i-го периода и дни-кандидаты в нём
+// (по возрастанию, после BYSETPOS).
+func (r rrule) period(start time.Time, i int) (time.Time, []time.Time) {
+	y, m, d := start.Date()
+	loc := start.Location()
+	step := i * r.Interval
+
+	var first time.Time
+	var days []time.Time
+	switch r.Freq {
+	case "DAILY":
+		first = time.Date(y, m, d+step, 0, 0, 0, 0, loc)
+		if r.matchMonth(first) && r.matchMonthDay(first) && r.matchWeekday(first) {
+			days = []time.Time{first}
+		}
+	case "WEEKLY":
+		back := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		first = time.Date(y, m, d-back+7*step, 0, 0, 0, 0, loc)
+		for k := 0; k < 7; k++ {
+			day := first.AddDate(0, 0, k)
+			if len(r.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchWeekday(day) && r.matchMonth(day) {
+				days = append(days, day)
+			}
+		}
+	case "MONTHLY":
+		first = time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if r.matchMonth(first) {
+			days = r.monthDays(first, d)
+		}
+	case "YEARLY":
+		first = time.Date(y+step, time.January, 1, 0, 0, 0, 0, loc)
+		days = r.yearDays(first, m, d)
+	}
+	return first, r.applySetPos(days)
+}
+
+// monthDays — дни месяца по BYMONTHDAY/BYDAY, а без них — тот же день,
+// что у DTSTART (31-е в коротких месяцах пропускается, как в RFC).
+func (r rrule) monthDays(first time.Time, startDay int) []time.Time {
+	n := daysIn(first)
+	day := func(d int) time.Time { return first.AddDate(0, 0, d-1) }
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = n + md + 1
+			}
+			if md >= 1 && md <= n && r.matchWeekday(day(md)) {
+				days = append(days, day(md))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var all []time.Time
+			for d := 1; d <= n; d++ {
+				if day(d).Weekday() == wd.Day {
+					all = append(all, day(d))
+				}
+			}
+			days = append(days, pickNth(all, wd.N)...)
+		}
+	default:
+		if startDay <= n {
+			days = append(days, day(startDay))
+		}
+	}
+	return sortDays(days)
+}
+
+func (r rrule) yearDays(first time.Time, startMonth time.Month, startDay int) []time.Time {
+	loc := first.Location()
+	var days []time.Time
+	switch {
+	case len(r.ByMonth) > 0 || len(r.ByMonthDay) > 0:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{startMonth}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(time.Date(first.Year(), month, 1, 0, 0, 0, 0, loc), startDay)...)
+		}
+	case len(r.ByDay) > 0:
+		// BYDAY без BYMONTH — номер дня недели внутри года («20MO»)
+		for _, wd := range r.ByDay {
+			var all []time.Time
+			for day := first; day.Year() == first.Year(); day = day.AddDate(0, 0, 1) {
+				if day.Weekday() == wd.Day {
+					all = append(all, day)
+				}
+			}
+			days = append(days, pickNth(all, wd.N)...)
+		}
+	default:
+		month := time.Date(first.Year(), startMonth, 1, 0, 0, 0, 0, loc)
+		if startDay <= daysIn(month) { // 29 февраля — только в високосные
+			days = append(days, month.AddDate(0, 0, startDay-1))
+		}
+	}
+	return sortDays(days)
+}
+
+func (r rrule) matchMonth(t time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, t.Month())
+}
+
+func (r rrule) matchMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	n := daysIn(t)
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || md < 0 && n+md+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rrule) matchWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rrule) applySetPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	var out []time.Time
+	for _, pos := range r.BySetPos {
+		out = append(out, pickNth(days, pos)...)
+	}
+	return sortDays(out)
+}
+
+// pickNth — n-й элемент с начала (n > 0) или с конца (n < 0); 0 — все.
+func pickNth(days []time.Time, n int) []time.Time {
+	switch {
+	case n == 0:
+		return days
+	case n > 0 && n <= len(days):
+		return days[n-1 : n]
+	case n < 0 && -n <= len(days):
+		return days[len(days)+n : len(days)+n+1]
+	}
+	return nil
+}
+
+func sortDays(days []time.Time) []time.Time {
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(days, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
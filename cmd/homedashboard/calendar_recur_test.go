@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// Ленты в testdata/calendar разворачиваются целиком через eventsFromICS:
+// RRULE, EXDATE и замены экземпляров вместе.
+func TestEventsFromICSRecurrence(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &displayZone, berlinLoc)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, berlinLoc)
+	to := time.Date(2027, 1, 1, 0, 0, 0, 0, berlinLoc)
+
+	tests := []struct {
+		file string
+		want []string // начала в поясе дисплея
+	}{
+		{
+			file: "weekly_byday_count.ics",
+			want: []string{
+				"2026-10-05 18:00", "2026-10-07 18:00", "2026-10-12 18:00",
+				"2026-10-14 18:00", "2026-10-19 18:00",
+			},
+		},
+		{
+			file: "monthly_last_friday.ics",
+			want: []string{
+				"2026-01-30 16:00", "2026-02-27 16:00", "2026-03-27 16:00", "2026-04-24 16:00",
+				"2026-05-29 16:00", "2026-06-26 16:00", "2026-07-31 16:00", "2026-08-28 16:00",
+				"2026-09-25 16:00", "2026-10-30 16:00", "2026-11-27 16:00", "2026-12-25 16:00",
+			},
+		},
+		{
+			// Месяцы без 31-го пропускаются, а не сдвигаются на 1-е
+			file: "monthly_day31.ics",
+			want: []string{
+				"2026-01-31 00:00", "2026-03-31 00:00", "2026-05-31 00:00", "2026-07-31 00:00",
+				"2026-08-31 00:00", "2026-10-31 00:00", "2026-12-31 00:00",
+			},
+		},
+		{
+			// Через перевод часов — те же 9:00; UNTIL в UTC включительно
+			file: "interval_until.ics",
+			want: []string{
+				"2026-09-22 09:00", "2026-10-06 09:00", "2026-10-20 09:00", "2026-11-03 09:00",
+			},
+		},
+		{
+			// EXDATE входит в COUNT, перенесённый экземпляр — по своему времени
+			file: "exdate.ics",
+			want: []string{
+				"2026-10-19 07:00", "2026-10-20 07:00", "2026-10-22 08:00", "2026-10-23 07:00",
+			},
+		},
+		{
+			// RFC 5545: DTSTART — первый экземпляр, даже не попадая в BYDAY
+			file: "dtstart_not_byday.ics",
+			want: []string{
+				"2026-10-01 10:00", "2026-10-05 10:00", "2026-10-12 10:00",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			cal, err := ics.ParseCalendar(bytes.NewReader(readTestdata(t, "calendar/"+tt.file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ev := range eventsFromICS(cal, from, to) {
+				got = append(got, ev.Start.Format("2006-01-02 15:04"))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got  %s\nwant %s", strings.Join(got, ", "), strings.Join(tt.want, ", "))
+			}
+		})
+	}
+}
+
+// Окно режет развёрнутую серию, но COUNT считается от DTSTART, а не от
+// начала окна.
+func TestEventsFromICSWindow(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &displayZone, berlinLoc)
+
+	cal, err := ics.ParseCalendar(bytes.NewReader(readTestdata(t, "calendar/weekly_byday_count.ics")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 10, 13, 0, 0, 0, 0, berlinLoc)
+	to := time.Date(2026, 10, 26, 0, 0, 0, 0, berlinLoc)
+	var got []string
+	for _, ev := range eventsFromICS(cal, from, to) {
+		got = append(got, ev.Start.Format("01-02 15:04")+"–"+ev.End.Format("15:04"))
+	}
+	want := []string{"10-14 18:00–19:30", "10-19 18:00–19:30"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+//
+// ---------- SOURCES ----------
+//
+
+// Календари через «;»: подпись, адрес и стиль пометки на 1-битном экране.
+//
+//	DASHBOARD_CALENDARS="Familie=https://…/basic.ics|bold; Ferien=/etc/homedashboard/ferien.ics|hatched; Müll=webcal://…|outlined"
+//
+// Адрес — http(s)://, webcal://, путь к локальному .ics или коллекция
+// CalDAV со схемой caldav+https:// (см. calendar_caldav.go). Контакты
+// (.vcf, carddav+https://) дают дни рождения (calendar_contacts.go).
+var calendarSources = parseCalendarSpecs(envString("DASHBOARD_CALENDARS",
+	"Familie=https://calendar.google.com/calendar/ical/family12077548807296936472%40group.calendar.google.com/private-3e7a703aaa3f1462d0d9c2bf7faa0a9a/basic.ics"))
+
+// CalendarSource — один календарь. События отдаёт уже в поясе дисплея и
+// только пересекающиеся с окном [from, to).
+type CalendarSource interface {
+	Name() string
+	Events(ctx context.Context, from, to time.Time) ([]CalendarEvent, error)
+}
+
+// calendarStyle — чем помечены события календаря: цвета на экране нет,
+// поэтому календари различаются заливкой маркера.
+type calendarStyle string
+
+const (
+	styleBold     calendarStyle = "bold"     // залитый квадрат
+	styleOutlined calendarStyle = "outlined" // пустой квадрат
+	styleHatched  calendarStyle = "hatched"  // штриховка
+)
+
+func parseCalendarStyle(s string) calendarStyle {
+	switch st := calendarStyle(strings.ToLower(strings.TrimSpace(s))); st {
+	case styleBold, styleOutlined, styleHatched:
+		return st
+	case "":
+	default:
+		log.Printf("config: unknown calendar style %q, using bold", s)
+	}
+	return styleBold
+}
+
+// calendar — источник вместе с тем, как его показывать.
+type calendar struct {
+	Label  string
+	Style  calendarStyle
+	Source CalendarSource
+}
+
+func parseCalendarSpecs(s string) []calendar {
+	var cals []calendar
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		item, style, _ := strings.Cut(item, "|")
+		label, addr, ok := strings.Cut(item, "=")
+		if !ok {
+			label, addr = "", label
+		}
+		label, addr = strings.TrimSpace(label), strings.TrimSpace(addr)
+		if addr == "" {
+			log.Printf("config: calendar %q has no address", item)
+			continue
+		}
+		if label == "" {
+			label = fmt.Sprintf("#%d", len(cals)+1)
+		}
+		src, err := newCalendarSource(addr)
+		if err != nil {
+			log.Printf("config: calendar %s: %v", label, err)
+			continue
+		}
+		cals = append(cals, calendar{
+			Label:  label,
+			Style:  parseCalendarStyle(style),
+			Source: src,
+		})
+	}
+	return cals
+}
+
+func newCalendarSource(addr string) (CalendarSource, error) {
+	switch {
+	case strings.HasPrefix(addr, "caldav+"):
+		return newCalDAVSource(addr)
+	case strings.HasPrefix(addr, "carddav+"), strings.HasSuffix(strings.ToLower(addr), ".vcf"):
+		return newContactsSource(addr)
+	}
+	return &icsSource{Location: addr}, nil
+}
+
+//
+// ---------- ICS ----------
+//
+
+// Ленту не качаем на каждый рендер: разобранный календарь живёт
+// icsCacheTTL, потом переспрашивается с If-None-Match/If-Modified-Since.
+// Если лента не ответила, показываем последнюю удачную, а следующую
+// попытку откладываем: мёртвая лента не должна тормозить каждый рендер.
+const (
+	icsTimeout      = 15 * time.Second
+	icsRenderBudget = 5 * time.Second // на одну ленту за рендер
+	icsMaxBody      = 16 << 20
+	icsCacheTTL     = 5 * time.Minute
+	icsRetryMin     = time.Minute
+	icsRetryMax     = 30 * time.Minute
+)
+
+var icsClient = &http.Client{Timeout: icsTimeout}
+
+// icsSource — ICS-лента по ссылке или файл на диске.
+type icsSource struct {
+	Location string
+
+	mu           sync.Mutex
+	cal          *ics.Calendar
+	checked      time.Time // когда последний раз сверялись с лентой
+	retryAt      time.Time // после сбоя — не спрашивать раньше
+	failures     int       // сбоев подряд
+	lastErr      error
+	etag         string
+	lastModified string    // для ленты — заголовок как есть
+	modTime      time.Time // для файла
+}
+
+func (s *icsSource) Name() string { return redactLocation(s.Location) }
+
+func (s *icsSource) Events(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	cal, err := s.calendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return eventsFromICS(cal, from, to), nil
+}
+
+func (s *icsSource) calendar(ctx context.Context) (*ics.Calendar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := clock.Now()
+	if s.cal != nil && now.Sub(s.checked) < icsCacheTTL {
+		return s.cal, nil
+	}
+	if now.Before(s.retryAt) {
+		if s.cal == nil {
+			return nil, s.lastErr
+		}
+		return s.cal, nil
+	}
+
+	var err error
+	if u, remote := s.feedURL(); remote {
+		ctx, cancel := context.WithTimeout(ctx, icsRenderBudget)
+		err = s.fetch(ctx, u)
+		cancel()
+	} else {
+		err = s.readFile(u)
+	}
+	if err != nil {
+		// Пауза растёт вдвое с каждым сбоем подряд
+		s.failures++
+		s.lastErr = err
+		s.retryAt = now.Add(min(icsRetryMin<<(min(s.failures, 8)-1), icsRetryMax))
+		if s.cal == nil {
+			return nil, err
+		}
+		log.Printf("calendar %s: %v, using cached copy from %s", redactLocation(s.Location), err, s.checked.Format(time.DateTime))
+		return s.cal, nil
+	}
+	s.checked, s.failures, s.lastErr = now, 0, nil
+	return s.cal, nil
+}
+
+// feedURL — адрес для HTTP или путь к файлу.
+func (s *icsSource) feedURL() (string, bool) {
+	u := s.Location
+	if rest, ok := strings.CutPrefix(u, "webcal://"); ok {
+		u = "https://" + rest
+	}
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u, true
+	}
+	return strings.TrimPrefix(u, "file://"), false
+}
+
+func (s *icsSource) fetch(ctx context.Context, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if s.cal != nil {
+		if s.etag != "" {
+			req.Header.Set("If-None-Match", s.etag)
+		}
+		if s.lastModified != "" {
+			req.Header.Set("If-Modified-Since", s.lastModified)
+		}
+	}
+
+	resp, err := icsClient.Do(req)
+	if err != nil {
+		return redactURLError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && s.cal != nil:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	cal, err := parseICSLimited(resp.Body)
+	if err != nil {
+		return err
+	}
+	s.cal = cal
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	return nil
+}
+
+func (s *icsSource) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.cal != nil && st.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	cal, err := parseICSLimited(f)
+	if err != nil {
+		return err
+	}
+	s.cal, s.modTime = cal, st.ModTime()
+	return nil
+}
+
+// redactLocation — адрес ленты для лога. В приватных ссылках Google и
+// Nextcloud секрет сидит прямо в пути, поэтому от ссылки остаётся хост.
+func redactLocation(loc string) string {
+	u, err := url.Parse(loc)
+	switch {
+	case err != nil:
+		return "(invalid address)"
+	case u.Host == "":
+		return loc // файл на диске
+	}
+	return u.Scheme + "://" + u.Host + "/…"
+}
+
+// redactURLError убирает из ошибки http.Client полный адрес запроса.
+func redactURLError(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return fmt.Errorf("%s %s: %w", ue.Op, redactLocation(ue.URL), ue.Err)
+	}
+	return err
+}
+
+// parseICSLimited разбирает ленту не больше icsMaxBody: обрезанный
+// календарь хуже, чем ошибка.
+func parseICSLimited(r io.Reader) (*ics.Calendar, error) {
+	data, err := io.ReadAll(io.LimitReader(r, icsMaxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > icsMaxBody {
+		return nil, fmt.Errorf("feed larger than %d MB", icsMaxBody>>20)
+	}
+	cal, err := ics.ParseCalendar(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse ics: %w", err)
+	}
+	return cal, nil
+}
+
+//
+// ---------- MERGE ----------
+//
+
+// loadCalendarEvents собирает события всех календарей в одну ленту.
+// Упавший календарь пропускается; ошибка — только если не ответил никто.
+func loadCalendarEvents(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	var events []CalendarEvent
+	var errs []error
+	for _, cal := range calendarSources {
+		evs, err := cal.Source.Events(ctx, from, to)
+		if err != nil {
+			log.Printf("calendar %s: %v", cal.Label, err)
+			errs = append(errs, fmt.Errorf("%s: %w", cal.Label, err))
+			continue
+		}
+		for i := range evs {
+			evs[i].Calendar = cal.Label
+			evs[i].Style = cal.Style
+		}
+		events = append(events, evs...)
+	}
+	if len(errs) > 0 && len(errs) == len(calendarSources) {
+		return nil, errors.Join(errs...)
+	}
+
+	events = dedupEvents(events)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+// dedupEvents убирает одно и то же событие, пришедшее из нескольких
+// календарей (общий семейный и личный и т.п.). Остаётся вариант из
+// календаря, который в конфиге раньше.
+func dedupEvents(events []CalendarEvent) []CalendarEvent {
+	seen := map[string]bool{}
+	out := events[:0]
+	for _, ev := range events {
+		key := ev.dedupKey()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, ev)
+	}
+	return out
+}
+
+// dedupKey — UID и начало (у повторов одной серии UID общий), а без UID —
+// название и время.
+func (e CalendarEvent) dedupKey() string {
+	start := e.Start.UTC().Format(time.RFC3339)
+	if e.UID != "" {
+		return e.UID + "@" + start
+	}
+	return strings.ToLower(strings.TrimSpace(e.Summary)) + "@" + start + "/" + e.End.UTC().Format(time.RFC3339)
+}
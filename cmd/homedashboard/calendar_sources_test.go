@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// icsFeed — лента за httptest: отвечает 304 на совпавший ETag или
+// Last-Modified и запоминает условные заголовки запросов.
+type icsFeed struct {
+	mu       sync.Mutex
+	summary  string
+	version  int
+	status   int // не 0 — ответить этим кодом
+	body     []byte
+	requests []string
+}
+
+const icsFeedModified = "Sat, 17 Oct 2026 08:00:00 GMT"
+
+func (f *icsFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	etag := fmt.Sprintf(`"v%d"`, f.version)
+	f.requests = append(f.requests, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+	switch {
+	case f.status != 0:
+		http.Error(w, "down", f.status)
+		return
+	case f.body != nil:
+		w.Write(f.body)
+		return
+	case r.Header.Get("If-None-Match") == etag,
+		r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == icsFeedModified:
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", icsFeedModified)
+	fmt.Fprintf(w, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:test\r\n"+
+		"BEGIN:VEVENT\r\nUID:1@test\r\nDTSTART:20261020T100000Z\r\nDTEND:20261020T110000Z\r\nSUMMARY:%s\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n", f.summary)
+}
+
+func (f *icsFeed) set(fn func(f *icsFeed)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+// takeRequests — условные заголовки запросов с прошлого вызова.
+func (f *icsFeed) takeRequests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.requests
+	f.requests = nil
+	return r
+}
+
+// Кэш ленты: TTL, условный запрос с 304, паузы после сбоев, растущие
+// вдвое, и старая копия, пока лента молчит.
+func TestICSSourceCache(t *testing.T) {
+	fc := useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))
+	feed := &icsFeed{summary: "Elternabend", version: 1}
+	srv := httptest.NewServer(feed)
+	defer srv.Close()
+	src := &icsSource{Location: srv.URL + "/basic.ics"}
+
+	from := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	cond := `"v1"|` + icsFeedModified
+
+	steps := []struct {
+		name     string
+		advance  time.Duration
+		change   func(f *icsFeed)
+		requests []string
+		want     string
+	}{
+		{name: "first fetch", requests: []string{"|"}, want: "Elternabend"},
+		{name: "within TTL", advance: icsCacheTTL - time.Second, want: "Elternabend"},
+		{name: "not modified", advance: time.Second, requests: []string{cond}, want: "Elternabend"},
+		{
+			name:    "changed",
+			advance: icsCacheTTL,
+			change:  func(f *icsFeed) { f.summary, f.version = "Elternabend (verschoben)", 2 },
+			// ETag новый — сервер отдаёт ленту целиком
+			requests: []string{cond},
+			want:     "Elternabend (verschoben)",
+		},
+		{
+			name:     "down",
+			advance:  icsCacheTTL,
+			change:   func(f *icsFeed) { f.status = http.StatusBadGateway },
+			requests: []string{`"v2"|` + icsFeedModified},
+			want:     "Elternabend (verschoben)",
+		},
+		{name: "first pause", advance: icsRetryMin - time.Second, want: "Elternabend (verschoben)"},
+		{name: "second failure", advance: time.Second, requests: []string{`"v2"|` + icsFeedModified}, want: "Elternabend (verschoben)"},
+		{name: "pause doubled", advance: 2*icsRetryMin - time.Second, want: "Elternabend (verschoben)"},
+		{
+			name:     "back",
+			advance:  time.Second,
+			change:   func(f *icsFeed) { f.status = 0 },
+			requests: []string{`"v2"|` + icsFeedModified},
+			want:     "Elternabend (verschoben)",
+		},
+		{name: "TTL again", advance: icsCacheTTL - time.Second, want: "Elternabend (verschoben)"},
+	}
+	for _, s := range steps {
+		fc.Advance(s.advance)
+		if s.change != nil {
+			feed.set(s.change)
+		}
+		events, err := src.Events(context.Background(), from, to)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if len(events) != 1 || events[0].Summary != s.want {
+			t.Errorf("%s: events %+v, want %q", s.name, events, s.want)
+		}
+		if got := feed.takeRequests(); strings.Join(got, ",") != strings.Join(s.requests, ",") {
+			t.Errorf("%s: requests %q, want %q", s.name, got, s.requests)
+		}
+	}
+	if src.failures != 0 || src.lastErr != nil {
+		t.Errorf("after recovery failures=%d lastErr=%v", src.failures, src.lastErr)
+	}
+}
+
+// Пауза растёт вдвое и упирается в icsRetryMax; без старой копии
+// ошибка возвращается и во время паузы, но лента не дёргается.
+func TestICSSourceBackoff(t *testing.T) {
+	fc := useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))
+	feed := &icsFeed{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(feed)
+	defer srv.Close()
+	src := &icsSource{Location: srv.URL + "/basic.ics"}
+
+	var pauses []time.Duration
+	for range 7 {
+		if _, err := src.Events(context.Background(), fc.Now(), fc.Now().AddDate(0, 0, 1)); err == nil || err.Error() != "status 500" {
+			t.Fatalf("err = %v, want status 500", err)
+		}
+		pause := src.retryAt.Sub(fc.Now())
+		pauses = append(pauses, pause)
+
+		fc.Advance(pause - time.Second)
+		if _, err := src.Events(context.Background(), fc.Now(), fc.Now().AddDate(0, 0, 1)); err == nil {
+			t.Fatal("no error during pause")
+		}
+		fc.Advance(time.Second)
+	}
+	want := []time.Duration{
+		time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute,
+		16 * time.Minute, icsRetryMax, icsRetryMax,
+	}
+	if fmt.Sprint(pauses) != fmt.Sprint(want) {
+		t.Errorf("pauses %v, want %v", pauses, want)
+	}
+	if got := len(feed.takeRequests()); got != 7 {
+		t.Errorf("%d requests, want 7", got)
+	}
+}
+
+// Лента больше icsMaxBody — ошибка, а не обрезанный календарь.
+func TestICSSourceTooLarge(t *testing.T) {
+	useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))
+	feed := &icsFeed{body: bytes.Repeat([]byte("X"), icsMaxBody+1)}
+	srv := httptest.NewServer(feed)
+	defer srv.Close()
+
+	src := &icsSource{Location: srv.URL + "/huge.ics"}
+	_, err := src.Events(context.Background(), clock.Now(), clock.Now().AddDate(0, 0, 1))
+	if err == nil || err.Error() != "feed larger than 16 MB" {
+		t.Errorf("err = %v, want feed larger than 16 MB", err)
+	}
+}
+
+// Секрет из приватной ссылки не попадает ни в лог, ни в ошибку.
+func TestICSSourceRedactsLocation(t *testing.T) {
+	fc := useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))
+	feed := &icsFeed{summary: "Elternabend", version: 1}
+	srv := httptest.NewServer(feed)
+	const secret = "private-3e7a703aaa3f1462d0d9c2bf7faa0a9a"
+	src := &icsSource{Location: srv.URL + "/calendar/ical/family/" + secret + "/basic.ics"}
+	if _, err := src.Events(context.Background(), fc.Now(), fc.Now().AddDate(0, 0, 7)); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(prev) })
+
+	srv.Close() // соединение отклоняется: ошибка http.Client с адресом
+	fc.Advance(icsCacheTTL)
+	if _, err := src.Events(context.Background(), fc.Now(), fc.Now().AddDate(0, 0, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "using cached copy") {
+		t.Fatalf("no cached copy message in log %q", logs.String())
+	}
+	for what, s := range map[string]string{"log": logs.String(), "error": src.lastErr.Error(), "name": src.Name()} {
+		if strings.Contains(s, secret) {
+			t.Errorf("%s leaks the secret: %s", what, s)
+		}
+	}
+}
+
+func TestRedactLocation(t *testing.T) {
+	tests := map[string]string{
+		"https://calendar.google.com/calendar/ical/x%40group/private-abc/basic.ics": "https://calendar.google.com/…",
+		"webcal://example.org/feed.ics?token=abc":                                   "webcal://example.org/…",
+		"/etc/homedashboard/ferien.ics":                                             "/etc/homedashboard/ferien.ics",
+		"https://[::1/feed.ics":                                                     "(invalid address)",
+	}
+	for in, want := range tests {
+		if got := redactLocation(in); got != want {
+			t.Errorf("redactLocation(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
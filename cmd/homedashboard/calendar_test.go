@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// allDayZonesEvents — события testdata/calendar/allday_zones.ics за неделю
+// 19–26 октября 2026, по UID.
+func allDayZonesEvents(t *testing.T) (map[string]CalendarEvent, time.Time, time.Time) {
+	t.Helper()
+	loc := berlin(t, 2026, 1, 1, 0, 0).Location()
+	swap(t, &displayZone, loc)
+
+	cal, err := ics.ParseCalendar(bytes.NewReader(readTestdata(t, "calendar/allday_zones.ics")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, loc)
+	to := time.Date(2026, 10, 27, 0, 0, 0, 0, loc)
+	byUID := map[string]CalendarEvent{}
+	for _, ev := range eventsFromICS(cal, from, to) {
+		byUID[strings.TrimSuffix(ev.UID, "@example.org")] = ev
+	}
+	return byUID, from, to
+}
+
+func TestIsAllDay(t *testing.T) {
+	cal, err := ics.ParseCalendar(bytes.NewReader(readTestdata(t, "calendar/allday_zones.ics")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"value-date":     true, // DTSTART;VALUE=DATE:20261020
+		"bare-date":      true, // DTSTART:20261021 без VALUE
+		"range":          true,
+		"floating":       false,
+		"overnight":      false,
+		"utc":            false,
+		"iana":           false,
+		"windows":        false,
+		"windows-quoted": false,
+		"mozilla":        false,
+	}
+	for _, e := range cal.Events() {
+		uid := strings.TrimSuffix(e.Id(), "@example.org")
+		if got := isAllDay(e.GetProperty(ics.ComponentPropertyDtStart)); got != want[uid] {
+			t.Errorf("%s: isAllDay = %v, want %v", uid, got, want[uid])
+		}
+	}
+}
+
+// Время в поясе дисплея: плавающее — по его часам, TZID — IANA, имя
+// Windows (и в кавычках) или путь Mozilla. Конец целодневного события не
+// включается: DTEND 26-го — последний день 25-е.
+func TestEventsFromICSAllDayAndZones(t *testing.T) {
+	events, _, _ := allDayZonesEvents(t)
+	tests := []struct {
+		uid      string
+		want     string // начало–конец
+		multiDay bool
+		lastDay  string
+	}{
+		{"value-date", "10-20 00:00–10-21 00:00", false, "10-20"},
+		{"bare-date", "10-21 00:00–10-22 00:00", false, "10-21"},
+		{"range", "10-23 00:00–10-26 00:00", true, "10-25"},
+		{"floating", "10-22 19:30–10-22 21:00", false, "10-22"},
+		{"overnight", "10-24 22:00–10-25 02:00", true, "10-25"},
+		{"utc", "10-23 14:00–10-23 15:00", false, "10-23"},
+		{"iana", "10-22 15:00–10-22 16:00", false, "10-22"},
+		{"windows", "10-20 08:00–10-20 08:30", false, "10-20"},
+		{"windows-quoted", "10-26 17:00–10-26 18:00", false, "10-26"}, // у нас уже зимнее время, в США ещё летнее
+		{"mozilla", "10-21 11:00–10-21 12:00", false, "10-21"},
+	}
+	if len(events) != len(tests) {
+		t.Errorf("%d events, want %d", len(events), len(tests))
+	}
+	for _, tt := range tests {
+		ev, ok := events[tt.uid]
+		if !ok {
+			t.Errorf("%s: missing", tt.uid)
+			continue
+		}
+		got := ev.Start.Format("01-02 15:04") + "–" + ev.End.Format("01-02 15:04")
+		if got != tt.want || ev.MultiDay() != tt.multiDay || ev.LastDay().Format("01-02") != tt.lastDay {
+			t.Errorf("%s: %s multiDay=%v last=%s; want %s multiDay=%v last=%s", tt.uid,
+				got, ev.MultiDay(), ev.LastDay().Format("01-02"), tt.want, tt.multiDay, tt.lastDay)
+		}
+		if ev.Start.Location() != displayZone {
+			t.Errorf("%s: in %v, want display zone", tt.uid, ev.Start.Location())
+		}
+	}
+}
+
+func TestSplitMultiDay(t *testing.T) {
+	events, from, to := allDayZonesEvents(t)
+	tests := []struct {
+		name     string
+		uid      string
+		from, to time.Time
+		want     []string
+	}{
+		{
+			// Целодневные куски — целые дни, через перевод часов тоже
+			name: "all-day range", uid: "range", from: from, to: to,
+			want: []string{
+				"1/3 all-day 10-23 00:00–10-24 00:00",
+				"2/3 all-day 10-24 00:00–10-25 00:00",
+				"3/3 all-day 10-25 00:00–10-26 00:00",
+			},
+		},
+		{
+			name: "window cuts", uid: "range", from: from.AddDate(0, 0, 5), to: to,
+			want: []string{
+				"2/3 all-day 10-24 00:00–10-25 00:00",
+				"3/3 all-day 10-25 00:00–10-26 00:00",
+			},
+		},
+		{
+			name: "past midnight", uid: "overnight", from: from, to: to,
+			want: []string{
+				"1/2 timed 10-24 22:00–10-25 00:00",
+				"2/2 timed 10-25 00:00–10-25 02:00",
+			},
+		},
+		{
+			name: "single day untouched", uid: "floating", from: from, to: to,
+			want: []string{"0/0 timed 10-22 19:30–10-22 21:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range splitMultiDay([]CalendarEvent{events[tt.uid]}, tt.from, tt.to) {
+				kind := "timed"
+				if p.AllDay {
+					kind = "all-day"
+				}
+				got = append(got, fmt.Sprintf("%d/%d %s %s–%s", p.Part, p.Parts, kind,
+					p.Start.Format("01-02 15:04"), p.End.Format("01-02 15:04")))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// Подписи в списке: один целодневный день — «ganztägig», диапазон — по
+// последний день включительно.
+func TestCalendarListAllDayLabels(t *testing.T) {
+	events, _, _ := allDayZonesEvents(t)
+	var list []CalendarEvent
+	for _, uid := range []string{"value-date", "bare-date", "range", "overnight", "windows"} {
+		list = append(list, events[uid])
+	}
+	data := calendarPageData{Lang: langDE}
+	data.Columns, data.More = buildColumnsFixed(list, 2, 5)
+
+	var buf bytes.Buffer
+	if err := template.Must(template.ParseFS(templateFS, "templates/calendar.html")).Execute(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	html := strings.Join(strings.Fields(buf.String()), " ")
+	for _, want := range []string{
+		"Di 20.10.&nbsp;ganztägig",
+		"Mi 21.10.&nbsp;ganztägig",
+		"Fr 23.10. – So 25.10.",
+		"Di 20.10.&nbsp;08:00–08:30",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("no %q in\n%s", want, html)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+	_ "time/tzdata" // на Pi часто нет /usr/share/zoneinfo
+)
+
+//
+// ---------- CLOCK ----------
+//
+
+// Clock — единственный источник текущего времени для виджетов и расписания.
+// В тестах и при отладке страниц его подменяют на FakeClock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// FakeClock стоит на месте, пока его не передвинут.
+type FakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func NewFakeClock(t time.Time) *FakeClock { return &FakeClock{t: t} }
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+var (
+	// Часовой пояс, в котором показываем время, считаем «сегодня» и
+	// переключаем страницы по расписанию — независимо от TZ системы.
+	displayZone = loadDisplayZone(envString("DASHBOARD_TIMEZONE", "Europe/Berlin"))
+
+	// DASHBOARD_FAKE_NOW="2026-03-29T01:55" замораживает часы — удобно
+	// смотреть, как страницы выглядят ночью, в выходные или при переводе часов.
+	clock = newClock(envString("DASHBOARD_FAKE_NOW", ""))
+)
+
+func loadDisplayZone(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("config: time zone %q: %v, using %s", name, err, time.Local)
+		return time.Local
+	}
+	return loc
+}
+
+func newClock(fake string) Clock {
+	if fake == "" {
+		return systemClock{}
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04", fake, displayZone)
+	if err != nil {
+		log.Printf("config: DASHBOARD_FAKE_NOW %q: %v", fake, err)
+		return systemClock{}
+	}
+	log.Printf("clock frozen at %s", t)
+	return NewFakeClock(t)
+}
+
+// displayNow — текущее время в поясе дисплея.
+func displayNow() time.Time {
+	return clock.Now().In(displayZone)
+}
+
+// displayToday — полночь текущего дня в поясе дисплея.
+func displayToday() time.Time {
+	return dayOf(displayNow(), displayZone)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Последнее воскресенье марта и октября 2026: в Берлине 02:00 CET
+// превращается в 03:00 CEST, а 03:00 CEST — обратно в 02:00 CET.
+func TestDisplayClockDST(t *testing.T) {
+	swap(t, &displayZone, berlin(t, 2026, 1, 1, 0, 0).Location())
+	fc := useFakeClock(t, time.Time{})
+
+	tests := []struct {
+		name  string
+		at    time.Time // в UTC, чтобы не зависеть от разбора настенного времени
+		clock string
+		zone  string
+		today string
+	}{
+		{"march, before midnight", time.Date(2026, 3, 28, 22, 30, 0, 0, time.UTC), "23:30", "CET", "2026-03-28"},
+		{"march, after midnight", time.Date(2026, 3, 28, 23, 30, 0, 0, time.UTC), "00:30", "CET", "2026-03-29"},
+		{"march, last CET minute", time.Date(2026, 3, 29, 0, 59, 0, 0, time.UTC), "01:59", "CET", "2026-03-29"},
+		{"march, first CEST minute", time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC), "03:00", "CEST", "2026-03-29"},
+		{"march, late evening", time.Date(2026, 3, 29, 21, 30, 0, 0, time.UTC), "23:30", "CEST", "2026-03-29"},
+		{"march, next midnight", time.Date(2026, 3, 29, 22, 0, 0, 0, time.UTC), "00:00", "CEST", "2026-03-30"},
+		{"october, before midnight", time.Date(2026, 10, 24, 21, 30, 0, 0, time.UTC), "23:30", "CEST", "2026-10-24"},
+		{"october, first 02:30", time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC), "02:30", "CEST", "2026-10-25"},
+		{"october, second 02:30", time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC), "02:30", "CET", "2026-10-25"},
+		{"october, late evening", time.Date(2026, 10, 25, 22, 30, 0, 0, time.UTC), "23:30", "CET", "2026-10-25"},
+		{"october, next midnight", time.Date(2026, 10, 25, 23, 0, 0, 0, time.UTC), "00:00", "CET", "2026-10-26"},
+	}
+	for _, tt := range tests {
+		fc.Set(tt.at)
+		now := displayNow()
+		zone, _ := now.Zone()
+		if got := now.Format("15:04"); got != tt.clock || zone != tt.zone {
+			t.Errorf("%s: displayNow = %s %s, want %s %s", tt.name, got, zone, tt.clock, tt.zone)
+		}
+		if got, want := nowInMinutes(), parseClock(tt.clock); got != want {
+			t.Errorf("%s: nowInMinutes = %d, want %d", tt.name, got, want)
+		}
+		today := displayToday()
+		if got := today.Format(time.DateOnly); got != tt.today || today.Hour() != 0 || today.Location() != displayZone {
+			t.Errorf("%s: displayToday = %s, want midnight of %s", tt.name, today, tt.today)
+		}
+	}
+}
+
+func TestDisplayDayLengthDST(t *testing.T) {
+	swap(t, &displayZone, berlin(t, 2026, 1, 1, 0, 0).Location())
+
+	tests := []struct {
+		at   time.Time
+		want time.Duration
+	}{
+		{berlin(t, 2026, 3, 29, 12, 0), 23 * time.Hour},
+		{berlin(t, 2026, 3, 30, 12, 0), 24 * time.Hour},
+		{berlin(t, 2026, 10, 25, 12, 0), 25 * time.Hour},
+		{berlin(t, 2026, 10, 26, 12, 0), 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		useFakeClock(t, tt.at)
+		today := displayToday()
+		if got := today.AddDate(0, 0, 1).Sub(today); got != tt.want {
+			t.Errorf("%s: day lasts %s, want %s", today.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+// Неделя календаря, захватывающая перевод часов, начинается и
+// заканчивается в полночь по настенным часам.
+func TestCalendarPeriodDST(t *testing.T) {
+	swap(t, &displayZone, berlin(t, 2026, 1, 1, 0, 0).Location())
+
+	tests := []struct {
+		at       time.Time
+		layout   calendarLayout
+		from, to string
+		hours    float64
+	}{
+		{berlin(t, 2026, 3, 26, 8, 0), layoutWeek, "2026-03-26 00:00 CET", "2026-04-02 00:00 CEST", 7*24 - 1},
+		{berlin(t, 2026, 10, 25, 8, 0), layoutAgenda, "2026-10-25 00:00 CEST", "2026-10-27 00:00 CET", 2*24 + 1},
+		{berlin(t, 2026, 3, 29, 23, 30), layoutMonth, "2026-03-01 00:00 CET", "2026-04-01 00:00 CEST", 31*24 - 1},
+	}
+	for _, tt := range tests {
+		useFakeClock(t, tt.at)
+		from, to := tt.layout.period(displayToday())
+		const layout = "2006-01-02 15:04 MST"
+		if from.Format(layout) != tt.from || to.Format(layout) != tt.to || to.Sub(from).Hours() != tt.hours {
+			t.Errorf("%s: period %s – %s (%vh), want %s – %s (%vh)", tt.at.Format(layout),
+				from.Format(layout), to.Format(layout), to.Sub(from).Hours(), tt.from, tt.to, tt.hours)
+		}
+	}
+}
+
+func TestFakeClockAcrossDST(t *testing.T) {
+	swap(t, &displayZone, berlin(t, 2026, 1, 1, 0, 0).Location())
+
+	// 01:30 + час весной — уже 03:30, осенью 02:30 повторяется
+	fc := useFakeClock(t, berlin(t, 2026, 3, 29, 1, 30))
+	fc.Advance(time.Hour)
+	if got := displayNow().Format("15:04 MST"); got != "03:30 CEST" {
+		t.Errorf("march: %s, want 03:30 CEST", got)
+	}
+
+	fc.Set(berlin(t, 2026, 10, 25, 1, 30))
+	var got []string
+	for range 3 {
+		fc.Advance(time.Hour)
+		got = append(got, displayNow().Format("15:04 MST"))
+	}
+	want := []string{"02:30 CEST", "02:30 CET", "03:30 CET"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("october: %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+// DASHBOARD_FAKE_NOW читается в поясе дисплея; несуществующее время
+// в ночь на переход сдвигается вперёд, как у time.Date.
+func TestNewClockFakeNowDST(t *testing.T) {
+	swap(t, &displayZone, berlin(t, 2026, 1, 1, 0, 0).Location())
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2026-03-29T01:55", "2026-03-29 01:55 CET"},
+		{"2026-03-29T02:30", "2026-03-29 03:30 CEST"},
+		{"2026-10-25T03:30", "2026-10-25 03:30 CET"},
+	}
+	for _, tt := range tests {
+		fc, ok := newClock(tt.in).(*FakeClock)
+		if !ok {
+			t.Errorf("%s: clock not frozen", tt.in)
+			continue
+		}
+		if got := fc.Now().In(displayZone).Format("2006-01-02 15:04 MST"); got != tt.want {
+			t.Errorf("%s: %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if _, ok := newClock("29.03.2026 02:30").(systemClock); !ok {
+		t.Error("bad DASHBOARD_FAKE_NOW must fall back to the system clock")
+	}
+}
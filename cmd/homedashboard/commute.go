@@ -0,0 +1,250 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ---------- CONFIG ----------
+//
+
+// Маршруты на работу и в школу через «;»: остановка (подпись из
+// DASHBOARD_TRANSPORT_STOPS или id), линии, минуты пешком до остановки и
+// время, не раньше которого нужен рейс:
+//
+//	DASHBOARD_COMMUTE="Schule=Breslauer Straße|U79|8|07:25; Arbeit=Schlesische Straße|706|5|07:40"
+//
+// В рабочие дни (DASHBOARD_COMMUTE_DAYS) табло само открывается за
+// DASHBOARD_COMMUTE_LEAD минут до выхода к первому рейсу не раньше этого
+// времени. Без маршрутов остаётся прежнее окно 07:15–07:40.
+var (
+	commuteRoutes = parseCommuteRoutes(envString("DASHBOARD_COMMUTE", ""))
+	commuteDays   = parseWeekdays(envList("DASHBOARD_COMMUTE_DAYS", "mon,tue,wed,thu,fri"))
+	commuteLead   = envMinutes("DASHBOARD_COMMUTE_LEAD", 15)
+)
+
+const (
+	commuteAlternatives = 3               // рейсов в строке маршрута
+	commuteLinger       = 2 * time.Minute // держать табло после «пора выходить»
+	commuteBadDelay     = 3               // минут опоздания, которые уже выделяем
+)
+
+type commuteRoute struct {
+	Label  string
+	StopID string
+	Lines  []string
+	Walk   time.Duration
+	After  TimeOfDay
+	// After задан: без него годится любой рейс
+	hasAfter bool
+}
+
+func parseCommuteRoutes(s string) []commuteRoute {
+	var routes []commuteRoute
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		fields := strings.Split(item, "|")
+		label, stop, ok := strings.Cut(fields[0], "=")
+		if !ok || strings.TrimSpace(stop) == "" {
+			log.Printf("config: commute route %q must be Label=stop|lines|walk|HH:MM", item)
+			continue
+		}
+		r := commuteRoute{Label: strings.TrimSpace(label), StopID: resolveStop(strings.TrimSpace(stop))}
+		if len(fields) > 1 {
+			r.Lines = splitList(fields[1])
+		}
+		if len(fields) > 2 {
+			walk, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+			if err != nil {
+				log.Printf("config: commute route %s: walk %q is not minutes", r.Label, fields[2])
+			}
+			r.Walk = time.Duration(walk) * time.Minute
+		}
+		if len(fields) > 3 {
+			t, err := time.Parse("15:04", strings.TrimSpace(fields[3]))
+			if err != nil {
+				log.Printf("config: commute route %s: %q is not HH:MM", r.Label, fields[3])
+			} else {
+				r.After, r.hasAfter = TimeOfDay(t.Hour()*60+t.Minute()), true
+			}
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// resolveStop — id остановки по подписи из DASHBOARD_TRANSPORT_STOPS.
+func resolveStop(s string) string {
+	for _, stop := range transportStops {
+		if strings.EqualFold(stop.Label, s) {
+			return stop.ID
+		}
+	}
+	return s
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"so": time.Sunday, "mo": time.Monday, "di": time.Tuesday, "mi": time.Wednesday,
+	"do": time.Thursday, "fr": time.Friday, "sa": time.Saturday,
+}
+
+func parseWeekdays(items []string) map[time.Weekday]bool {
+	days := map[time.Weekday]bool{}
+	for _, item := range items {
+		d, ok := weekdayNames[strings.ToLower(item)]
+		if !ok {
+			log.Printf("config: unknown weekday %q", item)
+			continue
+		}
+		days[d] = true
+	}
+	return days
+}
+
+func envMinutes(name string, def int) time.Duration {
+	v := envString(name, strconv.Itoa(def))
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("config: %s=%q is not minutes, using %d", name, v, def)
+		n = def
+	}
+	return time.Duration(n) * time.Minute
+}
+
+//
+// ---------- PLANNER ----------
+//
+
+// commuteStatus — строка маршрута над табло: когда выходить и что с рейсами.
+type commuteStatus struct {
+	Label   string
+	Next    *Departure  // рейс, на который ещё успеваем; nil — нет данных
+	LeaveIn int         // минут до выхода, 0 — пора
+	Options []Departure // ближайшие рейсы маршрута, включая отменённые
+	Trouble bool        // отмена или заметное опоздание — выделить
+}
+
+// relevant — рейсы маршрута в порядке отправления: нужные линии и не
+// раньше заданного времени.
+func (r commuteRoute) relevant(deps []Departure, now time.Time) []Departure {
+	stop := transportStop{Lines: r.Lines}
+	today := dayOf(now, displayZone)
+	var out []Departure
+	for _, d := range upcoming(deps, stop, now) {
+		if r.hasAfter && dayOf(d.Planned, displayZone).Equal(today) && minuteOfDay(d.Planned, today) < int(r.After) {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// catchable — первый неотменённый рейс, к которому ещё можно дойти.
+func (r commuteRoute) catchable(options []Departure, now time.Time) *Departure {
+	for i, d := range options {
+		if !d.Cancelled && !d.Expected.Add(-r.Walk).Before(now) {
+			return &options[i]
+		}
+	}
+	return nil
+}
+
+func commuteStatuses(now time.Time) []commuteStatus {
+	transportMu.Lock()
+	deps := lastDepartures
+	transportMu.Unlock()
+
+	var out []commuteStatus
+	for _, r := range commuteRoutes {
+		options := r.relevant(deps[r.StopID], now)
+		st := commuteStatus{Label: r.Label}
+		if next := r.catchable(options, now); next != nil {
+			st.Next = next
+			st.LeaveIn = max(0, int(next.Expected.Add(-r.Walk).Sub(now)/time.Minute))
+		}
+		if len(options) > commuteAlternatives {
+			options = options[:commuteAlternatives]
+		}
+		st.Options = options
+		for _, d := range options {
+			if d.Cancelled || d.Delay() >= commuteBadDelay {
+				st.Trouble = true
+			}
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// commuteDone — маршруты, чей рейс на сегодня уже ушёл: больше в этот день
+// табло само не открывается. Свой мьютекс: transportMu держится на всё
+// время опроса провайдера, а commuteActive зовётся из HTTP-обработчика.
+var (
+	commuteMu   sync.Mutex
+	commuteDone = map[string]time.Time{}
+)
+
+// commuteActive — рабочий день и до выхода к рейсу маршрута осталось не
+// больше commuteLead: табло пора показать вне очереди. Маршруты без
+// времени только показываются на табло.
+func commuteActive() bool {
+	now := clock.Now()
+	today := dayOf(now, displayZone)
+	if !commuteDays[today.Weekday()] {
+		return false
+	}
+
+	transportMu.Lock()
+	deps := lastDepartures
+	transportMu.Unlock()
+
+	commuteMu.Lock()
+	defer commuteMu.Unlock()
+
+	active := false
+	for _, r := range commuteRoutes {
+		// Через час после заданного времени маршрут на сегодня закрыт, даже
+		// если ухода рейса никто не застал (дашборд не опрашивали)
+		if !r.hasAfter || commuteDone[r.Label].Equal(today) || minuteOfDay(now, today) > int(r.After)+60 {
+			continue
+		}
+		target := r.target(deps[r.StopID], today)
+		if target == nil {
+			continue
+		}
+		leave := target.Expected.Add(-r.Walk)
+		switch {
+		case !now.Before(leave.Add(commuteLinger)):
+			commuteDone[r.Label] = today
+		case !now.Before(leave.Add(-commuteLead)):
+			active = true
+		}
+	}
+	return active
+}
+
+// target — первый неотменённый рейс дня нужных линий не раньше After.
+func (r commuteRoute) target(deps []Departure, today time.Time) *Departure {
+	var best *Departure
+	for i, d := range deps {
+		if d.Cancelled || (len(r.Lines) > 0 && !containsFold(r.Lines, d.Line)) {
+			continue
+		}
+		if !dayOf(d.Planned, displayZone).Equal(today) || minuteOfDay(d.Planned, today) < int(r.After) {
+			continue
+		}
+		if best == nil || d.Planned.Before(best.Planned) {
+			best = &deps[i]
+		}
+	}
+	return best
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useRecordedDepartures кладёт записанное табло VRR (Breslauer Straße,
+// воскресенье 18.10.2026 около 14:00) в lastDepartures, как после опроса.
+func useRecordedDepartures(t *testing.T) {
+	t.Helper()
+	swap(t, &displayZone, berlin(t, 2026, 1, 1, 0, 0).Location())
+	srv := transportServer(t)
+	deps, err := efaProvider{BaseURL: srv + "/efa"}.Departures(context.Background(), transportStop{ID: "20018235"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &lastDepartures, map[string][]Departure{"20018235": deps})
+	swap(t, &commuteDone, map[string]time.Time{})
+}
+
+func TestCommuteActive(t *testing.T) {
+	useRecordedDepartures(t)
+	swap(t, &commuteLead, 15*time.Minute)
+	// Первый U79 не раньше 14:00 — 14:04, ожидается 14:06; идти 8 минут,
+	// значит выходить в 13:58, табло открыто с 13:43 до 14:00
+	swap(t, &commuteRoutes, parseCommuteRoutes("Uni=20018235|U79|8|14:00; Без времени=20018235|706|5"))
+
+	tests := []struct {
+		name   string
+		days   string
+		clock  string
+		active bool
+	}{
+		{"too early", "sun", "13:42", false},
+		{"lead starts", "sun", "13:43", true},
+		{"leave now", "sun", "13:58", true},
+		{"linger", "sun", "13:59", true},
+		{"gone", "sun", "14:00", false},
+		// Рейс ушёл — до конца дня маршрут закрыт, даже если часы отмотать
+		{"done for today", "sun", "13:50", false},
+		{"not a commute day", "mon,tue,wed,thu,fri", "13:50", false},
+	}
+	fc := useFakeClock(t, time.Time{})
+	for _, tt := range tests {
+		swap(t, &commuteDays, parseWeekdays(strings.Split(tt.days, ",")))
+		h, m := int(parseClock(tt.clock))/60, int(parseClock(tt.clock))%60
+		fc.Set(berlin(t, 2026, 10, 18, h, m))
+		if got := commuteActive(); got != tt.active {
+			t.Errorf("%s at %s: active = %v, want %v", tt.name, tt.clock, got, tt.active)
+		}
+	}
+}
+
+func TestCommuteActiveLateClose(t *testing.T) {
+	useRecordedDepartures(t)
+	swap(t, &commuteDays, parseWeekdays([]string{"sun"}))
+	swap(t, &commuteLead, 15*time.Minute)
+	// Рейсов не раньше 12:30 нет в записи: через час после 12:30 маршрут
+	// закрывается сам, иначе он бы ждал 13:55
+	swap(t, &commuteRoutes, parseCommuteRoutes("Früh=20018235|U79|8|12:30"))
+
+	useFakeClock(t, berlin(t, 2026, 10, 18, 13, 45))
+	if commuteActive() {
+		t.Error("route must close an hour after its time")
+	}
+}
+
+func TestCommuteStatuses(t *testing.T) {
+	useRecordedDepartures(t)
+	swap(t, &commuteRoutes, parseCommuteRoutes("Uni=20018235|U79|8|14:00; Tram=20018235|706,707|3"))
+
+	tests := []struct {
+		clock string
+		want  []string
+	}{
+		{"13:50", []string{
+			// Отменённый 14:14 в вариантах — строка выделена
+			"Uni next 14:04 leave 8 options 14:04 14:09 14:14X trouble",
+			"Tram next 14:07 leave 14 options 14:07 14:12 14:17X trouble",
+		}},
+		{"14:00", []string{
+			"Uni next 14:09 leave 1 options 14:04 14:09 14:14X trouble",
+			"Tram next 14:07 leave 4 options 14:07 14:12 14:17X trouble",
+		}},
+		{"14:02", []string{
+			// К 14:09 уже не успеть, следующий отменён
+			"Uni next - leave 0 options 14:04 14:09 14:14X trouble",
+			"Tram next 14:07 leave 2 options 14:07 14:12 14:17X trouble",
+		}},
+		{"14:11", []string{
+			"Uni next - leave 0 options 14:14X trouble",
+			"Tram next - leave 0 options 14:12 14:17X trouble",
+		}},
+	}
+	fc := useFakeClock(t, time.Time{})
+	for _, tt := range tests {
+		h, m := int(parseClock(tt.clock))/60, int(parseClock(tt.clock))%60
+		fc.Set(berlin(t, 2026, 10, 18, h, m))
+		var got []string
+		for _, st := range commuteStatuses(clock.Now()) {
+			got = append(got, commuteLine(st))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.clock, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+// HTTP-обработчик и фоновый рендер спрашивают маршруты одновременно.
+func TestCommuteActiveConcurrent(t *testing.T) {
+	useRecordedDepartures(t)
+	swap(t, &commuteDays, parseWeekdays([]string{"sun"}))
+	swap(t, &commuteRoutes, parseCommuteRoutes("Uni=20018235|U79|8|14:00"))
+	useFakeClock(t, berlin(t, 2026, 10, 18, 14, 0))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			commuteActive()
+			commuteStatuses(clock.Now())
+		})
+	}
+	wg.Wait()
+	if !commuteDone["Uni"].Equal(berlin(t, 2026, 10, 18, 0, 0)) {
+		t.Errorf("commuteDone = %v", commuteDone)
+	}
+}
+
+func commuteLine(st commuteStatus) string {
+	next := "-"
+	if st.Next != nil {
+		next = st.Next.Planned.Format("15:04")
+	}
+	s := fmt.Sprintf("%s next %s leave %d options", st.Label, next, st.LeaveIn)
+	for _, d := range st.Options {
+		s += " " + d.Planned.Format("15:04")
+		if d.Cancelled {
+			s += "X"
+		}
+	}
+	if st.Trouble {
+		s += " trouble"
+	}
+	return s
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//
+// ---------- CONFIG ----------
+//
+
+// Настройки берутся из переменных окружения (локально их выставляет .envrc
+// через Makefile, на Pi — systemd-юнит). Значения по умолчанию совпадают с
+// тем, что раньше было зашито в код.
+
+func envString(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+func envBool(name string, def bool) bool {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("config: %s=%q is not a bool, using %v", name, v, def)
+		return def
+	}
+	return b
+}
+
+// envList читает список через запятую, пустые элементы отбрасывает.
+func envList(name, def string) []string {
+	var out []string
+	for _, item := range strings.Split(envString(name, def), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//go:embed assets/JetBrainsMono-Bold.ttf
+var fontCustom []byte
+
+// Все шрифты, которые нужны страницам, едут внутри бинарника и подставляются
+// в HTML как data-URL. Иначе Chromium на Pi и на ноутбуке берёт разные
+// системные шрифты, и вёрстка страниц съезжает.
+type embeddedFont struct {
+	Family string
+	Weight string
+	Data   func() []byte
+}
+
+var embeddedFonts = []embeddedFont{
+	{Family: "Dashboard Sans", Weight: "100 500", Data: func() []byte { return goregular.TTF }},
+	{Family: "Dashboard Sans", Weight: "600 900", Data: func() []byte { return gobold.TTF }},
+	{Family: "Dashboard Mono", Weight: "100 900", Data: func() []byte { return fontCustom }},
+}
+
+// fontFaceCSS собирается один раз: base64 шрифтов занимает сотни килобайт.
+var fontFaceCSS = sync.OnceValue(func() string {
+	var b strings.Builder
+	b.WriteString("<style>\n")
+	for _, f := range embeddedFonts {
+		fmt.Fprintf(&b, "@font-face { font-family: %q; font-weight: %s; src: url(data:font/ttf;base64,%s) format(\"truetype\"); }\n",
+			f.Family, f.Weight, base64.StdEncoding.EncodeToString(f.Data()))
+	}
+	b.WriteString("</style>\n")
+	return b.String()
+})
+
+// injectFonts вставляет @font-face в конец <head>, чтобы meta charset
+// оставался в начале документа.
+func injectFonts(html string) string {
+	if i := strings.Index(html, "</head>"); i >= 0 {
+		return html[:i] + fontFaceCSS() + html[i:]
+	}
+	return fontFaceCSS() + html
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			if r.Header.Get("User-Agent") != "homedashboard" {
+				http.Error(w, "no user agent", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"name":"Essen"}`))
+		case "/broken":
+			w.Write([]byte(`{"name":`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr string
+	}{
+		{"/ok", "Essen", ""},
+		{"/broken", "", "json: unexpected EOF"},
+		{"/missing", "", "status: 404 Not Found"},
+	}
+	for _, tt := range tests {
+		var out struct{ Name string }
+		err := getJSON(context.Background(), srv.URL+tt.path, map[string]string{"User-Agent": "homedashboard"}, &out)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: err = %v, want %q", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || out.Name != tt.want {
+			t.Errorf("%s: got %q, %v", tt.path, out.Name, err)
+		}
+	}
+
+	// Ошибка сети не выдаёт себя за погоду: подпись добавляет вызывающий
+	srv.Close()
+	err := getJSON(context.Background(), srv.URL+"/ok", nil, &struct{}{})
+	if err == nil || strings.Contains(err.Error(), "weather") {
+		t.Errorf("err = %v", err)
+	}
+}
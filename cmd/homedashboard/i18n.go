@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ---------- I18N ----------
+//
+
+// Lang — язык страницы. Тексты берутся из каталогов (i18n_catalog.go), а
+// даты и числа форматируются по правилам языка. В шаблонах язык лежит в
+// данных страницы: {{.Lang.T "weather.today"}}, {{$.Lang.Date .Start "weekday_day"}}.
+type Lang string
+
+const (
+	langDE Lang = "de"
+	langEN Lang = "en"
+	langRU Lang = "ru"
+)
+
+var (
+	// Язык по умолчанию — для устройств, которые о себе ничего не сообщают.
+	defaultLang = parseLang(envString("DASHBOARD_LANGUAGE", "de"), langDE)
+	// Язык по устройству: устройство добавляет к URL ?device=<id>.
+	//   DASHBOARD_DEVICE_LANGUAGES="e1001=de, kitchen=ru"
+	deviceLangs = parseDeviceLangs(envList("DASHBOARD_DEVICE_LANGUAGES", ""))
+)
+
+func parseLang(s string, def Lang) Lang {
+	l := Lang(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := catalogs[l]; ok {
+		return l
+	}
+	if s != "" {
+		log.Printf("config: unsupported language %q, using %s", s, def)
+	}
+	return def
+}
+
+func parseDeviceLangs(items []string) map[string]Lang {
+	langs := map[string]Lang{}
+	for _, item := range items {
+		device, lang, ok := strings.Cut(item, "=")
+		if !ok {
+			log.Printf("config: device language %q must be device=lang", item)
+			continue
+		}
+		langs[strings.TrimSpace(device)] = parseLang(lang, defaultLang)
+	}
+	return langs
+}
+
+// requestLang — язык для запроса: явный ?lang=, потом язык устройства
+// из ?device=, потом язык по умолчанию.
+func requestLang(r *http.Request) Lang {
+	q := r.URL.Query()
+	if l := q.Get("lang"); l != "" {
+		return parseLang(l, defaultLang)
+	}
+	if l, ok := deviceLangs[q.Get("device")]; ok {
+		return l
+	}
+	return defaultLang
+}
+
+// activeLangs — языки, на которых рендерятся страницы: по умолчанию и все
+// языки устройств. Каждый язык — отдельный набор картинок в кэше.
+func activeLangs() []Lang {
+	langs := []Lang{defaultLang}
+	for _, l := range deviceLangs {
+		if !slices.Contains(langs, l) {
+			langs = append(langs, l)
+		}
+	}
+	slices.Sort(langs[1:])
+	return langs
+}
+
+// T возвращает перевод ключа, подставляя аргументы через fmt. Если в языке
+// ключа нет — берётся немецкий, если нет и там — сам ключ.
+func (l Lang) T(key string, args ...any) string {
+	msg, ok := catalogs[l][key]
+	if !ok {
+		if msg, ok = catalogs[langDE][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Tag — BCP 47 для Intl в браузере.
+func (l Lang) Tag() string {
+	return l.T("locale.tag")
+}
+
+// Date форматирует время по именованному формату языка ("day", "stamp", …),
+// заменяя английские названия дней и месяцев Go на местные.
+func (l Lang) Date(t time.Time, format string) string {
+	layout := l.T("layout." + format)
+
+	var b strings.Builder
+	for layout != "" {
+		switch {
+		case strings.HasPrefix(layout, "Monday"):
+			b.WriteString(l.names("weekdays")[t.Weekday()])
+			layout = layout[len("Monday"):]
+		case strings.HasPrefix(layout, "Mon"):
+			b.WriteString(l.names("weekdays.short")[t.Weekday()])
+			layout = layout[len("Mon"):]
+		case strings.HasPrefix(layout, "January"):
+			b.WriteString(l.names("months")[t.Month()-1])
+			layout = layout[len("January"):]
+		case strings.HasPrefix(layout, "Jan"):
+			b.WriteString(l.names("months.short")[t.Month()-1])
+			layout = layout[len("Jan"):]
+		default:
+			// Кусок до следующего названия форматируем обычным Format
+			next := len(layout)
+			for _, name := range []string{"Mon", "Jan"} {
+				if i := strings.Index(layout, name); i >= 0 && i < next {
+					next = i
+				}
+			}
+			b.WriteString(t.Format(layout[:next]))
+			layout = layout[next:]
+		}
+	}
+	return b.String()
+}
+
+// names — список названий через «|» из каталога.
+func (l Lang) names(key string) []string {
+	return strings.Split(l.T(key), "|")
+}
+
+// Number форматирует число с нужным количеством знаков и десятичным
+// разделителем языка.
+func (l Lang) Number(v float64, decimals int) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if sep := l.T("number.decimal"); sep != "." {
+		s = strings.Replace(s, ".", sep, 1)
+	}
+	return s
+}
@@ -0,0 +1,460 @@
+package main
+
+//
+// ---------- MESSAGE CATALOGS ----------
+//
+
+// Ключи сгруппированы по страницам. Немецкий — основной: чего нет в других
+// языках, берётся из него. Названия дней недели начинаются с воскресенья,
+// как time.Weekday.
+var catalogs = map[Lang]map[string]string{
+	langDE: {
+		"locale.tag":     "de-DE",
+		"number.decimal": ",",
+
+		"weekdays":           "Sonntag|Montag|Dienstag|Mittwoch|Donnerstag|Freitag|Samstag",
+		"weekdays.short":     "So|Mo|Di|Mi|Do|Fr|Sa",
+		"months":             "Januar|Februar|März|April|Mai|Juni|Juli|August|September|Oktober|November|Dezember",
+		"months.short":       "Jan|Feb|Mär|Apr|Mai|Jun|Jul|Aug|Sep|Okt|Nov|Dez",
+		"layout.time":        "15:04",
+		"layout.day":         "02.01.",
+		"layout.date":        "02.01.2006",
+		"layout.month":       "01.06",
+		"layout.month_year":  "January 2006",
+		"layout.weekday":     "Mon",
+		"layout.weekday_day": "Mon 02.01.",
+		"layout.weekday_at":  "Mon 15:04",
+		"layout.short_stamp": "02.01. 15:04",
+		"layout.stamp":       "Mon, 02.01. 15:04",
+		"layout.full_stamp":  "Mon 02.01. 15:04",
+
+		"page.updated": "Stand: %s",
+		"unit.kmh":     "km/h",
+		"unit.mm":      "mm",
+
+		"weather.title":         "%s Wetter",
+		"weather.today":         "Heute",
+		"weather.tomorrow":      "Morgen",
+		"weather.min":           "Min",
+		"weather.max":           "Max",
+		"weather.feels_like":    "Gefühlt",
+		"weather.humidity":      "Feuchtigkeit",
+		"weather.wind":          "Wind",
+		"weather.gusts":         "Böen",
+		"weather.sun":           "Sonne",
+		"weather.uv":            "UV-Index",
+		"weather.precipitation": "Niederschlag",
+		"weather.next_hours":    "Nächste 24 Stunden",
+		"weather.forecast":      "Vorhersage",
+		"weather.footer":        "Aktualisiert: %s · %s",
+		"weather.rain_now":      "Regen jetzt (%d%%, %s mm)",
+		"weather.rain_from":     "Regen ab %s Uhr (%d%%, %s mm)",
+		"weather.rain_none":     "Kein Regen erwartet",
+
+		"compare.title":      "Wetter im Vergleich",
+		"compare.local_time": "Ortszeit %s",
+		"compare.no_data":    "Keine Daten",
+
+		"wmo.unknown": "—",
+		"wmo.0":       "Klar",
+		"wmo.1":       "Überwiegend klar",
+		"wmo.2":       "Teilw. bewölkt",
+		"wmo.3":       "Bewölkt",
+		"wmo.45":      "Nebel",
+		"wmo.48":      "Nebel",
+		"wmo.51":      "Leichter Niesel",
+		"wmo.53":      "Nieselregen",
+		"wmo.55":      "Starker Niesel",
+		"wmo.56":      "Gefrierender Niesel",
+		"wmo.57":      "Starker gefr. Niesel",
+		"wmo.61":      "Leichter Regen",
+		"wmo.63":      "Regen",
+		"wmo.65":      "Starker Regen",
+		"wmo.66":      "Gefrierender Regen",
+		"wmo.67":      "Starker gefr. Regen",
+		"wmo.71":      "Leichter Schnee",
+		"wmo.73":      "Schnee",
+		"wmo.75":      "Starker Schnee",
+		"wmo.77":      "Schneegriesel",
+		"wmo.80":      "Schauer",
+		"wmo.81":      "Starke Schauer",
+		"wmo.82":      "Gewittrige Schauer",
+		"wmo.85":      "Schneeschauer",
+		"wmo.86":      "Starke Schneeschauer",
+		"wmo.95":      "Gewitter",
+		"wmo.96":      "Gewitter & Hagel",
+		"wmo.99":      "Starkes Gewitter",
+
+		"air.title":          "Luftqualität & Pollen",
+		"air.aqi":            "Europ. AQI",
+		"air.pollen":         "Pollen",
+		"air.level.0":        "gering",
+		"air.level.1":        "mäßig",
+		"air.level.2":        "hoch",
+		"air.level.3":        "sehr hoch",
+		"air.warning_aqi":    "Luftqualität %s (AQI %.0f)",
+		"air.warning_pollen": "%s-Pollen %s",
+		"air.pm25":           "PM2.5",
+		"air.pm10":           "PM10",
+		"air.ozone":          "Ozon",
+		"air.birch":          "Birke",
+		"air.grass":          "Gräser",
+		"air.ragweed":        "Ambrosia",
+
+		"warnings.title":    "Wetterwarnungen",
+		"warnings.none":     "Keine aktiven Warnungen",
+		"warnings.from":     "ab %s",
+		"warnings.until":    "bis %s",
+		"severity.unknown":  "Warnung",
+		"severity.minor":    "Wetterwarnung",
+		"severity.moderate": "Markantes Wetter",
+		"severity.severe":   "Unwetterwarnung",
+		"severity.extreme":  "Extremes Unwetter",
+
+		"calendar.title":             "Kalender %s",
+		"calendar.untitled":          "(kein Titel)",
+		"calendar.all_day":           "ganztägig",
+		"calendar.day_of":            "Tag %d/%d",
+		"calendar.more":              "+%d weitere",
+		"calendar.today":             "Heute",
+		"calendar.tomorrow":          "Morgen",
+		"calendar.nothing":           "Keine Termine",
+		"calendar.this_week":         "Diese Woche:",
+		"calendar.birthday":          "Geburtstag %s",
+		"calendar.birthday_years":    "Geburtstag %s (%d)",
+		"calendar.anniversary":       "Jahrestag %s",
+		"calendar.anniversary_years": "Jahrestag %s (%d.)",
+
+		"waste.title":     "Abfuhrkalender",
+		"waste.none":      "Keine Abfuhrtermine",
+		"waste.today":     "heute",
+		"waste.tomorrow":  "morgen",
+		"waste.in_days":   "in %d Tagen",
+		"waste.rest":      "Restmüll",
+		"waste.bio":       "Biotonne",
+		"waste.paper":     "Altpapier",
+		"waste.packaging": "Gelbe Tonne",
+		"waste.glass":     "Altglas",
+		"waste.bulky":     "Sperrmüll",
+		"waste.hazardous": "Schadstoffe",
+
+		"quote.title": "Zitat des Tages",
+		"quote.open":  "„",
+		"quote.close": "“",
+
+		"transport.title":       "Abfahrten",
+		"transport.now":         "sofort",
+		"transport.minutes":     "%d min",
+		"transport.delay":       "+%d",
+		"transport.cancelled":   "fällt aus",
+		"transport.platform":    "Gl. %s",
+		"transport.none":        "Keine Abfahrten",
+		"transport.unavailable": "Keine Daten",
+
+		"commute.leave_in":      "Los in %d min",
+		"commute.leave_now":     "Jetzt los!",
+		"commute.no_connection": "Keine Verbindung",
+
+		"bikes.closed": "geschlossen",
+	},
+
+	langEN: {
+		"locale.tag":     "en-GB",
+		"number.decimal": ".",
+
+		"weekdays":           "Sunday|Monday|Tuesday|Wednesday|Thursday|Friday|Saturday",
+		"weekdays.short":     "Sun|Mon|Tue|Wed|Thu|Fri|Sat",
+		"months":             "January|February|March|April|May|June|July|August|September|October|November|December",
+		"months.short":       "Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec",
+		"layout.time":        "15:04",
+		"layout.day":         "02/01",
+		"layout.date":        "02/01/2006",
+		"layout.month":       "01/06",
+		"layout.month_year":  "January 2006",
+		"layout.weekday":     "Mon",
+		"layout.weekday_day": "Mon 02/01",
+		"layout.weekday_at":  "Mon 15:04",
+		"layout.short_stamp": "02/01 15:04",
+		"layout.stamp":       "Mon, 02/01 15:04",
+		"layout.full_stamp":  "Mon 02/01 15:04",
+
+		"page.updated": "As of %s",
+		"unit.kmh":     "km/h",
+		"unit.mm":      "mm",
+
+		"weather.title":         "%s weather",
+		"weather.today":         "Today",
+		"weather.tomorrow":      "Tomorrow",
+		"weather.min":           "Min",
+		"weather.max":           "Max",
+		"weather.feels_like":    "Feels like",
+		"weather.humidity":      "Humidity",
+		"weather.wind":          "Wind",
+		"weather.gusts":         "gusts",
+		"weather.sun":           "Sun",
+		"weather.uv":            "UV index",
+		"weather.precipitation": "Precipitation",
+		"weather.next_hours":    "Next 24 hours",
+		"weather.forecast":      "Forecast",
+		"weather.footer":        "Updated %s · %s",
+		"weather.rain_now":      "Rain now (%d%%, %s mm)",
+		"weather.rain_from":     "Rain from %s:00 (%d%%, %s mm)",
+		"weather.rain_none":     "No rain expected",
+
+		"compare.title":      "Weather comparison",
+		"compare.local_time": "Local time %s",
+		"compare.no_data":    "No data",
+
+		"wmo.unknown": "—",
+		"wmo.0":       "Clear",
+		"wmo.1":       "Mainly clear",
+		"wmo.2":       "Partly cloudy",
+		"wmo.3":       "Overcast",
+		"wmo.45":      "Fog",
+		"wmo.48":      "Fog",
+		"wmo.51":      "Light drizzle",
+		"wmo.53":      "Drizzle",
+		"wmo.55":      "Heavy drizzle",
+		"wmo.56":      "Freezing drizzle",
+		"wmo.57":      "Heavy freezing drizzle",
+		"wmo.61":      "Light rain",
+		"wmo.63":      "Rain",
+		"wmo.65":      "Heavy rain",
+		"wmo.66":      "Freezing rain",
+		"wmo.67":      "Heavy freezing rain",
+		"wmo.71":      "Light snow",
+		"wmo.73":      "Snow",
+		"wmo.75":      "Heavy snow",
+		"wmo.77":      "Snow grains",
+		"wmo.80":      "Showers",
+		"wmo.81":      "Heavy showers",
+		"wmo.82":      "Violent showers",
+		"wmo.85":      "Snow showers",
+		"wmo.86":      "Heavy snow showers",
+		"wmo.95":      "Thunderstorm",
+		"wmo.96":      "Thunderstorm & hail",
+		"wmo.99":      "Severe thunderstorm",
+
+		"air.title":          "Air quality & pollen",
+		"air.aqi":            "European AQI",
+		"air.pollen":         "Pollen",
+		"air.level.0":        "low",
+		"air.level.1":        "moderate",
+		"air.level.2":        "high",
+		"air.level.3":        "very high",
+		"air.warning_aqi":    "Air quality %s (AQI %.0f)",
+		"air.warning_pollen": "%s pollen %s",
+		"air.pm25":           "PM2.5",
+		"air.pm10":           "PM10",
+		"air.ozone":          "Ozone",
+		"air.birch":          "Birch",
+		"air.grass":          "Grass",
+		"air.ragweed":        "Ragweed",
+
+		"warnings.title":    "Weather warnings",
+		"warnings.none":     "No active warnings",
+		"warnings.from":     "from %s",
+		"warnings.until":    "until %s",
+		"severity.unknown":  "Warning",
+		"severity.minor":    "Weather warning",
+		"severity.moderate": "Significant weather",
+		"severity.severe":   "Severe weather warning",
+		"severity.extreme":  "Extreme weather",
+
+		"calendar.title":             "Calendar %s",
+		"calendar.untitled":          "(no title)",
+		"calendar.all_day":           "all day",
+		"calendar.day_of":            "day %d/%d",
+		"calendar.more":              "+%d more",
+		"calendar.today":             "Today",
+		"calendar.tomorrow":          "Tomorrow",
+		"calendar.nothing":           "Nothing planned",
+		"calendar.this_week":         "This week:",
+		"calendar.birthday":          "Birthday %s",
+		"calendar.birthday_years":    "Birthday %s (%d)",
+		"calendar.anniversary":       "Anniversary %s",
+		"calendar.anniversary_years": "Anniversary %s (%d)",
+
+		"waste.title":     "Bin collection",
+		"waste.none":      "No collections planned",
+		"waste.today":     "today",
+		"waste.tomorrow":  "tomorrow",
+		"waste.in_days":   "in %d days",
+		"waste.rest":      "General waste",
+		"waste.bio":       "Organic waste",
+		"waste.paper":     "Paper",
+		"waste.packaging": "Packaging",
+		"waste.glass":     "Glass",
+		"waste.bulky":     "Bulky waste",
+		"waste.hazardous": "Hazardous waste",
+
+		"quote.title": "Quote of the day",
+		"quote.open":  "“",
+		"quote.close": "”",
+
+		"transport.title":       "Departures",
+		"transport.now":         "now",
+		"transport.minutes":     "%d min",
+		"transport.delay":       "+%d",
+		"transport.cancelled":   "cancelled",
+		"transport.platform":    "Pl. %s",
+		"transport.none":        "No departures",
+		"transport.unavailable": "No data",
+
+		"commute.leave_in":      "Leave in %d min",
+		"commute.leave_now":     "Leave now!",
+		"commute.no_connection": "No connection",
+
+		"bikes.closed": "closed",
+	},
+
+	langRU: {
+		"locale.tag":     "ru-RU",
+		"number.decimal": ",",
+
+		"weekdays":           "воскресенье|понедельник|вторник|среда|четверг|пятница|суббота",
+		"weekdays.short":     "Вс|Пн|Вт|Ср|Чт|Пт|Сб",
+		"months":             "январь|февраль|март|апрель|май|июнь|июль|август|сентябрь|октябрь|ноябрь|декабрь",
+		"months.short":       "янв|фев|мар|апр|май|июн|июл|авг|сен|окт|ноя|дек",
+		"layout.time":        "15:04",
+		"layout.day":         "02.01",
+		"layout.date":        "02.01.2006",
+		"layout.month":       "01.06",
+		"layout.month_year":  "January 2006",
+		"layout.weekday":     "Mon",
+		"layout.weekday_day": "Mon 02.01",
+		"layout.weekday_at":  "Mon 15:04",
+		"layout.short_stamp": "02.01 15:04",
+		"layout.stamp":       "Mon, 02.01 15:04",
+		"layout.full_stamp":  "Mon 02.01 15:04",
+
+		"page.updated": "Данные на %s",
+		"unit.kmh":     "км/ч",
+		"unit.mm":      "мм",
+
+		"weather.title":         "Погода: %s",
+		"weather.today":         "Сегодня",
+		"weather.tomorrow":      "Завтра",
+		"weather.min":           "Мин",
+		"weather.max":           "Макс",
+		"weather.feels_like":    "Ощущается",
+		"weather.humidity":      "Влажность",
+		"weather.wind":          "Ветер",
+		"weather.gusts":         "порывы",
+		"weather.sun":           "Солнце",
+		"weather.uv":            "УФ-индекс",
+		"weather.precipitation": "Осадки",
+		"weather.next_hours":    "Ближайшие 24 часа",
+		"weather.forecast":      "Прогноз",
+		"weather.footer":        "Обновлено: %s · %s",
+		"weather.rain_now":      "Сейчас дождь (%d%%, %s мм)",
+		"weather.rain_from":     "Дождь с %s:00 (%d%%, %s мм)",
+		"weather.rain_none":     "Дождя не ожидается",
+
+		"compare.title":      "Погода в разных местах",
+		"compare.local_time": "Местное время %s",
+		"compare.no_data":    "Нет данных",
+
+		"wmo.unknown": "—",
+		"wmo.0":       "Ясно",
+		"wmo.1":       "Малооблачно",
+		"wmo.2":       "Переменная облачность",
+		"wmo.3":       "Пасмурно",
+		"wmo.45":      "Туман",
+		"wmo.48":      "Туман",
+		"wmo.51":      "Слабая морось",
+		"wmo.53":      "Морось",
+		"wmo.55":      "Сильная морось",
+		"wmo.56":      "Ледяная морось",
+		"wmo.57":      "Сильная ледяная морось",
+		"wmo.61":      "Небольшой дождь",
+		"wmo.63":      "Дождь",
+		"wmo.65":      "Сильный дождь",
+		"wmo.66":      "Ледяной дождь",
+		"wmo.67":      "Сильный ледяной дождь",
+		"wmo.71":      "Небольшой снег",
+		"wmo.73":      "Снег",
+		"wmo.75":      "Сильный снег",
+		"wmo.77":      "Снежные зёрна",
+		"wmo.80":      "Ливень",
+		"wmo.81":      "Сильный ливень",
+		"wmo.82":      "Очень сильный ливень",
+		"wmo.85":      "Снегопад",
+		"wmo.86":      "Сильный снегопад",
+		"wmo.95":      "Гроза",
+		"wmo.96":      "Гроза с градом",
+		"wmo.99":      "Сильная гроза",
+
+		"air.title":          "Воздух и пыльца",
+		"air.aqi":            "Европ. AQI",
+		"air.pollen":         "Пыльца",
+		"air.level.0":        "низкий",
+		"air.level.1":        "умеренный",
+		"air.level.2":        "высокий",
+		"air.level.3":        "очень высокий",
+		"air.warning_aqi":    "Качество воздуха: %s (AQI %.0f)",
+		"air.warning_pollen": "Пыльца (%s): %s",
+		"air.pm25":           "PM2.5",
+		"air.pm10":           "PM10",
+		"air.ozone":          "Озон",
+		"air.birch":          "Берёза",
+		"air.grass":          "Злаки",
+		"air.ragweed":        "Амброзия",
+
+		"warnings.title":    "Погодные предупреждения",
+		"warnings.none":     "Активных предупреждений нет",
+		"warnings.from":     "с %s",
+		"warnings.until":    "до %s",
+		"severity.unknown":  "Предупреждение",
+		"severity.minor":    "Неблагоприятная погода",
+		"severity.moderate": "Значительная погода",
+		"severity.severe":   "Опасная погода",
+		"severity.extreme":  "Чрезвычайно опасная погода",
+
+		"calendar.title":             "Календарь %s",
+		"calendar.untitled":          "(без названия)",
+		"calendar.all_day":           "весь день",
+		"calendar.day_of":            "день %d/%d",
+		"calendar.more":              "ещё %d",
+		"calendar.today":             "Сегодня",
+		"calendar.tomorrow":          "Завтра",
+		"calendar.nothing":           "Ничего не запланировано",
+		"calendar.this_week":         "На этой неделе:",
+		"calendar.birthday":          "День рождения: %s",
+		"calendar.birthday_years":    "День рождения: %s (%d)",
+		"calendar.anniversary":       "Годовщина: %s",
+		"calendar.anniversary_years": "Годовщина: %s (%d)",
+
+		"waste.title":     "Вывоз мусора",
+		"waste.none":      "Вывоз не запланирован",
+		"waste.today":     "сегодня",
+		"waste.tomorrow":  "завтра",
+		"waste.in_days":   "через %d дн.",
+		"waste.rest":      "Бытовые отходы",
+		"waste.bio":       "Биоотходы",
+		"waste.paper":     "Бумага",
+		"waste.packaging": "Упаковка",
+		"waste.glass":     "Стекло",
+		"waste.bulky":     "Крупногабарит",
+		"waste.hazardous": "Опасные отходы",
+
+		"quote.title": "Цитата дня",
+		"quote.open":  "«",
+		"quote.close": "»",
+
+		"transport.title":       "Отправления",
+		"transport.now":         "сейчас",
+		"transport.minutes":     "%d мин",
+		"transport.delay":       "+%d",
+		"transport.cancelled":   "отменён",
+		"transport.platform":    "пл. %s",
+		"transport.none":        "Отправлений нет",
+		"transport.unavailable": "Нет данных",
+
+		"commute.leave_in":      "Выходить через %d мин",
+		"commute.leave_now":     "Пора выходить!",
+		"commute.no_connection": "Нет рейсов",
+
+		"bikes.closed": "закрыта",
+	},
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// Каждый каталог переводит ровно те ключи, что есть в немецком: иначе
+// строка молча показывается по-немецки.
+func TestCatalogKeys(t *testing.T) {
+	want := slices.Sorted(maps.Keys(catalogs[langDE]))
+	for lang, catalog := range catalogs {
+		if lang == langDE {
+			continue
+		}
+		for _, key := range want {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("%s: missing %q", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := catalogs[langDE][key]; !ok {
+				t.Errorf("%s: %q is not in the de catalog", lang, key)
+			}
+		}
+	}
+}
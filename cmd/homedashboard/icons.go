@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+//
+// ---------- PIXEL ICONS ----------
+//
+
+// Иконки рисуются прямо на пиксельной сетке, без сглаживания:
+// на 1-битном экране любой полутон всё равно превратится в шум после дезеринга.
+const iconGrid = 24
+
+// iconBitmap — монохромная иконка, true = чёрный пиксель.
+type iconBitmap [iconGrid][iconGrid]bool
+
+// plot закрашивает (или стирает) все пиксели, центр которых попадает в фигуру.
+func (b *iconBitmap) plot(inside func(x, y float64) bool, on bool) {
+	for y := 0; y < iconGrid; y++ {
+		for x := 0; x < iconGrid; x++ {
+			if inside(float64(x)+0.5, float64(y)+0.5) {
+				b[y][x] = on
+			}
+		}
+	}
+}
+
+func (b *iconBitmap) disk(cx, cy, r float64) {
+	b.plot(func(x, y float64) bool {
+		return math.Hypot(x-cx, y-cy) <= r
+	}, true)
+}
+
+func (b *iconBitmap) rect(x0, y0, x1, y1 float64) {
+	b.plot(func(x, y float64) bool {
+		return x >= x0 && x < x1 && y >= y0 && y < y1
+	}, true)
+}
+
+func (b *iconBitmap) line(x0, y0, x1, y1, width float64) {
+	b.plot(func(x, y float64) bool {
+		return segmentDistance(x, y, x0, y0, x1, y1) <= width/2
+	}, true)
+}
+
+func (b *iconBitmap) polygon(pts ...[2]float64) {
+	b.plot(func(x, y float64) bool {
+		in := false
+		for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+			xi, yi := pts[i][0], pts[i][1]
+			xj, yj := pts[j][0], pts[j][1]
+			if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+				in = !in
+			}
+		}
+		return in
+	}, true)
+}
+
+// overlay кладёт верхний слой поверх текущего с белой обводкой в 1 пиксель,
+// чтобы, например, облако отделялось от солнца за ним.
+func (b *iconBitmap) overlay(top iconBitmap) {
+	for y := 0; y < iconGrid; y++ {
+		for x := 0; x < iconGrid; x++ {
+			if top.near(x, y) {
+				b[y][x] = false
+			}
+		}
+	}
+	for y := 0; y < iconGrid; y++ {
+		for x := 0; x < iconGrid; x++ {
+			if top[y][x] {
+				b[y][x] = true
+			}
+		}
+	}
+}
+
+func (b *iconBitmap) near(x, y int) bool {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if nx >= 0 && ny >= 0 && nx < iconGrid && ny < iconGrid && b[ny][nx] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func segmentDistance(px, py, x0, y0, x1, y1 float64) float64 {
+	dx, dy := x1-x0, y1-y0
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((px-x0)*dx+(py-y0)*dy)/l))
+	}
+	return math.Hypot(px-(x0+t*dx), py-(y0+t*dy))
+}
+
+//
+// ---------- SHAPES ----------
+//
+
+func sunShape(cx, cy, r float64) iconBitmap {
+	var b iconBitmap
+	b.disk(cx, cy, r)
+	for i := 0; i < 8; i++ {
+		a := float64(i) * math.Pi / 4
+		b.line(cx+math.Cos(a)*(r+2), cy+math.Sin(a)*(r+2),
+			cx+math.Cos(a)*(r+4), cy+math.Sin(a)*(r+4), 2)
+	}
+	return b
+}
+
+// moonShape — серп: диск, из которого вырезан сдвинутый вправо-вверх диск.
+func moonShape(cx, cy, r float64) iconBitmap {
+	var b iconBitmap
+	b.disk(cx, cy, r)
+	b.plot(func(x, y float64) bool {
+		return math.Hypot(x-cx-r*0.55, y-cy+r*0.45) <= r*0.85
+	}, false)
+	return b
+}
+
+// cloudShape рисует облако в прямоугольнике шириной w с нижней кромкой на bottom.
+func cloudShape(x, bottom, w float64) iconBitmap {
+	var b iconBitmap
+	h := w * 0.3
+	b.disk(x+h, bottom-h, h)
+	b.disk(x+w-h, bottom-h, h)
+	b.disk(x+w*0.38, bottom-h*1.3, w*0.22)
+	b.disk(x+w*0.64, bottom-h*1.5, w*0.28)
+	b.rect(x+h, bottom-2*h, x+w-h, bottom)
+	return b
+}
+
+func boltShape() iconBitmap {
+	var b iconBitmap
+	b.polygon([2]float64{12, 11}, [2]float64{8, 18}, [2]float64{11.5, 18},
+		[2]float64{10, 24}, [2]float64{16, 16}, [2]float64{12.5, 16}, [2]float64{15, 11})
+	return b
+}
+
+func (b *iconBitmap) drop(x, y float64) {
+	b.line(x+1, y, x-1, y+5, 2)
+}
+
+func (b *iconBitmap) flake(x, y float64) {
+	b.rect(x-1, y-1, x+1, y+1)
+	b.line(x-2, y, x+2, y, 1)
+	b.line(x, y-2, x, y+2, 1)
+}
+
+func (b *iconBitmap) dot(x, y float64) {
+	b.rect(x-1, y-1, x+1, y+1)
+}
+
+//
+// ---------- WMO WEATHER CODES ----------
+//
+
+type precipKind int
+
+const (
+	precipNone precipKind = iota
+	precipDrizzle
+	precipRain
+	precipHeavyRain
+	precipSleet
+	precipSnow
+	precipHeavySnow
+	precipGrains
+	precipHail
+)
+
+// weatherIcon возвращает иконку для WMO-кода. Ночью ясное небо, облачность
+// и ливни рисуются с луной вместо солнца; остальные коды от времени суток не зависят.
+func weatherIcon(code int, night bool) iconBitmap {
+	switch code {
+	case 0:
+		if night {
+			return moonShape(12, 12, 8)
+		}
+		return sunShape(12, 12, 5)
+	case 1:
+		b := celestial(night, 10, 10, 5)
+		b.overlay(cloudShape(10, 22, 13))
+		return b
+	case 2:
+		b := celestial(night, 8, 8, 4)
+		b.overlay(cloudShape(2, 21, 21))
+		return b
+	case 3:
+		return cloudShape(1, 18, 22)
+	case 45, 48:
+		return fogIcon(code == 48)
+	case 51, 53, 55:
+		return precipIcon(precipDrizzle, false, night)
+	case 56, 57, 66, 67:
+		return precipIcon(precipSleet, false, night)
+	case 61, 63:
+		return precipIcon(precipRain, false, night)
+	case 65:
+		return precipIcon(precipHeavyRain, false, night)
+	case 71, 73:
+		return precipIcon(precipSnow, false, night)
+	case 75:
+		return precipIcon(precipHeavySnow, false, night)
+	case 77:
+		return precipIcon(precipGrains, false, night)
+	case 80, 81:
+		return precipIcon(precipRain, true, night)
+	case 82:
+		return precipIcon(precipHeavyRain, true, night)
+	case 85:
+		return precipIcon(precipSnow, true, night)
+	case 86:
+		return precipIcon(precipHeavySnow, true, night)
+	case 95:
+		b := cloudShape(1, 15, 22)
+		b.overlay(boltShape())
+		return b
+	case 96, 99:
+		b := precipIcon(precipHail, false, night)
+		b.overlay(boltShape())
+		return b
+	}
+	var b iconBitmap
+	b.dot(12, 12)
+	return b
+}
+
+func celestial(night bool, cx, cy, r float64) iconBitmap {
+	if night {
+		return moonShape(cx, cy, r+2)
+	}
+	return sunShape(cx, cy, r)
+}
+
+func fogIcon(rime bool) iconBitmap {
+	var b iconBitmap
+	for _, y := range []float64{5, 11, 17} {
+		b.rect(2, y, 22, y+2)
+	}
+	b.rect(0, 8, 18, 10)
+	b.rect(6, 14, 24, 16)
+	if rime {
+		b.flake(20, 21)
+	}
+	return b
+}
+
+// precipIcon — облако с осадками под ним; для ливней за облаком
+// выглядывает солнце или луна.
+func precipIcon(kind precipKind, shower, night bool) iconBitmap {
+	var b iconBitmap
+	if shower {
+		b = celestial(night, 7, 6, 3)
+		b.overlay(cloudShape(3, 16, 20))
+	} else {
+		b = cloudShape(1, 15, 22)
+	}
+
+	switch kind {
+	case precipDrizzle:
+		for _, p := range [][2]float64{{7, 18}, {13, 18}, {10, 22}, {16, 22}} {
+			b.dot(p[0], p[1])
+		}
+	case precipRain:
+		for _, x := range []float64{7, 12, 17} {
+			b.drop(x, 17)
+		}
+	case precipHeavyRain:
+		for _, x := range []float64{5, 10, 15, 20} {
+			b.drop(x, 17)
+			b.drop(x-2.5, 22)
+		}
+	case precipSleet:
+		b.drop(7, 17)
+		b.flake(12, 20)
+		b.drop(17, 17)
+	case precipSnow:
+		for _, p := range [][2]float64{{6, 18}, {12, 21}, {18, 18}} {
+			b.flake(p[0], p[1])
+		}
+	case precipHeavySnow:
+		for _, p := range [][2]float64{{5, 18}, {11, 18}, {17, 18}, {8, 22}, {14, 22}, {20, 22}} {
+			b.flake(p[0], p[1])
+		}
+	case precipGrains:
+		for _, p := range [][2]float64{{5, 18}, {9, 20}, {13, 18}, {17, 20}, {7, 22}, {15, 22}} {
+			b.dot(p[0], p[1])
+		}
+	case precipHail:
+		for _, p := range [][2]float64{{5, 19}, {19, 19}, {7, 23}, {17, 23}} {
+			b.rect(p[0]-1, p[1]-1.5, p[0]+1.5, p[1]+1)
+		}
+	}
+	return b
+}
+
+//
+// ---------- OUTPUT ----------
+//
+
+// runs вызывает fn для каждой горизонтальной серии чёрных пикселей.
+func (b iconBitmap) runs(fn func(x, y, w int)) {
+	for y := 0; y < iconGrid; y++ {
+		for x := 0; x < iconGrid; {
+			if !b[y][x] {
+				x++
+				continue
+			}
+			start := x
+			for x < iconGrid && b[y][x] {
+				x++
+			}
+			fn(start, y, x-start)
+		}
+	}
+}
+
+// SVG отдаёт иконку для HTML-шаблонов. scale должен быть целым, чтобы
+// пиксели иконки ложились ровно на пиксели экрана.
+func (b iconBitmap) SVG(scale int) template.HTML {
+	var path strings.Builder
+	b.runs(func(x, y, w int) {
+		fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x, y, w, w)
+	})
+	size := iconGrid * scale
+	return template.HTML(fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges"><path d="%s" fill="currentColor"/></svg>`,
+		size, size, iconGrid, iconGrid, path.String()))
+}
+
+// drawIcon рисует иконку текущим цветом контекста gg, левый верхний угол в (x, y).
+func drawIcon(dc *gg.Context, b iconBitmap, x, y float64, scale int) {
+	s := float64(scale)
+	b.runs(func(px, py, w int) {
+		dc.DrawRectangle(x+float64(px)*s, y+float64(py)*s, float64(w)*s, s)
+	})
+	dc.Fill()
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ---------- INDOOR SENSORS ----------
+//
+
+// Показания комнатных датчиков приходят тремя путями: MQTT (indoor_mqtt.go),
+// нативный API ESPHome (indoor_esphome.go) и HTTP POST /indoor. Все они
+// складываются сюда, а погодная страница берёт плитки через indoorTiles.
+
+type indoorMetric string
+
+const (
+	metricTemperature indoorMetric = "temperature"
+	metricHumidity    indoorMetric = "humidity"
+	metricCO2         indoorMetric = "co2"
+)
+
+// parseIndoorMetric понимает и короткие имена из топиков/JSON, и то, как
+// их обычно называют в Home Assistant/ESPHome.
+func parseIndoorMetric(s string) (indoorMetric, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "temperature", "temp", "t":
+		return metricTemperature, true
+	case "humidity", "hum", "rh", "h":
+		return metricHumidity, true
+	case "co2", "carbon_dioxide", "eco2":
+		return metricCO2, true
+	}
+	return "", false
+}
+
+const (
+	indoorHistory      = 24 * time.Hour   // сколько истории держим для спарклайна
+	indoorSampleStep   = 10 * time.Minute // не чаще одной точки за шаг
+	indoorMaxAge       = 2 * time.Hour    // старше — комнату не показываем
+	indoorMaxRooms     = 4
+	indoorMaxTracked   = 32 // комнат в памяти: имя приходит из топика или запроса
+	indoorSparklineW   = 96
+	indoorSparklineH   = 22
+	indoorCO2Threshold = 1000 // ppm, после которого пора проветрить
+
+	// Переподключение к брокеру и устройствам
+	sensorRetryMin    = 5 * time.Second
+	sensorRetryMax    = 5 * time.Minute
+	sensorDialTimeout = 5 * time.Second
+)
+
+// startIndoorSources запускает подписки на настроенные источники.
+func startIndoorSources(ctx context.Context) {
+	if mqttBroker != "" {
+		go runMQTT(ctx, mqttBroker, mqttTopics)
+	}
+	for _, dev := range esphomeDevices {
+		go runESPHome(ctx, dev)
+	}
+}
+
+type indoorSample struct {
+	At    time.Time
+	Value float64
+}
+
+type indoorSeries struct {
+	Last    indoorSample
+	History []indoorSample
+}
+
+func (s *indoorSeries) add(at time.Time, v float64) {
+	if at.Before(s.Last.At) {
+		return // запоздавшее показание (retained-сообщение и т.п.)
+	}
+	s.Last = indoorSample{At: at, Value: v}
+	if n := len(s.History); n > 0 && at.Sub(s.History[n-1].At) < indoorSampleStep {
+		s.History[n-1].Value = v
+	} else {
+		s.History = append(s.History, s.Last)
+	}
+
+	cut := 0
+	for cut < len(s.History) && at.Sub(s.History[cut].At) > indoorHistory {
+		cut++
+	}
+	s.History = s.History[cut:]
+}
+
+type indoorRoom struct {
+	Name    string
+	Metrics map[indoorMetric]*indoorSeries
+}
+
+var (
+	indoorMu    sync.Mutex
+	indoorRooms = map[string]*indoorRoom{}
+)
+
+// recordIndoor — единая точка входа для всех источников показаний.
+func recordIndoor(room string, metric indoorMetric, value float64, at time.Time) {
+	room = strings.TrimSpace(room)
+	if room == "" || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	indoorMu.Lock()
+	defer indoorMu.Unlock()
+
+	key := strings.ToLower(room)
+	r, ok := indoorRooms[key]
+	if !ok {
+		makeRoomForIndoor(at)
+		r = &indoorRoom{Name: room, Metrics: map[indoorMetric]*indoorSeries{}}
+		indoorRooms[key] = r
+	}
+	s, ok := r.Metrics[metric]
+	if !ok {
+		s = &indoorSeries{}
+		r.Metrics[metric] = s
+	}
+	s.add(at, value)
+}
+
+// lastAt — время самого свежего показания комнаты.
+func (r *indoorRoom) lastAt() time.Time {
+	var last time.Time
+	for _, s := range r.Metrics {
+		if s.Last.At.After(last) {
+			last = s.Last.At
+		}
+	}
+	return last
+}
+
+// makeRoomForIndoor вызывается перед новой комнатой, под indoorMu.
+// Комнаты без свежих показаний на странице всё равно не видны — их
+// выбрасываем; если и свежих больше indoorMaxTracked (опечатки в топиках,
+// чужие датчики), уходит самая давно обновлявшаяся.
+func makeRoomForIndoor(now time.Time) {
+	for key, r := range indoorRooms {
+		if now.Sub(r.lastAt()) > indoorMaxAge {
+			delete(indoorRooms, key)
+		}
+	}
+	for len(indoorRooms) >= indoorMaxTracked {
+		var oldest string
+		for key, r := range indoorRooms {
+			if oldest == "" || r.lastAt().Before(indoorRooms[oldest].lastAt()) {
+				oldest = key
+			}
+		}
+		log.Printf("indoor: more than %d rooms, dropping %q", indoorMaxTracked, indoorRooms[oldest].Name)
+		delete(indoorRooms, oldest)
+	}
+}
+
+//
+// ---------- VIEW ----------
+//
+
+type IndoorTile struct {
+	Room      string
+	Temp      string
+	Humidity  string
+	CO2       string
+	CO2High   bool
+	Sparkline template.HTML
+}
+
+// indoorTiles — свежие комнаты по алфавиту, не больше indoorMaxRooms.
+func indoorTiles(now time.Time) []IndoorTile {
+	indoorMu.Lock()
+	defer indoorMu.Unlock()
+
+	var tiles []IndoorTile
+	for _, r := range indoorRooms {
+		fresh := func(m indoorMetric) (indoorSample, bool) {
+			s, ok := r.Metrics[m]
+			if !ok || now.Sub(s.Last.At) > indoorMaxAge {
+				return indoorSample{}, false
+			}
+			return s.Last, true
+		}
+
+		tile := IndoorTile{Room: r.Name}
+		if v, ok := fresh(metricTemperature); ok {
+			tile.Temp = fmt.Sprintf("%.1f°", v.Value)
+			tile.Sparkline = sparklineSVG(r.Metrics[metricTemperature].History)
+		}
+		if v, ok := fresh(metricHumidity); ok {
+			tile.Humidity = fmt.Sprintf("%.0f%%", v.Value)
+		}
+		if v, ok := fresh(metricCO2); ok {
+			tile.CO2 = fmt.Sprintf("%.0f ppm", v.Value)
+			tile.CO2High = v.Value >= indoorCO2Threshold
+			if tile.Sparkline == "" {
+				tile.Sparkline = sparklineSVG(r.Metrics[metricCO2].History)
+			}
+		}
+		if tile.Temp == "" && tile.Humidity == "" && tile.CO2 == "" {
+			continue
+		}
+		tiles = append(tiles, tile)
+	}
+
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i].Room < tiles[j].Room })
+	if len(tiles) > indoorMaxRooms {
+		tiles = tiles[:indoorMaxRooms]
+	}
+	return tiles
+}
+
+// sparklineSVG — ломаная за последние сутки без подписей, как в hourlyChartSVG.
+func sparklineSVG(history []indoorSample) template.HTML {
+	if len(history) < 2 {
+		return ""
+	}
+
+	lo, hi := history[0].Value, history[0].Value
+	for _, s := range history {
+		lo, hi = math.Min(lo, s.Value), math.Max(hi, s.Value)
+	}
+	span := math.Max(hi-lo, 0.5)
+	from := history[0].At
+	total := math.Max(history[len(history)-1].At.Sub(from).Seconds(), 1)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" shape-rendering="crispEdges">`,
+		indoorSparklineW, indoorSparklineH)
+	b.WriteString(`<polyline fill="none" stroke="#000" stroke-width="2" points="`)
+	for _, s := range history {
+		x := 1 + s.At.Sub(from).Seconds()/total*(indoorSparklineW-2)
+		y := indoorSparklineH - 2 - (s.Value-lo)/span*(indoorSparklineH-4)
+		fmt.Fprintf(&b, "%.0f,%.0f ", x, y)
+	}
+	b.WriteString(`"/></svg>`)
+	return template.HTML(b.String())
+}
+
+//
+// ---------- HTTP PUSH ----------
+//
+
+// handleIndoorPush принимает показания от датчиков, которые умеют только
+// HTTP (Shelly, самодельные ESP с http_request):
+//
+//	POST /indoor {"room":"Bad","temperature":22.4,"humidity":61}
+//	POST /indoor?room=Bad&co2=840
+//
+// С DASHBOARD_INDOOR_TOKEN датчик передаёт его в заголовке
+// "Authorization: Bearer …" или параметром token (Shelly умеет только URL).
+// Без токена принимаем только с самого Pi — например, от локального моста.
+var indoorToken = envString("DASHBOARD_INDOOR_TOKEN", "")
+
+func handleIndoorPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !indoorAuthorized(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	values := map[string]any{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&values); err != nil {
+			http.Error(w, "bad json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for k := range r.Form {
+			values[k] = r.Form.Get(k)
+		}
+	}
+
+	room, _ := values["room"].(string)
+	if room == "" {
+		http.Error(w, "room is required", http.StatusBadRequest)
+		return
+	}
+	n := recordIndoorValues(room, values, clock.Now())
+	if n == 0 {
+		http.Error(w, "no known metrics", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func indoorAuthorized(r *http.Request) bool {
+	if indoorToken == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	got := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		got = bearer
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(indoorToken)) == 1
+}
+
+// recordIndoorValues разбирает объект вида {"temperature": 21.5, ...}:
+// числа могут прийти и строками. Возвращает число принятых метрик.
+func recordIndoorValues(room string, values map[string]any, at time.Time) int {
+	n := 0
+	for k, raw := range values {
+		metric, ok := parseIndoorMetric(k)
+		if !ok {
+			continue
+		}
+		var v float64
+		switch x := raw.(type) {
+		case float64:
+			v = x
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+			if err != nil {
+				log.Printf("indoor %s: %s=%q is not a number", room, k, x)
+				continue
+			}
+			v = f
+		default:
+			continue
+		}
+		recordIndoor(room, metric, v, at)
+		n++
+	}
+	return n
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+//
+// ---------- ESPHOME NATIVE API ----------
+//
+
+// Комнатные датчики на ESPHome опрашиваем по их нативному API (порт 6053)
+// так же, как это делает Home Assistant. Только без шифрования: в конфиге
+// устройства должно быть `api:` без `encryption:`, как у e1001.yaml.
+//
+//	DASHBOARD_ESPHOME_DEVICES="Wohnzimmer=192.168.3.40; Bad=bad-sensor.local:6053"
+var (
+	esphomeDevices  = parseESPHomeDevices(envString("DASHBOARD_ESPHOME_DEVICES", ""))
+	esphomePassword = envString("DASHBOARD_ESPHOME_PASSWORD", "")
+)
+
+const (
+	esphomePort        = "6053"
+	esphomeIdleTimeout = 3 * time.Minute // устройство пингует каждые ~20 с
+	esphomeMaxFrameLen = 64 << 10
+)
+
+// Номера сообщений из api.proto ESPHome.
+const (
+	esphomeHelloRequest               = 1
+	esphomeHelloResponse              = 2
+	esphomeConnectRequest             = 3
+	esphomeConnectResponse            = 4
+	esphomeDisconnectRequest          = 5
+	esphomeDisconnectResponse         = 6
+	esphomePingRequest                = 7
+	esphomePingResponse               = 8
+	esphomeListEntitiesRequest        = 11
+	esphomeListEntitiesSensorResponse = 16
+	esphomeListEntitiesDoneResponse   = 19
+	esphomeSubscribeStatesRequest     = 20
+	esphomeSensorStateResponse        = 25
+)
+
+type esphomeDevice struct {
+	Room    string
+	Address string
+}
+
+func parseESPHomeDevices(s string) []esphomeDevice {
+	var devices []esphomeDevice
+	for _, item := range strings.Split(s, ";") {
+		room, addr, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			if item = strings.TrimSpace(item); item != "" {
+				log.Printf("config: esphome device %q must be room=host[:port]", item)
+			}
+			continue
+		}
+		addr = strings.TrimSpace(addr)
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, esphomePort)
+		}
+		devices = append(devices, esphomeDevice{Room: strings.TrimSpace(room), Address: addr})
+	}
+	return devices
+}
+
+// runESPHome держит подключение к одному устройству, как runMQTT.
+func runESPHome(ctx context.Context, dev esphomeDevice) {
+	retry := sensorRetryMin
+	for {
+		err := esphomeSession(ctx, dev, func() { retry = sensorRetryMin })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("esphome %s: %v, retry in %s", dev.Address, err, retry)
+
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return
+		}
+		retry = min(retry*2, sensorRetryMax)
+	}
+}
+
+func esphomeSession(ctx context.Context, dev esphomeDevice, connected func()) error {
+	dialer := net.Dialer{Timeout: sensorDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", dev.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// Всё рукопожатие отправляем сразу, ответы разбираем в общем цикле
+	var hello, connect []byte
+	hello = protoAppendString(hello, 1, "homedashboard")
+	hello = protoAppendVarint(hello, 2, 1) // api_version_major
+	hello = protoAppendVarint(hello, 3, 10)
+	if esphomePassword != "" {
+		connect = protoAppendString(connect, 1, esphomePassword)
+	}
+	var out []byte
+	out = esphomeAppendFrame(out, esphomeHelloRequest, hello)
+	out = esphomeAppendFrame(out, esphomeConnectRequest, connect)
+	out = esphomeAppendFrame(out, esphomeListEntitiesRequest, nil)
+	out = esphomeAppendFrame(out, esphomeSubscribeStatesRequest, nil)
+	conn.SetWriteDeadline(time.Now().Add(sensorDialTimeout))
+	if _, err := conn.Write(out); err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Time{})
+
+	// key сущности → метрика; состояния неизвестных ключей пропускаем
+	sensors := map[uint32]indoorMetric{}
+	r := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(esphomeIdleTimeout))
+		kind, msg, err := esphomeReadFrame(r)
+		if err != nil {
+			return err
+		}
+
+		switch kind {
+		case esphomeHelloResponse:
+			var name string
+			protoScan(msg, func(f protoField) error {
+				if f.Num == 4 {
+					name = f.String()
+				}
+				return nil
+			})
+			log.Printf("esphome %s: connected to %q for %s", dev.Address, name, dev.Room)
+			connected()
+
+		case esphomeConnectResponse:
+			invalid := false
+			protoScan(msg, func(f protoField) error {
+				invalid = invalid || (f.Num == 1 && f.Bool())
+				return nil
+			})
+			if invalid {
+				return errors.New("invalid API password")
+			}
+
+		case esphomeListEntitiesSensorResponse:
+			key, metric, ok := esphomeSensorMetric(msg)
+			if ok {
+				sensors[key] = metric
+			}
+
+		case esphomeListEntitiesDoneResponse:
+			if len(sensors) == 0 {
+				log.Printf("esphome %s: no temperature, humidity or CO2 sensors", dev.Address)
+			}
+
+		case esphomeSensorStateResponse:
+			var key uint32
+			var state float32
+			missing := false
+			if err := protoScan(msg, func(f protoField) error {
+				switch f.Num {
+				case 1:
+					key = uint32(f.Int)
+				case 2:
+					state = f.Float32()
+				case 3:
+					missing = f.Bool()
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if metric, ok := sensors[key]; ok && !missing {
+				recordIndoor(dev.Room, metric, float64(state), clock.Now())
+			}
+
+		case esphomePingRequest:
+			if _, err := conn.Write(esphomeAppendFrame(nil, esphomePingResponse, nil)); err != nil {
+				return err
+			}
+
+		case esphomeDisconnectRequest:
+			conn.Write(esphomeAppendFrame(nil, esphomeDisconnectResponse, nil))
+			return errors.New("device closed the connection")
+		}
+	}
+}
+
+// esphomeSensorMetric определяет метрику по device_class, единице измерения
+// или, на худой конец, по object_id.
+func esphomeSensorMetric(msg []byte) (uint32, indoorMetric, bool) {
+	var key uint32
+	var objectID, unit, class string
+	if err := protoScan(msg, func(f protoField) error {
+		switch f.Num {
+		case 1:
+			objectID = f.String()
+		case 2:
+			key = uint32(f.Int)
+		case 6:
+			unit = f.String()
+		case 9:
+			class = f.String()
+		}
+		return nil
+	}); err != nil {
+		return 0, "", false
+	}
+
+	if metric, ok := parseIndoorMetric(class); ok {
+		return key, metric, true
+	}
+	switch unit {
+	case "°C":
+		return key, metricTemperature, true
+	case "ppm":
+		return key, metricCO2, true
+	}
+	for _, m := range []indoorMetric{metricTemperature, metricHumidity, metricCO2} {
+		if strings.Contains(objectID, string(m)) {
+			return key, m, true
+		}
+	}
+	return 0, "", false
+}
+
+// Кадр без шифрования: 0x00, varint длины сообщения, varint типа, сообщение.
+func esphomeAppendFrame(b []byte, kind int, msg []byte) []byte {
+	b = append(b, 0)
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	b = binary.AppendUvarint(b, uint64(kind))
+	return append(b, msg...)
+}
+
+func esphomeReadFrame(r *bufio.Reader) (int, []byte, error) {
+	preamble, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	if preamble != 0 {
+		return 0, nil, fmt.Errorf("unexpected preamble 0x%02x (is API encryption enabled?)", preamble)
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	kind, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if size > esphomeMaxFrameLen {
+		return 0, nil, fmt.Errorf("frame too large: %d bytes", size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return 0, nil, err
+	}
+	return int(kind), msg, nil
+}
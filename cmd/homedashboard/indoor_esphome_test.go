@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func protoAppendFixed32(b []byte, num int, v uint32) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|protoFixed32)
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+// esphomeSensor — ListEntitiesSensorResponse: object_id, key, единица, класс.
+func esphomeSensor(objectID string, key uint32, unit, class string) []byte {
+	var b []byte
+	b = protoAppendString(b, 1, objectID)
+	b = protoAppendFixed32(b, 2, key)
+	b = protoAppendString(b, 6, unit)
+	return protoAppendString(b, 9, class)
+}
+
+func esphomeState(key uint32, v float32, missing bool) []byte {
+	b := protoAppendFixed32(nil, 1, key)
+	b = protoAppendFixed32(b, 2, math.Float32bits(v))
+	if missing {
+		b = protoAppendVarint(b, 3, 1)
+	}
+	return b
+}
+
+func TestESPHomeSession(t *testing.T) {
+	useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	useIndoorRooms(t)
+	swap(t, &esphomePassword, "")
+
+	addr, device := serveOnce(t, func(conn net.Conn, r *bufio.Reader) error {
+		// Всё рукопожатие клиент шлёт одним куском
+		var kinds []int
+		for range 4 {
+			kind, msg, err := esphomeReadFrame(r)
+			if err != nil {
+				return err
+			}
+			if kind == esphomeHelloRequest {
+				var client string
+				protoScan(msg, func(f protoField) error {
+					if f.Num == 1 {
+						client = f.String()
+					}
+					return nil
+				})
+				if client != "homedashboard" {
+					return fmt.Errorf("hello from %q", client)
+				}
+			}
+			kinds = append(kinds, kind)
+		}
+		want := []int{esphomeHelloRequest, esphomeConnectRequest, esphomeListEntitiesRequest, esphomeSubscribeStatesRequest}
+		if !slices.Equal(kinds, want) {
+			return fmt.Errorf("handshake %v, want %v", kinds, want)
+		}
+
+		var out []byte
+		out = esphomeAppendFrame(out, esphomeHelloResponse, protoAppendString(protoAppendVarint(nil, 1, 1), 4, "wohnzimmer-sensor"))
+		out = esphomeAppendFrame(out, esphomeConnectResponse, nil)
+		out = esphomeAppendFrame(out, esphomeListEntitiesSensorResponse, esphomeSensor("wohnzimmer_temp", 1, "°C", "temperature"))
+		out = esphomeAppendFrame(out, esphomeListEntitiesSensorResponse, esphomeSensor("wohnzimmer_rh", 2, "%", "humidity"))
+		out = esphomeAppendFrame(out, esphomeListEntitiesSensorResponse, esphomeSensor("scd40_co2", 3, "ppm", ""))
+		out = esphomeAppendFrame(out, esphomeListEntitiesSensorResponse, esphomeSensor("uptime", 4, "s", "duration"))
+		out = esphomeAppendFrame(out, esphomeListEntitiesDoneResponse, nil)
+		out = esphomeAppendFrame(out, esphomeSensorStateResponse, esphomeState(1, 21.75, false))
+		out = esphomeAppendFrame(out, esphomeSensorStateResponse, esphomeState(2, 55, false))
+		out = esphomeAppendFrame(out, esphomeSensorStateResponse, esphomeState(2, 0, true)) // missing_state
+		out = esphomeAppendFrame(out, esphomeSensorStateResponse, esphomeState(3, 912, false))
+		out = esphomeAppendFrame(out, esphomeSensorStateResponse, esphomeState(4, 3600, false))
+		out = esphomeAppendFrame(out, esphomePingRequest, nil)
+		conn.Write(out)
+
+		kind, _, err := esphomeReadFrame(r)
+		if err != nil {
+			return err
+		}
+		if kind != esphomePingResponse {
+			return fmt.Errorf("got message %d, want ping response", kind)
+		}
+		// Длина кадра — varint длиннее 64 бит
+		conn.Write([]byte{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+		r.ReadByte()
+		return nil
+	})
+
+	connected := false
+	err := esphomeSession(context.Background(), esphomeDevice{Room: "Wohnzimmer", Address: addr}, func() { connected = true })
+	if err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Errorf("session ended with %v, want varint overflow", err)
+	}
+	if err := <-device; err != nil {
+		t.Fatal("device:", err)
+	}
+	if !connected {
+		t.Error("connected callback not called")
+	}
+
+	for _, tt := range []struct {
+		metric indoorMetric
+		want   float64
+	}{
+		{metricTemperature, 21.75},
+		{metricHumidity, 55},
+		{metricCO2, 912},
+	} {
+		if got, ok := indoorValue("Wohnzimmer", tt.metric); !ok || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v", tt.metric, got, ok, tt.want)
+		}
+	}
+}
+
+func TestESPHomeReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		kind    int
+		size    int
+		wantErr string
+	}{
+		{"empty message", esphomeAppendFrame(nil, esphomePingRequest, nil), esphomePingRequest, 0, ""},
+		{"two byte length", esphomeAppendFrame(nil, esphomeSensorStateResponse, make([]byte, 300)), esphomeSensorStateResponse, 300, ""},
+		{"encrypted", []byte{0x01, 0x00, 0x10}, 0, 0, "is API encryption enabled"},
+		{"length overflow", []byte{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, 0, 0, "overflow"},
+		{"length cut", []byte{0, 0x80}, 0, 0, "EOF"},
+		{"too large", append([]byte{0}, binary.AppendUvarint(binary.AppendUvarint(nil, esphomeMaxFrameLen+1), 25)...), 0, 0, "frame too large"},
+		{"body cut", []byte{0, 5, 25, 1, 2}, 0, 0, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		kind, msg, err := esphomeReadFrame(bufio.NewReader(bytes.NewReader(tt.in)))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || kind != tt.kind || len(msg) != tt.size {
+			t.Errorf("%s: got %d, %d bytes, %v", tt.name, kind, len(msg), err)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ---------- MQTT ----------
+//
+
+// Подписчик MQTT 3.1.1 на минимуме протокола: CONNECT, SUBSCRIBE с QoS 0,
+// приём PUBLISH и PINGREQ. Понимает два вида топиков:
+//
+//	dashboard/indoor/Bad/temperature  22.4                 — комната и метрика в топике
+//	zigbee2mqtt/Bad                   {"temperature":22.4} — JSON, комната — последний уровень
+var (
+	mqttBroker   = envString("DASHBOARD_MQTT_BROKER", "") // host:port, пусто — выключено
+	mqttTopics   = envList("DASHBOARD_MQTT_TOPICS", "dashboard/indoor/#")
+	mqttUsername = envString("DASHBOARD_MQTT_USERNAME", "")
+	mqttPassword = envString("DASHBOARD_MQTT_PASSWORD", "")
+)
+
+const (
+	mqttKeepAlive    = 60 * time.Second
+	mqttMaxPacketLen = 256 << 10
+)
+
+// Типы пакетов (старшие 4 бита первого байта).
+const (
+	mqttConnect   = 1
+	mqttConnAck   = 2
+	mqttPublish   = 3
+	mqttPubAck    = 4
+	mqttSubscribe = 8
+	mqttSubAck    = 9
+	mqttPingReq   = 12
+	mqttPingResp  = 13
+)
+
+// runMQTT держит подписку, переподключаясь с растущей паузой.
+func runMQTT(ctx context.Context, broker string, topics []string) {
+	retry := sensorRetryMin
+	for {
+		err := mqttSession(ctx, broker, topics, func() { retry = sensorRetryMin })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("mqtt %s: %v, retry in %s", broker, err, retry)
+
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return
+		}
+		retry = min(retry*2, sensorRetryMax)
+	}
+}
+
+// mqttSession — одно подключение от CONNECT до первой ошибки.
+func mqttSession(ctx context.Context, broker string, topics []string, connected func()) error {
+	dialer := net.Dialer{Timeout: sensorDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Закрываем соединение при остановке, чтобы разбудить чтение
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+
+	conn.SetDeadline(time.Now().Add(sensorDialTimeout))
+	if _, err := conn.Write(mqttConnectPacket(mqttUsername, mqttPassword)); err != nil {
+		return err
+	}
+	kind, body, err := mqttReadPacket(r)
+	if err != nil {
+		return err
+	}
+	if kind>>4 != mqttConnAck || len(body) < 2 {
+		return fmt.Errorf("expected CONNACK, got packet type %d", kind>>4)
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("connection refused, code %d", body[1])
+	}
+
+	if _, err := conn.Write(mqttSubscribePacket(1, topics)); err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	log.Printf("mqtt %s: subscribed to %s", broker, strings.Join(topics, ", "))
+	connected()
+
+	waitingPong := false
+	for {
+		// Тишина дольше половины keep-alive — пингуем; нет ответа и на пинг — рвём
+		conn.SetReadDeadline(time.Now().Add(mqttKeepAlive / 2))
+		if _, err := r.Peek(1); err != nil {
+			var ne net.Error
+			if !errors.As(err, &ne) || !ne.Timeout() || waitingPong {
+				return err
+			}
+			if _, err := conn.Write([]byte{mqttPingReq << 4, 0}); err != nil {
+				return err
+			}
+			waitingPong = true
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(mqttKeepAlive))
+		kind, body, err := mqttReadPacket(r)
+		if err != nil {
+			return err
+		}
+		waitingPong = false
+
+		switch kind >> 4 {
+		case mqttPublish:
+			topic, payload, id, err := mqttParsePublish(kind, body)
+			if err != nil {
+				return err
+			}
+			handleMQTTMessage(topic, payload, clock.Now())
+			if id != 0 {
+				ack := []byte{mqttPubAck << 4, 2, 0, 0}
+				binary.BigEndian.PutUint16(ack[2:], id)
+				if _, err := conn.Write(ack); err != nil {
+					return err
+				}
+			}
+		case mqttSubAck, mqttPingResp:
+		default:
+			log.Printf("mqtt %s: ignoring packet type %d", broker, kind>>4)
+		}
+	}
+}
+
+// handleMQTTMessage раскладывает сообщение по комнате и метрике.
+func handleMQTTMessage(topic string, payload []byte, at time.Time) {
+	levels := strings.Split(topic, "/")
+	text := strings.TrimSpace(string(payload))
+
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		if len(levels) < 2 {
+			return
+		}
+		if metric, ok := parseIndoorMetric(levels[len(levels)-1]); ok {
+			recordIndoor(levels[len(levels)-2], metric, v, at)
+		}
+		return
+	}
+
+	values := map[string]any{}
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return
+	}
+	room := levels[len(levels)-1]
+	if r, ok := values["room"].(string); ok && r != "" {
+		room = r
+	}
+	recordIndoorValues(room, values, at)
+}
+
+//
+// ---------- WIRE FORMAT ----------
+//
+
+func mqttConnectPacket(username, password string) []byte {
+	flags := byte(0x02) // clean session
+	var payload []byte
+	payload = mqttAppendString(payload, fmt.Sprintf("homedashboard-%d", time.Now().UnixNano()%1e6))
+	if username != "" {
+		flags |= 0x80
+		payload = mqttAppendString(payload, username)
+		if password != "" {
+			flags |= 0x40
+			payload = mqttAppendString(payload, password)
+		}
+	}
+
+	var vh []byte
+	vh = mqttAppendString(vh, "MQTT")
+	vh = append(vh, 4, flags) // уровень протокола 4 = 3.1.1
+	vh = binary.BigEndian.AppendUint16(vh, uint16(mqttKeepAlive/time.Second))
+	return mqttPacket(mqttConnect<<4, append(vh, payload...))
+}
+
+func mqttSubscribePacket(id uint16, topics []string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, t := range topics {
+		body = mqttAppendString(body, t)
+		body = append(body, 0) // QoS 0
+	}
+	return mqttPacket(mqttSubscribe<<4|0x02, body)
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	out := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func mqttAppendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// mqttReadPacket возвращает первый байт заголовка целиком (тип и флаги)
+// и тело пакета.
+func mqttReadPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		mult *= 128
+	}
+	if n > mqttMaxPacketLen {
+		return 0, nil, fmt.Errorf("packet too large: %d bytes", n)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func mqttParsePublish(header byte, body []byte) (topic string, payload []byte, id uint16, err error) {
+	if len(body) < 2 {
+		return "", nil, 0, errors.New("short PUBLISH")
+	}
+	tl := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+tl {
+		return "", nil, 0, errors.New("short PUBLISH topic")
+	}
+	topic, rest := string(body[2:2+tl]), body[2+tl:]
+
+	if qos := (header >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, 0, errors.New("short PUBLISH packet id")
+		}
+		id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	return topic, rest, id, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveOnce принимает одно подключение и отдаёт его handle — вместо
+// брокера или устройства. Ошибку handle возвращает канал.
+func serveOnce(t *testing.T, handle func(conn net.Conn, r *bufio.Reader) error) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		done <- handle(conn, bufio.NewReader(conn))
+	}()
+	return ln.Addr().String(), done
+}
+
+// useIndoorRooms начинает тест с пустым набором комнат.
+func useIndoorRooms(t *testing.T) {
+	t.Helper()
+	swap(t, &indoorRooms, map[string]*indoorRoom{})
+}
+
+func indoorValue(room string, metric indoorMetric) (float64, bool) {
+	indoorMu.Lock()
+	defer indoorMu.Unlock()
+	r, ok := indoorRooms[strings.ToLower(room)]
+	if !ok || r.Metrics[metric] == nil {
+		return 0, false
+	}
+	return r.Metrics[metric].Last.Value, true
+}
+
+func TestMQTTSession(t *testing.T) {
+	useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	useIndoorRooms(t)
+	swap(t, &mqttUsername, "dashboard")
+	swap(t, &mqttPassword, "geheim")
+
+	topics := []string{"dashboard/indoor/#", "zigbee2mqtt/+"}
+	// zigbee2mqtt шлёт длинный JSON — длина пакета занимает два байта
+	z2m := `{"battery":97,"humidity":"48","linkquality":120,"temperature":21.5,"voltage":2995,` +
+		`"device":{"friendlyName":"Küche","ieeeAddr":"0x00158d0001a2b3c4","model":"WSDCGQ11LM"}}`
+
+	addr, broker := serveOnce(t, func(conn net.Conn, r *bufio.Reader) error {
+		kind, body, err := mqttReadPacket(r)
+		if err != nil {
+			return err
+		}
+		if kind != mqttConnect<<4 {
+			return fmt.Errorf("first packet type %d, want CONNECT", kind>>4)
+		}
+		// Имя протокола, уровень 4, флаги (логин, пароль, clean session), keep-alive
+		want := []byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0xC2, 0, 60}
+		if !bytes.HasPrefix(body, want) {
+			return fmt.Errorf("CONNECT header % x, want % x", body[:min(len(body), len(want))], want)
+		}
+		fields := mqttStrings(body[len(want):])
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], "homedashboard-") ||
+			fields[1] != "dashboard" || fields[2] != "geheim" {
+			return fmt.Errorf("CONNECT payload %q", fields)
+		}
+		conn.Write([]byte{mqttConnAck << 4, 2, 0, 0})
+
+		kind, body, err = mqttReadPacket(r)
+		if err != nil {
+			return err
+		}
+		if kind != mqttSubscribe<<4|0x02 {
+			return fmt.Errorf("second packet 0x%02x, want SUBSCRIBE with flags 0x2", kind)
+		}
+		// Идентификатор 1, затем топики с QoS 0
+		var got []string
+		for rest := body[2:]; len(rest) > 2; {
+			n := int(binary.BigEndian.Uint16(rest))
+			got = append(got, fmt.Sprintf("%s/%d", rest[2:2+n], rest[2+n]))
+			rest = rest[3+n:]
+		}
+		if body[1] != 1 || !slices.Equal(got, []string{"dashboard/indoor/#/0", "zigbee2mqtt/+/0"}) {
+			return fmt.Errorf("SUBSCRIBE id %d, topics %q", body[1], got)
+		}
+		conn.Write([]byte{mqttSubAck << 4, 4, 0, 1, 0, 0})
+
+		conn.Write(mqttPacket(mqttPublish<<4,
+			append(mqttAppendString(nil, "dashboard/indoor/Bad/temperature"), "22.4"...)))
+		conn.Write(mqttPacket(mqttPublish<<4, append(mqttAppendString(nil, "dashboard/indoor/Bad/co2"), " 840\n"...)))
+		conn.Write(mqttPacket(mqttPublish<<4, append(mqttAppendString(nil, "dashboard/indoor/Bad/pressure"), "1013"...)))
+
+		// QoS 1 — ждём PUBACK с тем же идентификатором
+		pub := mqttAppendString(nil, "zigbee2mqtt/Küche")
+		pub = binary.BigEndian.AppendUint16(pub, 7)
+		packet := mqttPacket(mqttPublish<<4|0x02, append(pub, z2m...))
+		if packet[1]&0x80 == 0 {
+			return errors.New("fixture too short for a two-byte length")
+		}
+		conn.Write(packet)
+		kind, body, err = mqttReadPacket(r)
+		if err != nil {
+			return err
+		}
+		if kind != mqttPubAck<<4 || !bytes.Equal(body, []byte{0, 7}) {
+			return fmt.Errorf("got 0x%02x % x, want PUBACK 7", kind, body)
+		}
+
+		// Длина длиннее четырёх байт — сессия должна оборваться
+		conn.Write([]byte{mqttPublish << 4, 0xff, 0xff, 0xff, 0xff, 0x01})
+		r.ReadByte() // ждём, пока клиент закроет соединение
+		return nil
+	})
+
+	connected := false
+	err := mqttSession(context.Background(), addr, topics, func() { connected = true })
+	if err == nil || !strings.Contains(err.Error(), "malformed remaining length") {
+		t.Errorf("session ended with %v, want malformed remaining length", err)
+	}
+	if err := <-broker; err != nil {
+		t.Fatal("broker:", err)
+	}
+	if !connected {
+		t.Error("connected callback not called")
+	}
+
+	for _, tt := range []struct {
+		room   string
+		metric indoorMetric
+		want   float64
+		ok     bool
+	}{
+		{"Bad", metricTemperature, 22.4, true},
+		{"Bad", metricCO2, 840, true},
+		{"Bad", metricHumidity, 0, false},
+		{"Küche", metricTemperature, 21.5, true},
+		{"Küche", metricHumidity, 48, true},
+	} {
+		if got, ok := indoorValue(tt.room, tt.metric); got != tt.want || ok != tt.ok {
+			t.Errorf("%s %s = %v, %v; want %v, %v", tt.room, tt.metric, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMQTTSessionRefused(t *testing.T) {
+	addr, broker := serveOnce(t, func(conn net.Conn, r *bufio.Reader) error {
+		if _, _, err := mqttReadPacket(r); err != nil {
+			return err
+		}
+		_, err := conn.Write([]byte{mqttConnAck << 4, 2, 0, 5}) // not authorized
+		return err
+	})
+	err := mqttSession(context.Background(), addr, []string{"#"}, func() { t.Error("connected") })
+	if err == nil || !strings.Contains(err.Error(), "connection refused, code 5") {
+		t.Errorf("err = %v, want refusal", err)
+	}
+	if err := <-broker; err != nil {
+		t.Fatal("broker:", err)
+	}
+}
+
+func TestMQTTReadPacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		wantLen int
+		wantErr string
+	}{
+		{"empty body", []byte{0xd0, 0x00}, 0, ""},
+		{"one byte length", mqttPacket(0x30, make([]byte, 127)), 127, ""},
+		{"two byte length", mqttPacket(0x30, make([]byte, 128)), 128, ""},
+		{"three byte length", mqttPacket(0x30, make([]byte, 16384)), 16384, ""},
+		{"five byte length", []byte{0x30, 0x80, 0x80, 0x80, 0x80, 0x01}, 0, "malformed remaining length"},
+		{"too large", []byte{0x30, 0xff, 0xff, 0xff, 0x7f}, 0, "packet too large"},
+		{"length cut", []byte{0x30, 0x80}, 0, "EOF"},
+		{"body cut", []byte{0x30, 0x05, 'a', 'b'}, 0, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		kind, body, err := mqttReadPacket(bufio.NewReader(bytes.NewReader(tt.in)))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || kind != tt.in[0] || len(body) != tt.wantLen {
+			t.Errorf("%s: got 0x%02x, %d bytes, %v", tt.name, kind, len(body), err)
+		}
+	}
+}
+
+func TestMQTTParsePublish(t *testing.T) {
+	topic := mqttAppendString(nil, "a/b")
+	tests := []struct {
+		name    string
+		header  byte
+		body    []byte
+		topic   string
+		payload string
+		id      uint16
+		wantErr bool
+	}{
+		{"qos 0", mqttPublish << 4, append(slices.Clone(topic), "1.5"...), "a/b", "1.5", 0, false},
+		{"qos 1", mqttPublish<<4 | 0x02, append(slices.Clone(topic), 0, 9, '1'), "a/b", "1", 9, false},
+		{"retained, empty", mqttPublish<<4 | 0x01, slices.Clone(topic), "a/b", "", 0, false},
+		{"no topic length", mqttPublish << 4, []byte{0}, "", "", 0, true},
+		{"topic cut", mqttPublish << 4, []byte{0, 9, 'a'}, "", "", 0, true},
+		{"qos 1 without id", mqttPublish<<4 | 0x02, append(slices.Clone(topic), 0), "", "", 0, true},
+	}
+	for _, tt := range tests {
+		topic, payload, id, err := mqttParsePublish(tt.header, tt.body)
+		if (err != nil) != tt.wantErr || topic != tt.topic || string(payload) != tt.payload || id != tt.id {
+			t.Errorf("%s: got %q %q %d %v", tt.name, topic, payload, id, err)
+		}
+	}
+}
+
+// mqttStrings разбирает подряд идущие строки с длиной в два байта.
+func mqttStrings(b []byte) []string {
+	var out []string
+	for len(b) >= 2 {
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+n {
+			break
+		}
+		out = append(out, string(b[2:2+n]))
+		b = b[2+n:]
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleIndoorPush(t *testing.T) {
+	useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name   string
+		token  string
+		remote string
+		method string
+		target string
+		header map[string]string
+		body   string
+		status int
+		temp   float64 // 0 — не записано
+	}{
+		{"localhost form", "", "127.0.0.1:51234", "POST", "/indoor?room=Bad&temperature=22.4", nil, "", 204, 22.4},
+		{"localhost v6 json", "", "[::1]:51234", "POST", "/indoor",
+			map[string]string{"Content-Type": "application/json"}, `{"room":"Bad","temp":"21.0","humidity":60}`, 204, 21.0},
+		{"lan without token", "", "192.168.3.40:51234", "POST", "/indoor?room=Bad&temperature=30", nil, "", 403, 0},
+		{"token in query", "s3cret", "192.168.3.40:51234", "POST", "/indoor?room=Bad&temperature=23&token=s3cret", nil, "", 204, 23},
+		{"bearer token", "s3cret", "192.168.3.40:51234", "POST", "/indoor?room=Bad&t=24",
+			map[string]string{"Authorization": "Bearer s3cret"}, "", 204, 24},
+		{"wrong token", "s3cret", "192.168.3.40:51234", "POST", "/indoor?room=Bad&temperature=30&token=guess", nil, "", 403, 0},
+		// С токеном и localhost обязан его передать
+		{"localhost with token set", "s3cret", "127.0.0.1:51234", "POST", "/indoor?room=Bad&temperature=30", nil, "", 403, 0},
+		{"get", "", "127.0.0.1:51234", "GET", "/indoor?room=Bad&temperature=30", nil, "", 405, 0},
+		{"no room", "", "127.0.0.1:51234", "POST", "/indoor?temperature=30", nil, "", 400, 0},
+		{"no metrics", "", "127.0.0.1:51234", "POST", "/indoor?room=Bad&pressure=1013", nil, "", 400, 0},
+		{"bad json", "", "127.0.0.1:51234", "POST", "/indoor",
+			map[string]string{"Content-Type": "application/json"}, `{"room":`, 400, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useIndoorRooms(t)
+			swap(t, &indoorToken, tt.token)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handleIndoorPush(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			got, ok := indoorValue("Bad", metricTemperature)
+			if tt.temp == 0 && ok || tt.temp != 0 && got != tt.temp {
+				t.Errorf("temperature = %v, %v; want %v", got, ok, tt.temp)
+			}
+		})
+	}
+}
+
+func TestRecordIndoorLimit(t *testing.T) {
+	useIndoorRooms(t)
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	// Старые комнаты уходят, как только появляется новая
+	recordIndoor("Keller", metricTemperature, 14, start)
+	recordIndoor("Bad", metricTemperature, 22, start.Add(indoorMaxAge))
+	recordIndoor("Flur", metricTemperature, 19, start.Add(indoorMaxAge+time.Minute))
+	if _, ok := indoorValue("Keller", metricTemperature); ok {
+		t.Error("stale room kept")
+	}
+	if _, ok := indoorValue("Bad", metricTemperature); !ok {
+		t.Error("fresh room dropped")
+	}
+
+	// Поток новых имён: держим indoorMaxTracked, выпадают давно молчащие
+	at := start.Add(indoorMaxAge + 2*time.Minute)
+	recordIndoor("Bad", metricHumidity, 60, at) // Bad обновился позже Flur
+	for i := range 2 * indoorMaxTracked {
+		recordIndoor(fmt.Sprintf("sensor-%d", i), metricTemperature, 20, at.Add(time.Duration(i)*time.Second))
+	}
+	indoorMu.Lock()
+	n := len(indoorRooms)
+	indoorMu.Unlock()
+	if n != indoorMaxTracked {
+		t.Errorf("%d rooms tracked, want %d", n, indoorMaxTracked)
+	}
+	last := fmt.Sprintf("sensor-%d", 2*indoorMaxTracked-1)
+	if _, ok := indoorValue(last, metricTemperature); !ok {
+		t.Errorf("%s dropped", last)
+	}
+	if _, ok := indoorValue("Flur", metricTemperature); ok {
+		t.Error("Flur must go before newer rooms")
+	}
+
+	// Новые показания известной комнаты никого не вытесняют
+	first := fmt.Sprintf("sensor-%d", indoorMaxTracked) // самая давняя из оставшихся
+	recordIndoor(last, metricCO2, 900, at.Add(time.Minute))
+	if _, ok := indoorValue(first, metricTemperature); !ok {
+		t.Errorf("update of a known room evicted %s", first)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"context"
 	"embed"
 	"encoding/base64"
+	"fmt"
 	"html/template"
+	"image"
 	"image/png"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
@@ -19,19 +22,28 @@ import (
 var templateFS embed.FS
 
 var (
-	quoteTpl     *template.Template
-	transportTpl *template.Template
-	weatherTpl   *template.Template
-	calendarTpl  *template.Template
-	rootCtx      context.Context
-	browser      context.Context
+	quoteTpl          *template.Template
+	transportTpl      *template.Template
+	weatherTpl        *template.Template
+	weatherCompareTpl *template.Template
+	calendarTpls      map[calendarLayout]*template.Template
+	warningsTpl       *template.Template
+	rootCtx           context.Context
+	browser           context.Context
 )
 
 func main() {
 	quoteTpl = template.Must(template.ParseFS(templateFS, "templates/quote.html"))
 	transportTpl = template.Must(template.ParseFS(templateFS, "templates/transport.html"))
 	weatherTpl = template.Must(template.ParseFS(templateFS, "templates/weather.html"))
-	calendarTpl = template.Must(template.ParseFS(templateFS, "templates/calendar.html"))
+	weatherCompareTpl = template.Must(template.ParseFS(templateFS, "templates/weather_compare.html"))
+	calendarTpls = map[calendarLayout]*template.Template{
+		layoutList:   template.Must(template.ParseFS(templateFS, "templates/calendar.html")),
+		layoutAgenda: template.Must(template.ParseFS(templateFS, "templates/calendar_agenda.html")),
+		layoutWeek:   template.Must(template.ParseFS(templateFS, "templates/calendar_week.html")),
+		layoutMonth:  template.Must(template.ParseFS(templateFS, "templates/calendar_month.html")),
+	}
+	warningsTpl = template.Must(template.ParseFS(templateFS, "templates/warnings.html"))
 	rootCtx, _ = chromedp.NewExecAllocator(context.Background(),
 		chromedp.Flag("headless", true),
 		chromedp.Flag("disable-gpu", true),
@@ -41,6 +53,7 @@ func main() {
 	browser, _ = chromedp.NewContext(rootCtx)
 	loadAllImages(context.Background())
 	startBackgroundRenderer(browser)
+	startIndoorSources(context.Background())
 
 	http.HandleFunc("/dashboard.bmp", handleDashboardBMP)
 	http.HandleFunc("/quote.bmp", handleQuoteBMP)
@@ -49,36 +62,72 @@ func main() {
 	http.HandleFunc("/photo.bmp", handlePhotoBMP)
 	http.HandleFunc("/stocks.bmp", handleStocksBMP)
 	http.HandleFunc("/calendar.bmp", handleCalendarBMP)
+	http.HandleFunc("/airquality.bmp", handleAirQualityBMP)
+	http.HandleFunc("/warnings.bmp", handleWarningsBMP)
+	http.HandleFunc("/waste.bmp", handleWasteBMP)
+	http.HandleFunc("/indoor", handleIndoorPush)
 
 	log.Println("Listening on https://<PI-IP>:8443 ...")
 	log.Fatal(http.ListenAndServe(":8443", nil))
 }
 
-var lastPage = 0
+var (
+	lastPage    = 0
+	weatherPage = 0 // какое место показывать в слоте погоды
+)
 
 func handleDashboardBMP(w http.ResponseWriter, r *http.Request) {
 	from := parseClock("07:15")
 	to := parseClock("07:40")
 	now := nowInMinutes()
-	if now.Between(from, to) {
+	if preemptingWarning(requestLang(r)) {
+		handleWarningsBMP(w, r)
+		return
+	}
+	if commuteActive() || (len(commuteRoutes) == 0 && now.Between(from, to)) {
 		handleTransportBMP(w, r)
 		return
+	} else if wastePinned() {
+		handleWasteBMP(w, r)
+		return
 	} else {
 		if lastPage == 0 {
-			handleWeatherBMP(w, r)
+			serveCachedPage(w, r, &weatherPages, weatherPage)
+			weatherPage++
+			if weatherPage < weatherPages.Len(requestLang(r)) {
+				return // остальные места показываем подряд, не двигая ротацию
+			}
+			weatherPage = 0
 		} else if lastPage == 1 {
 			handleQuoteBMP(w, r)
 		} else if lastPage == 2 {
 			handleStocksBMP(w, r)
 		} else if lastPage == 3 {
 			handleCalendarBMP(w, r)
+		} else if lastPage == 4 {
+			handleAirQualityBMP(w, r)
+		} else if lastPage == 5 {
+			handleWasteBMP(w, r)
+		}
+		pages := 5
+		if wasteConfigured() {
+			pages++ // Abfuhrkalender — только если задан
 		}
-		lastPage = (lastPage + 1) % 4
+		lastPage = (lastPage + 1) % pages
 	}
 }
 
 func serveCachedImage(w http.ResponseWriter, r *http.Request, cache *CachedImage) {
-	data, updatedAt := cache.Get()
+	data, updatedAt := cache.Get(requestLang(r))
+	serveImage(w, data, updatedAt)
+}
+
+func serveCachedPage(w http.ResponseWriter, r *http.Request, cache *CachedPages, page int) {
+	data, updatedAt := cache.Get(requestLang(r), page)
+	serveImage(w, data, updatedAt)
+}
+
+func serveImage(w http.ResponseWriter, data []byte, updatedAt time.Time) {
 	if data == nil {
 		http.Error(w, "image not ready", http.StatusServiceUnavailable)
 		return
@@ -86,14 +135,32 @@ func serveCachedImage(w http.ResponseWriter, r *http.Request, cache *CachedImage
 
 	w.Header().Set("Content-Type", "image/bmp")
 	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
-	w.Header().Set("Last-Modified", updatedAt.Format(http.TimeFormat))
+	w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
 	if _, err := w.Write(data); err != nil {
 		log.Println("write image error:", err)
 	}
 }
 
-func htmlToBMP(html string) ([]byte, error) {
-	dataURL := "data:text/html;base64," + base64.StdEncoding.EncodeToString([]byte(html))
+// htmlToBMP рендерит страницу и дорисовывает баннер активного предупреждения.
+func htmlToBMP(html string, lang Lang) ([]byte, error) {
+	img, err := screenshotHTML(html)
+	if err != nil {
+		return nil, err
+	}
+	return encodeScreenBMP(withWarningBanner(img, lang))
+}
+
+func htmlToBMPNoBanner(html string) ([]byte, error) {
+	img, err := screenshotHTML(html)
+	if err != nil {
+		return nil, err
+	}
+	return encodeScreenBMP(img)
+}
+
+func screenshotHTML(html string) (image.Image, error) {
+	html = injectFonts(html)
+	dataURL := "data:text/html;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte(html))
 
 	tabCtx, tabCancel := chromedp.NewContext(browser)
 	defer tabCancel()
@@ -118,7 +185,10 @@ func htmlToBMP(html string) ([]byte, error) {
 		log.Println("png decode:", err)
 		return nil, err
 	}
+	return img, nil
+}
 
+func encodeScreenBMP(img image.Image) ([]byte, error) {
 	bmp, err := encode1bppBMP(ditherFloydSteinbergHybrid(img, false))
 	if err != nil {
 		log.Println("bmp encode:", err)
@@ -138,8 +208,21 @@ func parseClock(hhmm string) TimeOfDay {
 	return TimeOfDay(t.Hour()*60 + t.Minute())
 }
 
+// parseClockSpan разбирает интервал "HH:MM-HH:MM" из конфига.
+func parseClockSpan(s string) (TimeOfDay, TimeOfDay, error) {
+	from, to, _ := strings.Cut(s, "-")
+	f, err1 := time.Parse("15:04", strings.TrimSpace(from))
+	t, err2 := time.Parse("15:04", strings.TrimSpace(to))
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("%q must be HH:MM-HH:MM", s)
+	}
+	return TimeOfDay(f.Hour()*60 + f.Minute()), TimeOfDay(t.Hour()*60 + t.Minute()), nil
+}
+
+// nowInMinutes — время суток по часам дисплея: в день перевода часов
+// расписание идёт по настенному времени, а не по UTC.
 func nowInMinutes() TimeOfDay {
-	now := time.Now()
+	now := displayNow()
 	return TimeOfDay(now.Hour()*60 + now.Minute())
 }
 
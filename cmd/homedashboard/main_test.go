@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useFakeClock останавливает часы на время теста.
+func useFakeClock(t *testing.T, at time.Time) *FakeClock {
+	t.Helper()
+	fc := NewFakeClock(at)
+	swap(t, &clock, Clock(fc))
+	return fc
+}
+
+// swap подменяет глобальную настройку и возвращает её после теста.
+func swap[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	prev := *p
+	*p = v
+	t.Cleanup(func() { *p = prev })
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// berlin — время в поясе дисплея по умолчанию.
+func berlin(t *testing.T, year int, month time.Month, day, hour, min int) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Date(year, month, day, hour, min, 0, 0, loc)
+}
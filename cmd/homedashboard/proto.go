@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//
+// ---------- PROTOBUF ----------
+//
+
+// Ровно столько protobuf, сколько нужно для нескольких сообщений с
+// известными номерами полей — тянуть ради этого генератор незачем.
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+type protoField struct {
+	Num   int
+	Wire  int
+	Int   uint64 // varint, fixed32 и fixed64
+	Bytes []byte // length-delimited
+}
+
+func (f protoField) Float32() float32 { return math.Float32frombits(uint32(f.Int)) }
+func (f protoField) Float64() float64 { return math.Float64frombits(f.Int) }
+func (f protoField) Bool() bool       { return f.Int != 0 }
+func (f protoField) String() string   { return string(f.Bytes) }
+
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+// protoScan вызывает fn для каждого поля сообщения по порядку.
+func protoScan(b []byte, fn func(f protoField) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+
+		f := protoField{Num: int(key >> 3), Wire: int(key & 7)}
+		switch f.Wire {
+		case protoVarint:
+			f.Int, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			b = b[n:]
+		case protoFixed64:
+			if len(b) < 8 {
+				return errProtoTruncated
+			}
+			f.Int, b = binary.LittleEndian.Uint64(b), b[8:]
+		case protoFixed32:
+			if len(b) < 4 {
+				return errProtoTruncated
+			}
+			f.Int, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errProtoTruncated
+			}
+			f.Bytes, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", f.Wire)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func protoAppendVarint(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func protoAppendString(b []byte, num int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProtoScan(t *testing.T) {
+	var msg []byte
+	msg = protoAppendVarint(msg, 1, 150)
+	msg = protoAppendString(msg, 2, "testing")
+	msg = protoAppendFixed32(msg, 3, math.Float32bits(21.5))
+	msg = binary.AppendUvarint(msg, 4<<3|protoFixed64)
+	msg = binary.LittleEndian.AppendUint64(msg, math.Float64bits(-0.25))
+	msg = protoAppendVarint(msg, 5, 1<<63)
+
+	var got []protoField
+	if err := protoScan(msg, func(f protoField) error {
+		got = append(got, f)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 ||
+		got[0].Num != 1 || got[0].Int != 150 ||
+		got[1].Num != 2 || got[1].String() != "testing" ||
+		got[2].Num != 3 || got[2].Float32() != 21.5 ||
+		got[3].Num != 4 || got[3].Float64() != -0.25 ||
+		got[4].Num != 5 || got[4].Int != 1<<63 {
+		t.Errorf("got %+v", got)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	if err := protoScan(msg, func(protoField) error { n++; return stop }); err != stop || n != 1 {
+		t.Errorf("callback error: got %v after %d fields", err, n)
+	}
+}
+
+func TestProtoScanMalformed(t *testing.T) {
+	overflow := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	tests := []struct {
+		name    string
+		in      []byte
+		wantErr string
+	}{
+		{"key cut", []byte{0x80}, "truncated"},
+		{"key overflow", overflow, "truncated"},
+		{"varint cut", []byte{1 << 3, 0x96}, "truncated"},
+		{"varint overflow", append([]byte{1 << 3}, overflow...), "truncated"},
+		{"length past end", []byte{2<<3 | protoBytes, 5, 'a', 'b'}, "truncated"},
+		{"length overflow", append([]byte{2<<3 | protoBytes}, overflow...), "truncated"},
+		{"huge length", append([]byte{2<<3 | protoBytes}, binary.AppendUvarint(nil, math.MaxUint64)...), "truncated"},
+		{"fixed32 cut", []byte{3<<3 | protoFixed32, 1, 2}, "truncated"},
+		{"fixed64 cut", []byte{4<<3 | protoFixed64, 1, 2, 3, 4}, "truncated"},
+		{"group", []byte{5<<3 | 3}, "unsupported wire type 3"},
+	}
+	for _, tt := range tests {
+		err := protoScan(tt.in, func(protoField) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -3,27 +3,157 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
-func renderQuoteBMP(ctx context.Context) ([]byte, error) {
-	resp, err := http.Get("https://api.zitat-service.de/v1/quote?language=de")
+//
+// ---------- PROVIDERS ----------
+//
+
+// Quote — цитата для страницы, независимо от источника.
+type Quote struct {
+	Text   string
+	Author string
+}
+
+// QuoteProvider — источник цитат. Провайдеры опрашиваются по порядку
+// DASHBOARD_QUOTE_PROVIDERS, пока кто-нибудь не отдаст цитату: по умолчанию
+// zitat-service, а при сбое — своя библиотека (quote_library.go).
+type QuoteProvider interface {
+	Name() string
+	Quote(ctx context.Context, lang Lang) (Quote, error)
+}
+
+var quoteProviders = map[string]QuoteProvider{
+	"zitat-service": zitatServiceProvider{BaseURL: "https://api.zitat-service.de/v1"},
+	"library":       quoteLibrary,
+}
+
+var quoteProviderNames = envList("DASHBOARD_QUOTE_PROVIDERS", "zitat-service,library")
+
+// Цитата меняется раз в DASHBOARD_QUOTE_INTERVAL минут, а не на каждом
+// фоновом рендере: иначе API дёргается каждые 20 секунд, а история
+// библиотеки заполняется цитатами, которых никто не увидел.
+var quoteInterval = envMinutes("DASHBOARD_QUOTE_INTERVAL", 30)
+
+type shownQuote struct {
+	Quote Quote
+	At    time.Time
+}
+
+var (
+	quoteMu       sync.Mutex
+	currentQuotes = map[Lang]shownQuote{}
+)
+
+func loadQuote(ctx context.Context, lang Lang) (Quote, error) {
+	now := clock.Now()
+	quoteMu.Lock()
+	cur, ok := currentQuotes[lang]
+	quoteMu.Unlock()
+	if ok && now.Sub(cur.At) < quoteInterval {
+		return cur.Quote, nil
+	}
+
+	q, err := nextQuote(ctx, lang)
 	if err != nil {
-		log.Println("error fetching:", err)
-		return nil, fmt.Errorf("unable to fetch quotes: %w", err)
+		if ok {
+			log.Printf("quote %s: %v, keeping the current one", lang, err)
+			return cur.Quote, nil
+		}
+		return Quote{}, err
 	}
-	defer resp.Body.Close()
+	quoteMu.Lock()
+	currentQuotes[lang] = shownQuote{Quote: q, At: now}
+	quoteMu.Unlock()
+	return q, nil
+}
+
+// nextQuote опрашивает провайдеров по порядку.
+func nextQuote(ctx context.Context, lang Lang) (Quote, error) {
+	var errs []error
+	for _, name := range quoteProviderNames {
+		provider, ok := quoteProviders[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown quote provider", name))
+			continue
+		}
+		q, err := provider.Quote(ctx, lang)
+		if err == nil && strings.TrimSpace(q.Text) == "" {
+			err = errors.New("empty quote")
+		}
+		if errors.Is(err, errQuoteLang) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue // не сбой: просто следующий провайдер
+		}
+		if err != nil {
+			log.Printf("quote provider %s failed: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		return q, nil
+	}
+	if len(errs) == 0 {
+		return Quote{}, errors.New("no quote providers configured")
+	}
+	return Quote{}, errors.Join(errs...)
+}
+
+//
+// ---------- ZITAT-SERVICE ----------
+//
+
+// Языки, на которых у zitat-service есть цитаты. Русского среди них нет:
+// для ru провайдер отказывается, и цитату даёт библиотека, где русские
+// есть, а не zitat-service по-английски.
+var quoteLanguages = []Lang{"de", "en", "es", "ja", "uk"}
+
+var errQuoteLang = errors.New("no quotes in this language")
+
+type zitatServiceProvider struct {
+	BaseURL string
+}
+
+func (zitatServiceProvider) Name() string { return "zitat-service" }
+
+type zitatServiceQuote struct {
+	Quote      string `json:"quote"`
+	AuthorName string `json:"authorName"`
+}
+
+func (p zitatServiceProvider) Quote(ctx context.Context, lang Lang) (Quote, error) {
+	if !slices.Contains(quoteLanguages, lang) {
+		return Quote{}, fmt.Errorf("%s: %w", lang, errQuoteLang)
+	}
+	var raw zitatServiceQuote
+	if err := getJSON(ctx, p.BaseURL+"/quote?language="+url.QueryEscape(string(lang)), nil, &raw); err != nil {
+		return Quote{}, err
+	}
+	return Quote{Text: strings.TrimSpace(raw.Quote), Author: strings.TrimSpace(raw.AuthorName)}, nil
+}
+
+//
+// ---------- RENDER ----------
+//
 
-	var data map[string]any
-	err = json.NewDecoder(resp.Body).Decode(&data)
+func renderQuoteBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	q, err := loadQuote(ctx, lang)
 	if err != nil {
-		log.Println("decode error:", err)
-		return nil, fmt.Errorf("unable to decode quotes json: %w", err)
+		return nil, fmt.Errorf("unable to load quote: %w", err)
 	}
 
+	data := struct {
+		Lang  Lang
+		Quote Quote
+	}{lang, q}
 	var buf bytes.Buffer
 	if err := quoteTpl.Execute(&buf, data); err != nil {
 		log.Println("execute template:", err)
@@ -31,7 +161,7 @@ func renderQuoteBMP(ctx context.Context) ([]byte, error) {
 	}
 	htmlStr := buf.String()
 
-	png, err := htmlToBMP(htmlStr)
+	png, err := htmlToBMP(htmlStr, lang)
 	if err != nil {
 		log.Println("html to png:", err)
 		return nil, fmt.Errorf("unable render bmp: %w", err)
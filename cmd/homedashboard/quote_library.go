@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ---------- CONFIG ----------
+//
+
+// Своя библиотека цитат: JSON-массив или YAML-список с полями text, author,
+// lang (пусто — для любого языка) и weight (по умолчанию 1):
+//
+//	# quotes.yaml
+//	- text: Wer nichts weiß, muss alles glauben.
+//	  author: Marie von Ebner-Eschenbach
+//	  lang: de
+//	  weight: 2
+//
+// Без DASHBOARD_QUOTES_FILE берётся встроенный assets/quotes.yaml. Недавно
+// показанные цитаты хранятся в DASHBOARD_QUOTE_HISTORY и переживают
+// перезапуск.
+var quoteLibrary = &quoteLibraryProvider{
+	Path:        envString("DASHBOARD_QUOTES_FILE", ""),
+	HistoryPath: envString("DASHBOARD_QUOTE_HISTORY", defaultQuoteHistoryPath()),
+}
+
+//go:embed assets/quotes.yaml
+var defaultQuotes []byte
+
+const quoteHistorySize = 200
+
+// quoteRand — случайное число в [0, 1) для pick; тесты подменяют.
+var quoteRand = rand.Float64
+
+func defaultQuoteHistoryPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "homedashboard", "quote-history.json")
+}
+
+//
+// ---------- LIBRARY ----------
+//
+
+type libraryQuote struct {
+	Text   string  `json:"text"`
+	Author string  `json:"author"`
+	Lang   Lang    `json:"lang"`
+	Weight float64 `json:"weight"`
+}
+
+type quoteLibraryProvider struct {
+	Path        string
+	HistoryPath string
+
+	mu      sync.Mutex
+	quotes  []libraryQuote
+	modTime time.Time
+	history []string // тексты показанных цитат, последняя — в конце
+	loaded  bool     // история прочитана с диска
+}
+
+func (*quoteLibraryProvider) Name() string { return "library" }
+
+// Quote выбирает новую цитату и сразу записывает её в историю. Вызывается
+// только при смене цитаты (loadQuote), а не на каждом рендере.
+func (p *quoteLibraryProvider) Quote(_ context.Context, lang Lang) (Quote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.load(); err != nil {
+		// Старая копия лучше пустой страницы
+		if len(p.quotes) == 0 {
+			return Quote{}, err
+		}
+		log.Printf("quotes %s: %v, using previous copy", p.Path, err)
+	}
+	candidates := p.candidates(lang)
+	if len(candidates) == 0 {
+		return Quote{}, errors.New("quote library is empty")
+	}
+
+	if !p.loaded {
+		p.history, p.loaded = readQuoteHistory(p.HistoryPath), true
+	}
+	q := p.pick(candidates)
+	p.history = append(p.history, q.Text)
+	if len(p.history) > quoteHistorySize {
+		p.history = p.history[len(p.history)-quoteHistorySize:]
+	}
+	if err := writeQuoteHistory(p.HistoryPath, p.history); err != nil {
+		log.Println("quote history:", err)
+	}
+	return Quote{Text: q.Text, Author: q.Author}, nil
+}
+
+// load перечитывает файл, только если он изменился.
+func (p *quoteLibraryProvider) load() error {
+	if p.Path == "" {
+		if p.quotes != nil {
+			return nil
+		}
+		quotes, err := parseQuotes(defaultQuotes)
+		if err != nil {
+			return fmt.Errorf("built-in quotes: %w", err)
+		}
+		p.quotes = quotes
+		return nil
+	}
+
+	st, err := os.Stat(p.Path)
+	if err != nil {
+		return err
+	}
+	if p.quotes != nil && st.ModTime().Equal(p.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return err
+	}
+	quotes, err := parseQuotes(data)
+	if err != nil {
+		return err
+	}
+	p.quotes, p.modTime = quotes, st.ModTime()
+	return nil
+}
+
+// candidates — цитаты на языке страницы и без языка; если таких нет,
+// английские, а если нет и их — любые.
+func (p *quoteLibraryProvider) candidates(lang Lang) []libraryQuote {
+	for _, want := range []Lang{lang, langEN} {
+		var out []libraryQuote
+		for _, q := range p.quotes {
+			if q.Lang == want || q.Lang == "" {
+				out = append(out, q)
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+	}
+	return p.quotes
+}
+
+// pick выбирает цитату случайно с учётом веса, пропуская показанные
+// недавно. Пропускается не больше половины кандидатов, иначе на маленькой
+// библиотеке выбор выродился бы в обход по кругу и веса бы не работали.
+func (p *quoteLibraryProvider) pick(candidates []libraryQuote) libraryQuote {
+	inCandidates := map[string]bool{}
+	for _, q := range candidates {
+		inCandidates[q.Text] = true
+	}
+	recent := map[string]bool{}
+	for i := len(p.history) - 1; i >= 0 && len(recent) < len(candidates)/2; i-- {
+		if inCandidates[p.history[i]] {
+			recent[p.history[i]] = true
+		}
+	}
+
+	var fresh []libraryQuote
+	total := 0.0
+	for _, q := range candidates {
+		if !recent[q.Text] {
+			fresh = append(fresh, q)
+			total += q.Weight
+		}
+	}
+	r := quoteRand() * total
+	for _, q := range fresh {
+		if r -= q.Weight; r < 0 {
+			return q
+		}
+	}
+	return fresh[len(fresh)-1]
+}
+
+//
+// ---------- PARSING ----------
+//
+
+// parseQuotes разбирает JSON-массив или YAML-список.
+func parseQuotes(data []byte) ([]libraryQuote, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	var quotes []libraryQuote
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &quotes); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+	} else {
+		var err error
+		if quotes, err = parseQuotesYAML(string(data)); err != nil {
+			return nil, fmt.Errorf("yaml: %w", err)
+		}
+	}
+
+	out := quotes[:0]
+	for _, q := range quotes {
+		q.Text, q.Author = strings.TrimSpace(q.Text), strings.TrimSpace(q.Author)
+		q.Lang = Lang(strings.ToLower(strings.TrimSpace(string(q.Lang))))
+		if q.Text == "" {
+			continue
+		}
+		if q.Weight <= 0 {
+			q.Weight = 1
+		}
+		out = append(out, q)
+	}
+	return out, nil
+}
+
+// parseQuotesYAML понимает подмножество YAML, которого хватает библиотеке:
+// список «- ключ: значение», строки в кавычках и без, комментарии # и
+// перенос длинной строки с бо́льшим отступом.
+func parseQuotesYAML(s string) ([]libraryQuote, error) {
+	var (
+		quotes    []libraryQuote
+		keyIndent int    // колонка ключей текущего элемента
+		lastKey   string // ключ, к которому относится строка продолжения
+	)
+	for n, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		indent := len(line) - len(text)
+
+		if rest, ok := strings.CutPrefix(text, "-"); ok && (rest == "" || rest[0] == ' ') {
+			quotes = append(quotes, libraryQuote{})
+			rest = strings.TrimLeft(rest, " ")
+			keyIndent, lastKey = indent+len(text)-len(rest), ""
+			if rest == "" {
+				keyIndent = -1 // ключи начнутся со следующей строки
+				continue
+			}
+			text, indent = rest, keyIndent
+		}
+		if len(quotes) == 0 {
+			return nil, fmt.Errorf("line %d: expected list item", n+1)
+		}
+		q := &quotes[len(quotes)-1]
+		if keyIndent < 0 {
+			keyIndent = indent
+		}
+
+		if indent > keyIndent && lastKey != "" {
+			if err := q.set(lastKey, text, true); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(text, ":")
+		if !ok || indent != keyIndent {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		value, err := yamlScalar(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		lastKey = strings.TrimSpace(key)
+		if err := q.set(lastKey, value, false); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	return quotes, nil
+}
+
+func (q *libraryQuote) set(key, value string, continued bool) error {
+	join := func(old string) string {
+		if continued {
+			return old + " " + value
+		}
+		return value
+	}
+	switch key {
+	case "text":
+		q.Text = join(q.Text)
+	case "author":
+		q.Author = join(q.Author)
+	case "lang":
+		q.Lang = Lang(value)
+	case "weight":
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("weight %q is not a number", value)
+		}
+		q.Weight = w
+	}
+	return nil
+}
+
+// yamlScalar снимает кавычки и комментарий в конце строки.
+func yamlScalar(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		end := strings.LastIndex(v, `"`)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated string %s", v)
+		}
+		return strconv.Unquote(v[:end+1])
+	case strings.HasPrefix(v, "'"):
+		end := strings.LastIndex(v, "'")
+		if end == 0 {
+			return "", fmt.Errorf("unterminated string %s", v)
+		}
+		return strings.ReplaceAll(v[1:end], "''", "'"), nil
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v), nil
+}
+
+//
+// ---------- HISTORY ----------
+//
+
+func readQuoteHistory(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Println("quote history:", err)
+		}
+		return nil
+	}
+	var history []string
+	if err := json.Unmarshal(data, &history); err != nil {
+		log.Println("quote history:", err)
+		return nil
+	}
+	return history
+}
+
+// writeQuoteHistory пишет через временный файл, как и кэш геокодера.
+func writeQuoteHistory(path string, history []string) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseQuotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []libraryQuote
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			in: "\ufeff# комментарий\n---\n" +
+				"- text: Ohne Musik wäre das Leben ein Irrtum.  # Götzen-Dämmerung\n" +
+				"  author: Friedrich Nietzsche\n" +
+				"  lang: DE\n" +
+				"  weight: 2.5\n" +
+				"\n" +
+				"-\n" +
+				"  text: Es ist nicht genug zu wissen,\n" +
+				"    man muss auch anwenden.\n" +
+				"  author: Goethe\n" +
+				"- text: \"Sag \\\"ja\\\" # kein Kommentar\"\n" +
+				"  author: 'O''Brien'\n" +
+				"  weight: 0\n" +
+				"- text: ''\n" +
+				"  author: пусто\n" +
+				"- author: без текста\n" +
+				"  unknown: key\n",
+			want: []libraryQuote{
+				{Text: "Ohne Musik wäre das Leben ein Irrtum.", Author: "Friedrich Nietzsche", Lang: "de", Weight: 2.5},
+				{Text: "Es ist nicht genug zu wissen, man muss auch anwenden.", Author: "Goethe", Weight: 1},
+				{Text: `Sag "ja" # kein Kommentar`, Author: "O'Brien", Weight: 1},
+			},
+		},
+		{
+			name: "json",
+			in:   ` [{"text":" Не в силе Бог, а в правде. ","author":"Александр Невский","lang":"ru"},{"text":""}]`,
+			want: []libraryQuote{{Text: "Не в силе Бог, а в правде.", Author: "Александр Невский", Lang: "ru", Weight: 1}},
+		},
+		{name: "key before item", in: "text: x\n- text: y\n", wantErr: "yaml: line 1: expected list item"},
+		{name: "bad indent", in: "- text: x\n author: y\n", wantErr: "yaml: line 2: expected key: value"},
+		{name: "no colon", in: "- text: x\n  author\n", wantErr: "yaml: line 2: expected key: value"},
+		{name: "bad weight", in: "- text: x\n  weight: viel\n", wantErr: `yaml: line 2: weight "viel" is not a number`},
+		{name: "unterminated", in: "- text: \"x\n", wantErr: "yaml: line 1: unterminated string"},
+		{name: "bad json", in: `[{"text": 1}]`, wantErr: "json: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQuotes([]byte(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("quote %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// useQuoteRand подставляет заданную последовательность вместо случая.
+func useQuoteRand(t *testing.T, values ...float64) {
+	t.Helper()
+	swap(t, &quoteRand, func() float64 {
+		if len(values) == 0 {
+			t.Fatal("quoteRand called too often")
+		}
+		v := values[0]
+		values = values[1:]
+		return v
+	})
+}
+
+func writeQuotesFile(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "quotes.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testQuotes = `
+- text: A
+  lang: de
+- text: B
+  lang: de
+  weight: 2
+- text: C
+- text: D
+  lang: de
+- text: E
+  lang: en
+`
+
+// Веса и пропуск недавних: из четырёх кандидатов de пропускаются не
+// больше двух последних показанных, история переживает перезапуск.
+func TestQuoteLibraryPick(t *testing.T) {
+	path := writeQuotesFile(t, testQuotes)
+	history := filepath.Join(t.TempDir(), "history.json")
+	lib := &quoteLibraryProvider{Path: path, HistoryPath: history}
+
+	// Кандидаты de: A(1) B(2) C(1, без языка) D(1)
+	useQuoteRand(t, 0.5, 0.5, 0.5, 0, 0.99, 0)
+	var got []string
+	for range 5 {
+		q, err := lib.Quote(context.Background(), langDE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, q.Text)
+	}
+	// 0.5·5 → B; без B 0.5·3 → C; без B, C 0.5·2 → D; без C, D 0 → A;
+	// без D, A 0.99·3 → C
+	if strings.Join(got, "") != "BCDAC" {
+		t.Errorf("picked %s, want BCDAC", strings.Join(got, ""))
+	}
+
+	var saved []string
+	data, err := os.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil || strings.Join(saved, "") != "BCDAC" {
+		t.Fatalf("history file = %s, %v", data, err)
+	}
+
+	// После перезапуска недавние C и A по-прежнему пропускаются
+	restarted := &quoteLibraryProvider{Path: path, HistoryPath: history}
+	q, err := restarted.Quote(context.Background(), langDE)
+	if err != nil || q.Text != "B" {
+		t.Errorf("after restart got %q, %v; want B", q.Text, err)
+	}
+}
+
+func TestQuoteLibraryCandidates(t *testing.T) {
+	lib := &quoteLibraryProvider{Path: writeQuotesFile(t, testQuotes)}
+	if err := lib.load(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lang Lang
+		want string
+	}{
+		{langDE, "ABCD"},
+		{langEN, "CE"},
+		{langRU, "C"}, // без языка подходит всем
+	}
+	for _, tt := range tests {
+		var got string
+		for _, q := range lib.candidates(tt.lang) {
+			got += q.Text
+		}
+		if got != tt.want {
+			t.Errorf("%s: candidates %s, want %s", tt.lang, got, tt.want)
+		}
+	}
+
+	// Только английские и без языка нет — для ru берутся английские
+	lib = &quoteLibraryProvider{Path: writeQuotesFile(t, "- text: E\n  lang: en\n- text: F\n  lang: es\n")}
+	lib.load()
+	if got := lib.candidates(langRU); len(got) != 1 || got[0].Text != "E" {
+		t.Errorf("ru fallback = %+v, want E", got)
+	}
+}
+
+// zitat-service недоступен — цитата из библиотеки; для ru сервис не
+// спрашиваем вовсе. Цитата держится quoteInterval.
+func TestLoadQuoteFallback(t *testing.T) {
+	var requests atomic.Int32
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"quote":"Aus dem Netz.","authorName":"zitat-service","language":"` + r.URL.Query().Get("language") + `"}`))
+	}))
+	defer srv.Close()
+
+	fc := useFakeClock(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	useQuoteRand(t, 0, 0, 0)
+	lib := &quoteLibraryProvider{
+		Path:        writeQuotesFile(t, "- text: Из библиотеки.\n  lang: ru\n- text: Aus der Bibliothek.\n  lang: de\n"),
+		HistoryPath: filepath.Join(t.TempDir(), "history.json"),
+	}
+	swap(t, &quoteProviders, map[string]QuoteProvider{
+		"zitat-service": zitatServiceProvider{BaseURL: srv.URL},
+		"library":       QuoteProvider(lib),
+	})
+	swap(t, &quoteProviderNames, []string{"zitat-service", "library"})
+	swap(t, &quoteInterval, 30*time.Minute)
+	swap(t, &currentQuotes, map[Lang]shownQuote{})
+
+	library := []string{"zitat-service", "library"}
+	steps := []struct {
+		advance  time.Duration
+		down     bool
+		names    []string
+		lang     Lang
+		want     string
+		requests int32
+	}{
+		{0, true, library, langDE, "Aus der Bibliothek.", 1},
+		{10 * time.Minute, false, library, langDE, "Aus der Bibliothek.", 1}, // ещё в интервале
+		{20 * time.Minute, false, library, langDE, "Aus dem Netz.", 2},
+		{0, false, library, langRU, "Из библиотеки.", 2},
+		{30 * time.Minute, false, library, langRU, "Из библиотеки.", 2},
+		// Все провайдеры молчат — остаётся прежняя цитата, без неё ошибка
+		{0, true, []string{"zitat-service"}, langDE, "Aus dem Netz.", 3},
+		{0, true, []string{"zitat-service"}, langEN, "", 4},
+	}
+	for i, s := range steps {
+		fc.Advance(s.advance)
+		down.Store(s.down)
+		quoteProviderNames = s.names
+		q, err := loadQuote(context.Background(), s.lang)
+		if s.want == "" {
+			if err == nil {
+				t.Errorf("step %d: got %q, want error", i, q.Text)
+			}
+		} else if err != nil || q.Text != s.want {
+			t.Errorf("step %d: got %q, %v; want %q", i, q.Text, err, s.want)
+		}
+		if got := requests.Load(); got != s.requests {
+			t.Errorf("step %d: %d requests, want %d", i, got, s.requests)
+		}
+	}
+}
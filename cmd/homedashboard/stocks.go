@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	"context"
-	_ "embed"
 	"encoding/json"
 	"fmt"
 	"image"
@@ -18,9 +17,6 @@ import (
 	"golang.org/x/image/font"
 )
 
-//go:embed assets/JetBrainsMono-Bold.ttf
-var fontCustom []byte
-
 type yfResponse struct {
 	Chart struct {
 		Result []struct {
@@ -89,7 +85,7 @@ func loadVWCEYear() ([]time.Time, []float64, error) {
 		if p <= 0 {
 			continue
 		}
-		dates = append(dates, time.Unix(ts[i], 0))
+		dates = append(dates, time.Unix(ts[i], 0).In(displayZone))
 		prices = append(prices, p)
 	}
 
@@ -100,7 +96,7 @@ func loadVWCEYear() ([]time.Time, []float64, error) {
 	return dates, prices, nil
 }
 
-func renderVWCEChart(dates []time.Time, prices []float64) image.Image {
+func renderVWCEChart(dates []time.Time, prices []float64, lang Lang) image.Image {
 	const W, H = 800, 480
 
 	dc := gg.NewContext(W, H)
@@ -144,14 +140,18 @@ func renderVWCEChart(dates []time.Time, prices []float64) image.Image {
 
 	// ===== Заголовок =====
 	dc.SetFontFace(boldFace(12))
-	title := fmt.Sprintf("VWCE  %.2f €  (%+.1f%%)", last, changePct)
+	change := lang.Number(changePct, 1)
+	if changePct >= 0 {
+		change = "+" + change
+	}
+	title := fmt.Sprintf("VWCE  %s €  (%s%%)", lang.Number(last, 2), change)
 	dc.DrawStringAnchored(title, W/2, 42, 0.5, 0.5)
 
 	// ===== Даты =====
 	dc.SetFontFace(boldFace(12))
 	dateRange := fmt.Sprintf("%s — %s",
-		dates[0].Format("02.01.2006"),
-		dates[n-1].Format("02.01.2006"),
+		lang.Date(dates[0], "date"),
+		lang.Date(dates[n-1], "date"),
 	)
 	dc.DrawStringAnchored(dateRange, W/2, 68, 0.5, 0.5)
 
@@ -202,7 +202,7 @@ func renderVWCEChart(dates []time.Time, prices []float64) image.Image {
 		dc.DrawLine(x, top, x, H-bottom)
 		dc.Stroke()
 
-		label := tick.t.Format("01.06") // "11.25"
+		label := lang.Date(tick.t, "month") // "11.25"
 		dc.DrawStringAnchored(label, x, H-bottom+28, 0.5, 0.0)
 	}
 
@@ -241,14 +241,22 @@ func boldFace(size float64) font.Face {
 	})
 }
 
-func renderVWCEBMP(ctx context.Context) ([]byte, error) {
-	dates, prices, err := loadVWCEYear()
+func renderVWCEBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	type series struct {
+		dates  []time.Time
+		prices []float64
+	}
+	s, err := cycleLoad(ctx, "stocks", func() (series, error) {
+		dates, prices, err := loadVWCEYear()
+		return series{dates, prices}, err
+	})
+	dates, prices := s.dates, s.prices
 	if err != nil {
 		log.Println("loadVWCEYear error:", err)
 		return nil, err
 	}
 
-	img := renderVWCEChart(dates, prices)
+	img := withWarningBanner(renderVWCEChart(dates, prices, lang), lang)
 	var buf bytes.Buffer
 	if err := bmp.Encode(&buf, img); err != nil {
 		return nil, err
@@ -3,13 +3,418 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-func renderTransportBMP(ctx context.Context) ([]byte, error) {
-	data := ""
+//
+// ---------- CONFIG ----------
+//
+
+// Остановки через «;»: подпись, id или название остановки у провайдера,
+// фильтр линий и фильтр направлений (подстрока в названии конечной):
+//
+//	DASHBOARD_TRANSPORT_STOPS="Breslauer Straße=20018235|U79,706|Duisburg,Hbf; Schlesische Straße=20018296"
+//
+// Провайдер — efa (VRR и прочие EFA), hafas (через hafas-rest-api,
+// например v6.db.transport.rest) или gtfs (свой расчёт, transport_gtfs.go).
+// Свой сервер задаётся отдельно для каждого: DASHBOARD_EFA_URL (другой
+// EFA-регион) и DASHBOARD_HAFAS_URL (другой экземпляр hafas-rest-api).
+var (
+	transportProviderName = envString("DASHBOARD_TRANSPORT_PROVIDER", "efa")
+	transportStops        = parseTransportStops(envString("DASHBOARD_TRANSPORT_STOPS",
+		"Breslauer Straße=Düsseldorf, Breslauer Straße; Schlesische Straße=Düsseldorf, Schlesische Straße"))
+)
+
+const (
+	departuresPerStop = 5
+	departuresMaxAge  = 30 * time.Second // рендер на всех языках — один запрос
+)
+
+type transportStop struct {
+	Label      string
+	ID         string
+	Lines      []string // пусто — все линии
+	Directions []string // пусто — все направления
+}
+
+func parseTransportStops(s string) []transportStop {
+	var stops []transportStop
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		fields := strings.Split(item, "|")
+		label, id, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			label, id = "", label
+		}
+		stop := transportStop{Label: strings.TrimSpace(label), ID: strings.TrimSpace(id)}
+		if stop.ID == "" {
+			log.Printf("config: transport stop %q has no id", item)
+			continue
+		}
+		if stop.Label == "" {
+			stop.Label = stop.ID
+		}
+		if len(fields) > 1 {
+			stop.Lines = splitList(fields[1])
+		}
+		if len(fields) > 2 {
+			stop.Directions = splitList(fields[2])
+		}
+		stops = append(stops, stop)
+	}
+	return stops
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// wants — подходит ли отправление под фильтры остановки.
+func (s transportStop) wants(d Departure) bool {
+	if len(s.Lines) > 0 && !containsFold(s.Lines, d.Line) {
+		return false
+	}
+	if len(s.Directions) == 0 {
+		return true
+	}
+	dest := strings.ToLower(d.Destination)
+	for _, dir := range s.Directions {
+		if strings.Contains(dest, strings.ToLower(dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+//
+// ---------- PROVIDERS ----------
+//
+
+// Departure — одно отправление. Expected — с учётом опоздания, если
+// провайдер его знает, иначе равно Planned.
+type Departure struct {
+	Line        string
+	Destination string
+	Platform    string
+	Planned     time.Time
+	Expected    time.Time
+	Cancelled   bool
+}
+
+// Delay — опоздание в минутах, 0 — по расписанию или неизвестно.
+func (d Departure) Delay() int {
+	return int(d.Expected.Sub(d.Planned).Round(time.Minute) / time.Minute)
+}
+
+// Minutes — сколько минут до отправления.
+func (d Departure) Minutes(now time.Time) int {
+	return max(0, int(d.Expected.Sub(now)/time.Minute))
+}
+
+// TransportProvider — источник табло. Провайдер переводит ответ своего API
+// в []Departure, фильтры и сортировка общие.
+type TransportProvider interface {
+	Name() string
+	Departures(ctx context.Context, stop transportStop) ([]Departure, error)
+}
+
+var transportProviders = map[string]TransportProvider{
+	"efa":   efaProvider{BaseURL: envString("DASHBOARD_EFA_URL", "https://efa.vrr.de/standard/")},
+	"hafas": hafasRESTProvider{BaseURL: envString("DASHBOARD_HAFAS_URL", "https://v6.db.transport.rest/")},
+	"gtfs":  &gtfsProvider{Path: envString("DASHBOARD_GTFS", ""), RealtimeURL: envString("DASHBOARD_GTFS_RT", "")},
+}
+
+//
+// ---------- EFA ----------
+//
+
+// efaProvider — EFA (Mentz) в формате rapidJSON: VRR, VVS, MVV, KVV и др.
+type efaProvider struct {
+	BaseURL string
+}
+
+func (efaProvider) Name() string { return "efa" }
+
+type efaResponse struct {
+	StopEvents []struct {
+		DepartureTimePlanned   time.Time `json:"departureTimePlanned"`
+		DepartureTimeEstimated time.Time `json:"departureTimeEstimated"`
+		IsCancelled            bool      `json:"isCancelled"`
+		RealtimeStatus         []string  `json:"realtimeStatus"`
+		Location               struct {
+			Properties struct {
+				Platform string `json:"platform"`
+			} `json:"properties"`
+		} `json:"location"`
+		Transportation struct {
+			Number           string `json:"number"`
+			DisassembledName string `json:"disassembledName"`
+			Destination      struct {
+				Name string `json:"name"`
+			} `json:"destination"`
+		} `json:"transportation"`
+	} `json:"stopEvents"`
+}
+
+func (p efaProvider) Departures(ctx context.Context, stop transportStop) ([]Departure, error) {
+	q := url.Values{
+		"outputFormat":         {"rapidJSON"},
+		"coordOutputFormat":    {"WGS84[dd.ddddd]"},
+		"mode":                 {"direct"},
+		"useRealtime":          {"1"},
+		"depType":              {"stopEvents"},
+		"itdDateTimeDepArr":    {"dep"},
+		"limit":                {"40"},
+		"name_dm":              {stop.ID},
+		"type_dm":              {"stop"},
+		"locationServerActive": {"1"},
+	}
+	if !isDigits(stop.ID) {
+		q.Set("type_dm", "any") // название вместо id: EFA сам найдёт лучшую остановку
+	}
+
+	var resp efaResponse
+	u := strings.TrimSuffix(p.BaseURL, "/") + "/XML_DM_REQUEST?" + q.Encode()
+	if err := getJSON(ctx, u, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var deps []Departure
+	for _, ev := range resp.StopEvents {
+		d := Departure{
+			Line:        ev.Transportation.DisassembledName,
+			Destination: ev.Transportation.Destination.Name,
+			Platform:    ev.Location.Properties.Platform,
+			Planned:     ev.DepartureTimePlanned,
+			Expected:    ev.DepartureTimeEstimated,
+			Cancelled:   ev.IsCancelled,
+		}
+		if d.Line == "" {
+			d.Line = ev.Transportation.Number
+		}
+		if d.Expected.IsZero() {
+			d.Expected = d.Planned
+		}
+		for _, st := range ev.RealtimeStatus {
+			if st == "TRIP_CANCELLED" {
+				d.Cancelled = true
+			}
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+//
+// ---------- HAFAS ----------
+//
+
+// hafasRESTProvider — HAFAS через hafas-rest-api (db-rest, vbb-rest и т.п.):
+// сам протокол mgate требует ключей клиента, а REST-обёртка — нет.
+type hafasRESTProvider struct {
+	BaseURL string
+}
+
+func (hafasRESTProvider) Name() string { return "hafas" }
+
+type hafasDeparture struct {
+	When        *time.Time `json:"when"`
+	PlannedWhen time.Time  `json:"plannedWhen"`
+	Direction   string     `json:"direction"`
+	Platform    string     `json:"platform"`
+	Cancelled   bool       `json:"cancelled"`
+	Line        struct {
+		Name string `json:"name"`
+	} `json:"line"`
+}
+
+func (p hafasRESTProvider) Departures(ctx context.Context, stop transportStop) ([]Departure, error) {
+	u := fmt.Sprintf("%s/stops/%s/departures?duration=60&results=40",
+		strings.TrimSuffix(p.BaseURL, "/"), url.PathEscape(stop.ID))
+
+	// v6 отдаёт {"departures": [...]}, старые версии — голый массив
+	var raw json.RawMessage
+	if err := getJSON(ctx, u, nil, &raw); err != nil {
+		return nil, err
+	}
+	var list []hafasDeparture
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+	} else {
+		var wrapped struct {
+			Departures []hafasDeparture `json:"departures"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		list = wrapped.Departures
+	}
+
+	var deps []Departure
+	for _, dep := range list {
+		d := Departure{
+			Line:        dep.Line.Name,
+			Destination: dep.Direction,
+			Platform:    dep.Platform,
+			Planned:     dep.PlannedWhen,
+			Expected:    dep.PlannedWhen,
+			Cancelled:   dep.Cancelled,
+		}
+		// У отменённых when == null
+		if dep.When != nil {
+			d.Expected = *dep.When
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+//
+// ---------- BOARD ----------
+//
+
+// stopBoard — табло одной остановки для шаблона.
+type stopBoard struct {
+	Label      string
+	Departures []Departure
+	Err        bool // провайдер не ответил — показываем прочерк, а не пустое табло
+}
+
+var (
+	transportMu     sync.Mutex
+	lastDepartures  map[string][]Departure // по id остановки, без фильтров
+	lastFailed      map[string]bool
+	lastBoardsFetch time.Time
+)
+
+// loadDepartures опрашивает все нужные остановки — табло и маршруты
+// (commute.go); свежий результат переиспользуется рендерами на других языках.
+func loadDepartures(ctx context.Context) (map[string][]Departure, map[string]bool, error) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+
+	now := clock.Now()
+	if lastDepartures != nil && now.Sub(lastBoardsFetch) < departuresMaxAge {
+		return lastDepartures, lastFailed, nil
+	}
+
+	provider, ok := transportProviders[transportProviderName]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown transport provider %q", transportProviderName)
+	}
+
+	stops := append([]transportStop{}, transportStops...)
+	for _, r := range commuteRoutes {
+		stops = append(stops, transportStop{Label: r.Label, ID: r.StopID})
+	}
+
+	deps := map[string][]Departure{}
+	failed := map[string]bool{}
+	var errs []error
+	for _, stop := range stops {
+		if _, done := deps[stop.ID]; done || failed[stop.ID] {
+			continue
+		}
+		list, err := provider.Departures(ctx, stop)
+		if err != nil {
+			log.Printf("transport %s: %s: %v", provider.Name(), stop.Label, err)
+			errs = append(errs, fmt.Errorf("%s: %w", stop.Label, err))
+			failed[stop.ID] = true
+			continue
+		}
+		deps[stop.ID] = list
+	}
+	if len(deps) == 0 && len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	lastDepartures, lastFailed, lastBoardsFetch = deps, failed, now
+	return deps, failed, nil
+}
+
+func loadBoards(ctx context.Context) ([]stopBoard, error) {
+	deps, failed, err := loadDepartures(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var boards []stopBoard
+	for _, stop := range transportStops {
+		list := upcoming(deps[stop.ID], stop, clock.Now())
+		if len(list) > departuresPerStop {
+			list = list[:departuresPerStop]
+		}
+		boards = append(boards, stopBoard{Label: stop.Label, Departures: list, Err: failed[stop.ID]})
+	}
+	return boards, nil
+}
+
+// upcoming — ещё не ушедшие отправления под фильтры, по фактическому времени.
+func upcoming(deps []Departure, stop transportStop, now time.Time) []Departure {
+	var out []Departure
+	for _, d := range deps {
+		if d.Expected.Before(now.Add(-time.Minute)) || !stop.wants(d) {
+			continue
+		}
+		d.Planned, d.Expected = d.Planned.In(displayZone), d.Expected.In(displayZone)
+		out = append(out, d)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Expected.Before(out[j].Expected) })
+	return out
+}
+
+func renderTransportBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	boards, err := loadBoards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable load departures: %w", err)
+	}
+
+	now := clock.Now()
+	data := struct {
+		Lang      Lang
+		Now       time.Time
+		Commute   []commuteStatus
+		Bikes     []BikeStationStatus
+		BikeIcons bikeIcons
+		Boards    []stopBoard
+	}{lang, now.In(displayZone), commuteStatuses(now), bikesForTransport(ctx), bikeIconsSVG(), boards}
 	var buf bytes.Buffer
 	if err := transportTpl.Execute(&buf, data); err != nil {
 		log.Println("execute template:", err)
@@ -17,7 +422,7 @@ func renderTransportBMP(ctx context.Context) ([]byte, error) {
 	}
 	htmlStr := buf.String()
 
-	png, err := htmlToBMP(htmlStr)
+	png, err := htmlToBMP(htmlStr, lang)
 	if err != nil {
 		log.Println("html to bmp:", err)
 		return nil, fmt.Errorf("unable render bmp: %w", err)
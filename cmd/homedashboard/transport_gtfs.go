@@ -0,0 +1,609 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ---------- GTFS ----------
+//
+
+// gtfsProvider считает отправления сам, по статическому GTFS-архиву, и
+// поправляет их по GTFS-Realtime TripUpdates, если лента задана:
+//
+//	DASHBOARD_TRANSPORT_PROVIDER=gtfs
+//	DASHBOARD_GTFS=/var/lib/homedashboard/vrr-gtfs.zip
+//	DASHBOARD_GTFS_RT=https://…/tripupdates.pb   # или путь к файлу
+//
+// Id остановок в DASHBOARD_TRANSPORT_STOPS — stop_id из stops.txt; у
+// станции (location_type=1) берутся все её платформы. Из stop_times.txt
+// в памяти остаются только нужные остановки — городской фид на Pi иначе
+// не поместится.
+type gtfsProvider struct {
+	Path        string
+	RealtimeURL string
+
+	mu      sync.Mutex
+	feed    *gtfsFeed
+	modTime time.Time
+	rt      gtfsRealtime
+	rtAt    time.Time
+}
+
+const (
+	gtfsHorizon   = 2 * time.Hour
+	gtfsRTMaxAge  = 30 * time.Second
+	gtfsRTMaxBody = 32 << 20
+)
+
+func (*gtfsProvider) Name() string { return "gtfs" }
+
+type gtfsFeed struct {
+	loc      *time.Location
+	children map[string][]string // станция → платформы
+	platform map[string]string   // stop_id → platform_code
+	times    map[string][]gtfsStopTime
+	schedule map[string]map[int]int // рейс → stop_sequence → секунды, для realtime
+	trips    map[string]gtfsTrip
+	routes   map[string]string // route_id → название линии
+	services map[string]*gtfsService
+}
+
+type gtfsStopTime struct {
+	TripID   string
+	Seq      int
+	Depart   int // секунд от начала служебных суток, бывает больше 24 ч
+	Headsign string
+}
+
+type gtfsTrip struct {
+	RouteID, ServiceID, Headsign string
+}
+
+type gtfsService struct {
+	Weekdays   [7]bool // по time.Weekday
+	Start, End string  // YYYYMMDD
+	Added      map[string]bool
+	Removed    map[string]bool
+}
+
+func (s *gtfsService) activeOn(day time.Time) bool {
+	date := day.Format("20060102")
+	switch {
+	case s.Removed[date]:
+		return false
+	case s.Added[date]:
+		return true
+	}
+	return s.Start != "" && date >= s.Start && date <= s.End && s.Weekdays[day.Weekday()]
+}
+
+func (p *gtfsProvider) Departures(ctx context.Context, stop transportStop) ([]Departure, error) {
+	feed, rt, err := p.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stopIDs := append([]string{stop.ID}, feed.children[stop.ID]...)
+	now := clock.Now().In(feed.loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, feed.loc)
+
+	var deps []Departure
+	// Вчерашние сутки — ради рейсов 24:xx:xx, завтрашние — ради окна за полночь
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today, today.AddDate(0, 0, 1)} {
+		start := gtfsServiceDayStart(day)
+		for _, id := range stopIDs {
+			for _, st := range feed.times[id] {
+				trip := feed.trips[st.TripID]
+				svc := feed.services[trip.ServiceID]
+				if svc == nil || !svc.activeOn(day) {
+					continue
+				}
+				planned := start.Add(time.Duration(st.Depart) * time.Second)
+				if planned.Before(now.Add(-gtfsHorizon)) || planned.After(now.Add(gtfsHorizon)) {
+					continue // опоздание больше часа-двух — редкость, и такой рейс уже не наш
+				}
+
+				d := Departure{
+					Line:        feed.routes[trip.RouteID],
+					Destination: st.Headsign,
+					Platform:    feed.platform[id],
+					Planned:     planned,
+					Expected:    planned,
+				}
+				if d.Destination == "" {
+					d.Destination = trip.Headsign
+				}
+				rt.apply(&d, st, id, day, feed.schedule[st.TripID])
+				deps = append(deps, d)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// gtfsServiceDayStart — «полдень минус 12 часов»: от него считаются времена
+// GTFS, поэтому в дни перевода часов он не совпадает с полуночью.
+func gtfsServiceDayStart(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location()).Add(-12 * time.Hour)
+}
+
+// load — статический фид (перечитывается, если архив обновился) и свежий
+// realtime. Упавший realtime — не ошибка: показываем расписание.
+func (p *gtfsProvider) load(ctx context.Context) (*gtfsFeed, gtfsRealtime, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Path == "" {
+		return nil, nil, errors.New("DASHBOARD_GTFS is not set")
+	}
+	st, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.feed == nil || !st.ModTime().Equal(p.modTime) {
+		feed, err := loadGTFS(p.Path, gtfsWantedStops())
+		if err != nil {
+			return nil, nil, fmt.Errorf("gtfs: %w", err)
+		}
+		p.feed, p.modTime = feed, st.ModTime()
+	}
+
+	if p.RealtimeURL != "" && clock.Now().Sub(p.rtAt) >= gtfsRTMaxAge {
+		rt, err := fetchGTFSRealtime(ctx, p.RealtimeURL)
+		if err != nil {
+			log.Printf("gtfs-rt: %v, using schedule", err)
+			p.rt = nil
+		} else {
+			p.rt = rt
+		}
+		p.rtAt = clock.Now()
+	}
+	return p.feed, p.rt, nil
+}
+
+// gtfsWantedStops — остановки табло и маршрутов: только их stop_times
+// и грузим.
+func gtfsWantedStops() map[string]bool {
+	want := map[string]bool{}
+	for _, s := range transportStops {
+		want[s.ID] = true
+	}
+	for _, r := range commuteRoutes {
+		want[r.StopID] = true
+	}
+	return want
+}
+
+//
+// ---------- STATIC FEED ----------
+//
+
+func loadGTFS(path string, want map[string]bool) (*gtfsFeed, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	feed := &gtfsFeed{
+		loc:      displayZone,
+		children: map[string][]string{},
+		platform: map[string]string{},
+		times:    map[string][]gtfsStopTime{},
+		schedule: map[string]map[int]int{},
+		trips:    map[string]gtfsTrip{},
+		routes:   map[string]string{},
+		services: map[string]*gtfsService{},
+	}
+
+	err = readGTFSTable(&zr.Reader, "agency.txt", false, func(row gtfsRow) {
+		if tz := row.get("agency_timezone"); tz != "" {
+			if loc, err := time.LoadLocation(tz); err == nil {
+				feed.loc = loc
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGTFSTable(&zr.Reader, "stops.txt", true, func(row gtfsRow) {
+		id, parent := row.get("stop_id"), row.get("parent_station")
+		if parent != "" && want[parent] {
+			feed.children[parent] = append(feed.children[parent], id)
+			want[id] = true
+		}
+		if code := row.get("platform_code"); code != "" {
+			feed.platform[id] = code
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGTFSTable(&zr.Reader, "stop_times.txt", true, func(row gtfsRow) {
+		id := row.get("stop_id")
+		if !want[id] {
+			return
+		}
+		if row.get("pickup_type") == "1" {
+			return // посадки нет — обычно конечная
+		}
+		dep, ok := parseGTFSTime(row.get("departure_time"))
+		if !ok {
+			return // у промежуточных остановок время может быть не задано
+		}
+		seq, _ := strconv.Atoi(row.get("stop_sequence"))
+		feed.times[id] = append(feed.times[id], gtfsStopTime{
+			TripID: row.get("trip_id"), Seq: seq, Depart: dep, Headsign: row.get("stop_headsign"),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Рейсы — только проходящие через наши остановки
+	usedTrips := map[string]bool{}
+	for _, list := range feed.times {
+		for _, st := range list {
+			usedTrips[st.TripID] = true
+		}
+	}
+	// Второй проход — полное расписание наших рейсов: обновление realtime
+	// с одним абсолютным временем у предыдущей остановки переводится в
+	// опоздание только относительно её планового времени
+	err = readGTFSTable(&zr.Reader, "stop_times.txt", true, func(row gtfsRow) {
+		trip := row.get("trip_id")
+		if !usedTrips[trip] {
+			return
+		}
+		t, ok := parseGTFSTime(row.get("departure_time"))
+		if !ok {
+			if t, ok = parseGTFSTime(row.get("arrival_time")); !ok {
+				return
+			}
+		}
+		seq, _ := strconv.Atoi(row.get("stop_sequence"))
+		if feed.schedule[trip] == nil {
+			feed.schedule[trip] = map[int]int{}
+		}
+		feed.schedule[trip][seq] = t
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGTFSTable(&zr.Reader, "trips.txt", true, func(row gtfsRow) {
+		if id := row.get("trip_id"); usedTrips[id] {
+			feed.trips[id] = gtfsTrip{RouteID: row.get("route_id"), ServiceID: row.get("service_id"), Headsign: row.get("trip_headsign")}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGTFSTable(&zr.Reader, "routes.txt", true, func(row gtfsRow) {
+		name := row.get("route_short_name")
+		if name == "" {
+			name = row.get("route_long_name")
+		}
+		feed.routes[row.get("route_id")] = name
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	service := func(id string) *gtfsService {
+		s := feed.services[id]
+		if s == nil {
+			s = &gtfsService{Added: map[string]bool{}, Removed: map[string]bool{}}
+			feed.services[id] = s
+		}
+		return s
+	}
+	// calendar.txt или calendar_dates.txt может не быть, но не обоих сразу
+	err = readGTFSTable(&zr.Reader, "calendar.txt", false, func(row gtfsRow) {
+		s := service(row.get("service_id"))
+		for d, name := range []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"} {
+			s.Weekdays[d] = row.get(name) == "1"
+		}
+		s.Start, s.End = row.get("start_date"), row.get("end_date")
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = readGTFSTable(&zr.Reader, "calendar_dates.txt", false, func(row gtfsRow) {
+		s := service(row.get("service_id"))
+		switch row.get("exception_type") {
+		case "1":
+			s.Added[row.get("date")] = true
+		case "2":
+			s.Removed[row.get("date")] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(feed.services) == 0 {
+		return nil, errors.New("neither calendar.txt nor calendar_dates.txt")
+	}
+
+	n := 0
+	for _, list := range feed.times {
+		n += len(list)
+	}
+	log.Printf("gtfs: %d stop times for %d stops, %d trips", n, len(feed.times), len(feed.trips))
+	return feed, nil
+}
+
+// gtfsRow — строка CSV с доступом по имени колонки.
+type gtfsRow struct {
+	cols   map[string]int
+	record []string
+}
+
+func (r gtfsRow) get(name string) string {
+	if i, ok := r.cols[name]; ok && i < len(r.record) {
+		return strings.TrimSpace(r.record[i])
+	}
+	return ""
+}
+
+func readGTFSTable(zr *zip.Reader, name string, required bool, fn func(row gtfsRow)) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.TrimPrefix(strings.TrimSpace(h), "\ufeff")] = i
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fn(gtfsRow{cols: cols, record: record})
+	}
+}
+
+// parseGTFSTime — "H:MM:SS" в секундах; часы бывают 24 и больше.
+func parseGTFSTime(s string) (int, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	sec, err3 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+	return h*3600 + m*60 + sec, true
+}
+
+//
+// ---------- REALTIME ----------
+//
+
+// gtfsRealtime — TripUpdates по trip_id. У одного trip_id в ленте бывает
+// несколько дат (ночные рейсы), поэтому внутри — по start_date.
+type gtfsRealtime map[string][]gtfsTripUpdate
+
+type gtfsTripUpdate struct {
+	StartDate string // YYYYMMDD, пусто — сегодняшний
+	Cancelled bool
+	Stops     []gtfsStopUpdate // по порядку следования
+}
+
+type gtfsStopUpdate struct {
+	Seq     int // 0 — не указан
+	StopID  string
+	Delay   int64 // секунд
+	Time    int64 // unix, 0 — нет
+	Skipped bool
+	hasData bool
+}
+
+// Номера полей из gtfs-realtime.proto
+const (
+	rtFeedEntity       = 2
+	rtEntityTripUpdate = 3
+
+	rtTripDescriptor = 1
+	rtStopTimeUpdate = 2
+
+	rtTripID     = 1
+	rtStartDate  = 3
+	rtTripSchRel = 4
+
+	rtStopSequence = 1
+	rtArrival      = 2
+	rtDeparture    = 3
+	rtStopID       = 4
+	rtStopSchRel   = 5
+
+	rtEventDelay = 1
+	rtEventTime  = 2
+
+	rtTripCanceled = 3
+	rtStopSkipped  = 1
+)
+
+func fetchGTFSRealtime(ctx context.Context, location string) (gtfsRealtime, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = fetchBinary(ctx, location, gtfsRTMaxBody)
+	} else {
+		data, err = os.ReadFile(strings.TrimPrefix(location, "file://"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseGTFSRealtime(data)
+}
+
+func fetchBinary(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+func parseGTFSRealtime(data []byte) (gtfsRealtime, error) {
+	rt := gtfsRealtime{}
+	err := protoScan(data, func(f protoField) error {
+		if f.Num != rtFeedEntity || f.Wire != protoBytes {
+			return nil
+		}
+		return protoScan(f.Bytes, func(f protoField) error {
+			if f.Num != rtEntityTripUpdate || f.Wire != protoBytes {
+				return nil
+			}
+			tripID, tu, err := parseTripUpdate(f.Bytes)
+			if err != nil || tripID == "" {
+				return err
+			}
+			rt[tripID] = append(rt[tripID], tu)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func parseTripUpdate(b []byte) (string, gtfsTripUpdate, error) {
+	var tripID string
+	var tu gtfsTripUpdate
+	err := protoScan(b, func(f protoField) error {
+		switch f.Num {
+		case rtTripDescriptor:
+			return protoScan(f.Bytes, func(f protoField) error {
+				switch f.Num {
+				case rtTripID:
+					tripID = f.String()
+				case rtStartDate:
+					tu.StartDate = f.String()
+				case rtTripSchRel:
+					tu.Cancelled = f.Int == rtTripCanceled
+				}
+				return nil
+			})
+		case rtStopTimeUpdate:
+			var su gtfsStopUpdate
+			err := protoScan(f.Bytes, func(f protoField) error {
+				switch f.Num {
+				case rtStopSequence:
+					su.Seq = int(f.Int)
+				case rtStopID:
+					su.StopID = f.String()
+				case rtStopSchRel:
+					su.Skipped = f.Int == rtStopSkipped
+				case rtArrival, rtDeparture:
+					// Отправление важнее прибытия: оно идёт позже и перекрывает
+					return protoScan(f.Bytes, func(e protoField) error {
+						switch e.Num {
+						case rtEventDelay:
+							su.Delay, su.hasData = int64(int32(e.Int)), true
+						case rtEventTime:
+							su.Time, su.hasData = int64(e.Int), true
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			tu.Stops = append(tu.Stops, su)
+		}
+		return nil
+	})
+	return tripID, tu, err
+}
+
+// apply поправляет плановое отправление по realtime. Опоздание последней
+// обновлённой остановки до нашей переносится дальше по рейсу (так задумано
+// в GTFS-RT). Если у той остановки только абсолютное время, опоздание —
+// это разница с её плановым временем из schedule (stop_sequence → секунды).
+func (rt gtfsRealtime) apply(d *Departure, st gtfsStopTime, stopID string, day time.Time, schedule map[int]int) {
+	serviceDate := day.Format("20060102")
+	for _, tu := range rt[st.TripID] {
+		if tu.StartDate != "" && tu.StartDate != serviceDate {
+			continue
+		}
+		if tu.Cancelled {
+			d.Cancelled = true
+			return
+		}
+		for _, su := range tu.Stops {
+			here := (su.Seq != 0 && su.Seq == st.Seq) || (su.Seq == 0 && su.StopID == stopID)
+			if !here && (su.Seq == 0 || su.Seq > st.Seq) {
+				continue
+			}
+			if here && su.Skipped {
+				d.Cancelled = true
+			}
+			if !su.hasData {
+				continue
+			}
+			switch {
+			case here && su.Time != 0:
+				d.Expected = time.Unix(su.Time, 0)
+			case su.Time != 0:
+				dep, ok := schedule[su.Seq]
+				if !ok {
+					continue // плана той остановки не знаем — держим прежнее опоздание
+				}
+				planned := gtfsServiceDayStart(day).Add(time.Duration(dep) * time.Second)
+				d.Expected = d.Planned.Add(time.Unix(su.Time, 0).Sub(planned))
+			default:
+				d.Expected = d.Planned.Add(time.Duration(su.Delay) * time.Second)
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata/gtfs/feed.zip — кусок фида Rheinbahn: станция Breslauer Straße
+// с двумя платформами, будни (wd) и выходные (we), 1-й день Рождества по
+// воскресному расписанию и ночной рейс 24:10. tripupdates.pb — TripUpdates
+// на понедельник 28.12.2026 (см. комментарии в тестах).
+
+func TestParseGTFSTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"07:21:00", 7*3600 + 21*60, true},
+		{"7:05:30", 7*3600 + 5*60 + 30, true},
+		{"00:00:00", 0, true},
+		{"24:10:00", 24*3600 + 10*60, true},
+		{"25:59:59", 25*3600 + 59*60 + 59, true},
+		{"48:00:00", 48 * 3600, true},
+		{"", 0, false},
+		{"07:21", 0, false},
+		{"aa:00:00", 0, false},
+		{"07:21:00:00", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseGTFSTime(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseGTFSTime(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Времена GTFS отсчитываются от «полудня минус 12 часов»: в дни перевода
+// часов это не полночь, и рейсы до перевода сдвигаются на час (так в
+// спецификации — фиды это учитывают).
+func TestGTFSServiceDayAcrossDST(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		day  string
+		at   string
+		want string
+	}{
+		{"2026-10-18", "00:30:00", "2026-10-18 00:30 CEST"},
+		{"2026-10-18", "07:21:00", "2026-10-18 07:21 CEST"},
+		// Весна: сутки короче, начало — 23:00 накануне
+		{"2026-03-29", "00:30:00", "2026-03-28 23:30 CET"},
+		{"2026-03-29", "03:30:00", "2026-03-29 03:30 CEST"},
+		{"2026-03-29", "12:00:00", "2026-03-29 12:00 CEST"},
+		// Осень: сутки длиннее, начало — 01:00
+		{"2026-10-25", "00:30:00", "2026-10-25 01:30 CEST"},
+		{"2026-10-25", "03:30:00", "2026-10-25 03:30 CET"},
+		{"2026-10-25", "12:00:00", "2026-10-25 12:00 CET"},
+		// Рейс после полуночи — в сутках предыдущего дня
+		{"2026-10-24", "24:10:00", "2026-10-25 00:10 CEST"},
+		{"2026-12-28", "25:15:00", "2026-12-29 01:15 CET"},
+	}
+	for _, tt := range tests {
+		day, err := time.ParseInLocation(time.DateOnly, tt.day, berlinLoc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secs, _ := parseGTFSTime(tt.at)
+		got := gtfsServiceDayStart(day).Add(time.Duration(secs) * time.Second).Format("2006-01-02 15:04 MST")
+		if got != tt.want {
+			t.Errorf("%s %s = %s, want %s", tt.day, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestGTFSServiceActiveOn(t *testing.T) {
+	feed, err := loadGTFS("testdata/gtfs/feed.zip", map[string]bool{"de:05111:18235": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		day      string
+		weekday  bool
+		weekends bool
+	}{
+		{"2026-12-28", true, false},  // понедельник
+		{"2026-12-27", false, true},  // воскресенье
+		{"2026-12-24", true, false},  // Heiligabend — обычный четверг
+		{"2026-12-25", false, true},  // 1. Weihnachtstag: wd снят, we добавлен
+		{"2027-01-04", false, false}, // после end_date
+	}
+	for _, tt := range tests {
+		day, _ := time.ParseInLocation(time.DateOnly, tt.day, feed.loc)
+		if got := feed.services["wd"].activeOn(day); got != tt.weekday {
+			t.Errorf("%s: wd active = %v, want %v", tt.day, got, tt.weekday)
+		}
+		if got := feed.services["we"].activeOn(day); got != tt.weekends {
+			t.Errorf("%s: we active = %v, want %v", tt.day, got, tt.weekends)
+		}
+	}
+}
+
+// Для realtime нужен план всех остановок наших рейсов, не только своих,
+// включая остановки без посадки.
+func TestGTFSSchedule(t *testing.T) {
+	feed, err := loadGTFS("testdata/gtfs/feed.zip", map[string]bool{"de:05111:18235": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		trip string
+		want map[int]int
+	}{
+		{"u79-0721", map[int]int{4: 7*3600 + 17*60, 5: 7*3600 + 21*60, 6: 7*3600 + 30*60}},
+		{"ne7-2410", map[int]int{7: 24*3600 + 10*60, 8: 24*3600 + 25*60}},
+	}
+	for _, tt := range tests {
+		if got := feed.schedule[tt.trip]; !maps.Equal(got, tt.want) {
+			t.Errorf("%s: schedule %v, want %v", tt.trip, got, tt.want)
+		}
+	}
+}
+
+func TestGTFSDepartures(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &displayZone, berlinLoc)
+	swap(t, &commuteRoutes, nil)
+	swap(t, &transportStops, parseTransportStops("Breslauer Straße=de:05111:18235"))
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		realtime string
+		want     []string
+	}{
+		{
+			name: "weekday",
+			now:  time.Date(2026, 12, 28, 7, 15, 0, 0, berlinLoc),
+			want: []string{
+				"07:21 U79 Duisburg Hbf→07:21 1",
+				"07:25 706 Am Steinberg→07:25 2",
+				"07:41 U79 Duisburg Hbf→07:41 1",
+				// stop_headsign важнее trip_headsign
+				"07:45 706 Am Steinberg (Ersatzverkehr)→07:45 2",
+				"08:01 U79 Duisburg Hbf→08:01 1",
+				"08:21 U79 Duisburg Hbf→08:21 1",
+			},
+		},
+		{
+			name:     "weekday with realtime",
+			now:      time.Date(2026, 12, 28, 7, 15, 0, 0, berlinLoc),
+			realtime: "testdata/gtfs/tripupdates.pb",
+			want: []string{
+				// +2 мин на предыдущей остановке
+				"07:21 U79 Duisburg Hbf→07:23 1",
+				// Остановка пропущена
+				"07:25 706 Am Steinberg→07:25 2 X",
+				// Рейс отменён
+				"07:41 U79 Duisburg Hbf→07:41 1 X",
+				// −1 мин: отправление перекрывает прибытие +5
+				"07:45 706 Am Steinberg (Ersatzverkehr)→07:44 2",
+				// Абсолютное время на нашей остановке важнее опоздания до неё
+				"08:01 U79 Duisburg Hbf→08:04 1",
+				// Обновление за 27.12 к этому рейсу не относится
+				"08:21 U79 Duisburg Hbf→08:21 1",
+			},
+		},
+		{
+			name: "holiday on a friday",
+			now:  time.Date(2026, 12, 25, 7, 15, 0, 0, berlinLoc),
+			want: []string{"07:31 U79 Duisburg Hbf→07:31 1"},
+		},
+		{
+			// Ночь на перевод часов: 24:10 субботы и 00:30 воскресенья
+			name: "night before DST ends",
+			now:  time.Date(2026, 10, 25, 0, 5, 0, 0, berlinLoc),
+			want: []string{
+				"00:10 NachtExpress NE7 Kaiserswerth→00:10 1",
+				"01:30 U79 Duisburg Hbf→01:30 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeClock(t, tt.now)
+			p := &gtfsProvider{Path: "testdata/gtfs/feed.zip", RealtimeURL: tt.realtime}
+			deps, err := p.Departures(context.Background(), transportStops[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.SortFunc(deps, func(a, b Departure) int { return a.Planned.Compare(b.Planned) })
+			got := depLines(deps)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// Опоздание последней обновлённой остановки до нашей переносится дальше,
+// обновления после нашей и чужие остановки без stop_sequence — нет.
+func TestGTFSRealtimeApplyPropagation(t *testing.T) {
+	planned := time.Date(2026, 12, 28, 7, 21, 0, 0, time.UTC)
+	rt := gtfsRealtime{
+		"t1": {{
+			StartDate: "20261228",
+			Stops: []gtfsStopUpdate{
+				{Seq: 2, Delay: 60, hasData: true},
+				{Seq: 4, Delay: 180, hasData: true},
+				{Seq: 6, Skipped: true},
+				{StopID: "elsewhere", Delay: 900, hasData: true},
+			},
+		}},
+		"t2": {{
+			StartDate: "20261227",
+			Stops:     []gtfsStopUpdate{{Seq: 1, Delay: 600, hasData: true}},
+		}, {
+			// Без даты — про любые сутки
+			Stops: []gtfsStopUpdate{{StopID: "here", Time: planned.Add(90 * time.Second).Unix(), hasData: true}},
+		}},
+		// Только абсолютное время у предыдущих остановок: опоздание —
+		// относительно их плана, остановка без плана ничего не меняет
+		"t4": {{
+			StartDate: "20261228",
+			Stops: []gtfsStopUpdate{
+				{Seq: 2, Delay: 60, hasData: true},
+				{Seq: 3, Time: time.Date(2026, 12, 28, 7, 30, 0, 0, time.UTC).Unix(), hasData: true},
+				{Seq: 4, Time: time.Date(2026, 12, 28, 7, 17, 30, 0, time.UTC).Unix(), hasData: true},
+			},
+		}},
+		"t5": {{
+			Stops: []gtfsStopUpdate{
+				{Seq: 2, Delay: 60, hasData: true},
+				{Seq: 3, Time: time.Date(2026, 12, 28, 7, 30, 0, 0, time.UTC).Unix(), hasData: true},
+			},
+		}},
+	}
+	schedule := map[string]map[int]int{
+		"t4": {1: 7*3600 + 5*60, 2: 7*3600 + 10*60, 4: 7*3600 + 15*60, 5: 7*3600 + 21*60},
+		"t5": {2: 7*3600 + 10*60, 5: 7*3600 + 21*60},
+	}
+	tests := []struct {
+		trip      string
+		seq       int
+		stop      string
+		date      string
+		delay     time.Duration
+		cancelled bool
+	}{
+		{"t1", 1, "a", "20261228", 0, false},
+		{"t1", 2, "a", "20261228", time.Minute, false},
+		{"t1", 3, "a", "20261228", time.Minute, false},
+		{"t1", 5, "a", "20261228", 3 * time.Minute, false},
+		{"t1", 6, "a", "20261228", 3 * time.Minute, true},
+		{"t1", 7, "b", "20261228", 3 * time.Minute, false},
+		{"t1", 7, "elsewhere", "20261228", 15 * time.Minute, false},
+		{"t1", 5, "a", "20261229", 0, false},
+		{"t2", 3, "here", "20261228", 90 * time.Second, false},
+		{"t3", 3, "a", "20261228", 0, false},
+		{"t4", 3, "a", "20261228", 9 * time.Minute, false},
+		{"t4", 5, "a", "20261228", 150 * time.Second, false},
+		{"t5", 5, "a", "20261228", time.Minute, false},
+	}
+	for _, tt := range tests {
+		d := Departure{Planned: planned, Expected: planned}
+		day, err := time.Parse("20060102", tt.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rt.apply(&d, gtfsStopTime{TripID: tt.trip, Seq: tt.seq}, tt.stop, day, schedule[tt.trip])
+		if got := d.Expected.Sub(planned); got != tt.delay || d.Cancelled != tt.cancelled {
+			t.Errorf("%s seq %d %s: delay %s cancelled %v, want %s %v",
+				tt.trip, tt.seq, tt.date, got, d.Cancelled, tt.delay, tt.cancelled)
+		}
+	}
+}
+
+func TestParseGTFSRealtime(t *testing.T) {
+	data := readTestdata(t, "gtfs/tripupdates.pb")
+	rt, err := parseGTFSRealtime(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rt) != 6 {
+		t.Errorf("got %d trips, want 6", len(rt))
+	}
+	tu := rt["706-0745"][0]
+	if tu.StartDate != "20261228" || len(tu.Stops) != 1 || tu.Stops[0].Seq != 3 || tu.Stops[0].Delay != -60 {
+		t.Errorf("706-0745 = %+v", tu)
+	}
+	if !rt["u79-0741"][0].Cancelled {
+		t.Error("u79-0741 is not cancelled")
+	}
+	if su := rt["706-0725"][0].Stops[0]; su.StopID != "de:05111:18235:2:2" || !su.Skipped || su.hasData {
+		t.Errorf("706-0725 = %+v", su)
+	}
+
+	// Обрезанная лента — ошибка, а не половина обновлений
+	for _, n := range []int{len(data) - 1, len(data) - 7, 3} {
+		if _, err := parseGTFSRealtime(data[:n]); err == nil {
+			t.Errorf("truncated to %d bytes: no error", n)
+		}
+	}
+}
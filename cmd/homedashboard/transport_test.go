@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// depLine — отправление одной строкой для сравнения в тестах.
+func depLine(d Departure) string {
+	s := fmt.Sprintf("%s %s %s→%s %s", d.Planned.In(displayZone).Format("15:04"), d.Line,
+		d.Destination, d.Expected.In(displayZone).Format("15:04"), d.Platform)
+	if d.Cancelled {
+		s += " X"
+	}
+	return s
+}
+
+func depLines(deps []Departure) []string {
+	var out []string
+	for _, d := range deps {
+		out = append(out, depLine(d))
+	}
+	return out
+}
+
+// transportServer отдаёт записанные ответы VRR (EFA rapidJSON) и
+// db-rest/vbb-rest (hafas-rest-api): /efa/XML_DM_REQUEST?name_dm=<id> и
+// /hafas/stops/<id>/departures читают testdata/<провайдер>/<id>.json.
+func transportServer(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var file string
+		switch {
+		case r.URL.Path == "/efa/XML_DM_REQUEST":
+			q := r.URL.Query()
+			if q.Get("outputFormat") != "rapidJSON" || q.Get("type_dm") != "stop" {
+				http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+				return
+			}
+			file = filepath.Join("testdata", "efa", q.Get("name_dm")+".json")
+		case strings.HasPrefix(r.URL.Path, "/hafas/stops/") && strings.HasSuffix(r.URL.Path, "/departures"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/hafas/stops/"), "/departures")
+			file = filepath.Join("testdata", "hafas", id+".json")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestTransportProviders(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &displayZone, berlinLoc)
+	srv := transportServer(t)
+
+	tests := []struct {
+		name     string
+		provider TransportProvider
+		stop     string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "efa",
+			provider: efaProvider{BaseURL: srv + "/efa"},
+			stop:     "20018235",
+			want: []string{
+				"13:55 U79 Duisburg Hbf→13:57 1",
+				"14:04 U79 Duisburg Hbf→14:06 1",
+				"14:07 706 Düsseldorf Am Steinberg→14:07 2",
+				// Без прогноза — по расписанию
+				"14:09 U79 Düsseldorf Universität Ost/Botanischer Garten→14:09 2",
+				// Без disassembledName — номер линии
+				"14:12 707 Düsseldorf Unterrath S→14:13 2",
+				// Отмена через realtimeStatus и через isCancelled
+				"14:14 U79 Duisburg Hbf→14:14 1 X",
+				"14:17 706 Düsseldorf Am Steinberg→14:17 2 X",
+			},
+		},
+		{
+			name:     "efa truncated",
+			provider: efaProvider{BaseURL: srv + "/efa"},
+			stop:     "20018296",
+			wantErr:  "unexpected EOF",
+		},
+		{
+			name:     "efa missing",
+			provider: efaProvider{BaseURL: srv + "/efa"},
+			stop:     "20018000",
+			wantErr:  "status: 404 Not Found",
+		},
+		{
+			name:     "hafas v6",
+			provider: hafasRESTProvider{BaseURL: srv + "/hafas/"},
+			stop:     "8000085",
+			want: []string{
+				"14:03 RE 1 Köln/Bonn Flughafen→14:05 16",
+				"14:06 S 11 Bergisch Gladbach→14:06 10",
+				// У отменённых when == null
+				"14:10 ICE 945 Berlin Hbf (tief)→14:10 14 X",
+				"14:01 RB 33 Aachen Hbf→14:01 18",
+			},
+		},
+		{
+			name:     "hafas bare array",
+			provider: hafasRESTProvider{BaseURL: srv + "/hafas/"},
+			stop:     "900100003",
+			want: []string{
+				"14:02 U6 S+U Alt-Tegel→14:04 1",
+				"14:05 M1 Niederschönhausen, Schillerstr.→14:05 ",
+			},
+		},
+		{
+			name:     "hafas truncated",
+			provider: hafasRESTProvider{BaseURL: srv + "/hafas/"},
+			stop:     "8000086",
+			wantErr:  "unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := tt.provider.Departures(context.Background(), transportStop{ID: tt.stop})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := depLines(deps)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// Фильтры остановки, ушедшие отправления и порядок по фактическому времени
+// — на записанном табло EFA.
+func TestLoadBoards(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &displayZone, berlinLoc)
+	useFakeClock(t, time.Date(2026, 10, 18, 14, 0, 0, 0, berlinLoc))
+	swap(t, &transportProviderName, "efa")
+	srv := transportServer(t)
+	swap(t, &transportProviders, map[string]TransportProvider{
+		"efa": efaProvider{BaseURL: srv + "/efa"},
+	})
+	swap(t, &commuteRoutes, nil)
+	swap(t, &lastDepartures, nil)
+	swap(t, &transportStops, parseTransportStops(
+		"Breslauer Straße=20018235; Richtung Duisburg=20018235|U79|duisburg; Trams=20018235|706,707; Kaputt=20018296"))
+
+	boards, err := loadBoards(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		label string
+		deps  []string
+		err   bool
+	}{
+		{"Breslauer Straße", []string{
+			"14:04 U79 Duisburg Hbf→14:06 1",
+			"14:07 706 Düsseldorf Am Steinberg→14:07 2",
+			"14:09 U79 Düsseldorf Universität Ost/Botanischer Garten→14:09 2",
+			"14:12 707 Düsseldorf Unterrath S→14:13 2",
+			"14:14 U79 Duisburg Hbf→14:14 1 X",
+		}, false},
+		{"Richtung Duisburg", []string{
+			"14:04 U79 Duisburg Hbf→14:06 1",
+			"14:14 U79 Duisburg Hbf→14:14 1 X",
+		}, false},
+		{"Trams", []string{
+			"14:07 706 Düsseldorf Am Steinberg→14:07 2",
+			"14:12 707 Düsseldorf Unterrath S→14:13 2",
+			"14:17 706 Düsseldorf Am Steinberg→14:17 2 X",
+		}, false},
+		{"Kaputt", nil, true},
+	}
+	if len(boards) != len(want) {
+		t.Fatalf("got %d boards, want %d", len(boards), len(want))
+	}
+	for i, w := range want {
+		b := boards[i]
+		got := depLines(b.Departures)
+		if b.Label != w.label || b.Err != w.err || strings.Join(got, "\n") != strings.Join(w.deps, "\n") {
+			t.Errorf("board %d: %s err=%v\n%s\nwant %s err=%v\n%s",
+				i, b.Label, b.Err, strings.Join(got, "\n"), w.label, w.err, strings.Join(w.deps, "\n"))
+		}
+		for _, d := range b.Departures {
+			if d.Expected.Location() != displayZone {
+				t.Errorf("board %d: %s not in display zone", i, depLine(d))
+			}
+		}
+	}
+}
+
+func TestDepartureDelay(t *testing.T) {
+	planned := time.Date(2026, 10, 18, 12, 4, 0, 0, time.UTC)
+	tests := []struct {
+		expected time.Time
+		delay    int
+		minutes  int
+	}{
+		{planned, 0, 4},
+		{planned.Add(2 * time.Minute), 2, 6},
+		{planned.Add(89 * time.Second), 1, 5},
+		{planned.Add(-time.Minute), -1, 3},
+		{planned.Add(-10 * time.Minute), -10, 0},
+	}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		d := Departure{Planned: planned, Expected: tt.expected}
+		if got := d.Delay(); got != tt.delay {
+			t.Errorf("%s: Delay = %d, want %d", tt.expected.Format("15:04:05"), got, tt.delay)
+		}
+		if got := d.Minutes(now); got != tt.minutes {
+			t.Errorf("%s: Minutes = %d, want %d", tt.expected.Format("15:04:05"), got, tt.minutes)
+		}
+	}
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+//
+// ---------- CONFIG ----------
+//
+
+var (
+	// Atom-лента CAP-предупреждений DWD; подойдёт и любая другая CAP/Atom
+	// лента (MeteoAlarm) или один CAP-документ.
+	warningsFeedURL = envString("DASHBOARD_WARNINGS_FEED", "https://www.dwd.de/DWD/warnungen/cap-feed/de/atom.xml")
+	// Регион: geocode (WARNCELLID у DWD, EMMA_ID у MeteoAlarm) или часть areaDesc.
+	// 105111000 — Kreis und Stadt Düsseldorf.
+	warningsRegion = envString("DASHBOARD_WARNINGS_REGION", "105111000")
+	// С какой серьёзности предупреждение вытесняет ротацию страниц.
+	warningsPreempt = parseSeverity(envString("DASHBOARD_WARNINGS_PREEMPT", "Severe"))
+)
+
+// Больше этого CAP-документов за раз не скачиваем.
+const maxCAPFetches = 20
+
+//
+// ---------- MODEL ----------
+//
+
+type warningSeverity int
+
+const (
+	severityUnknown warningSeverity = iota
+	severityMinor
+	severityModerate
+	severitySevere
+	severityExtreme
+)
+
+func parseSeverity(s string) warningSeverity {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "minor":
+		return severityMinor
+	case "moderate":
+		return severityModerate
+	case "severe":
+		return severitySevere
+	case "extreme":
+		return severityExtreme
+	}
+	return severityUnknown
+}
+
+// Label — как DWD называет уровни на своих картах.
+func (s warningSeverity) Label(lang Lang) string {
+	switch s {
+	case severityMinor:
+		return lang.T("severity.minor")
+	case severityModerate:
+		return lang.T("severity.moderate")
+	case severitySevere:
+		return lang.T("severity.severe")
+	case severityExtreme:
+		return lang.T("severity.extreme")
+	}
+	return lang.T("severity.unknown")
+}
+
+type WeatherWarning struct {
+	Event       string
+	Headline    string
+	Description string
+	Area        string
+	Severity    warningSeverity
+	Onset       time.Time
+	Expires     time.Time
+
+	// CAP: identifier сообщения, какие сообщения оно заменяет (Update) или
+	// отменяет (Cancel). Отмена сама не показывается — см. supersede.
+	id     string
+	refs   []string
+	cancel bool
+}
+
+func (w WeatherWarning) Severe() bool {
+	return w.Severity >= severitySevere
+}
+
+func (w WeatherWarning) Active(now time.Time) bool {
+	if !w.Expires.IsZero() && !now.Before(w.Expires) {
+		return false
+	}
+	return w.Onset.IsZero() || !now.Before(w.Onset)
+}
+
+//
+// ---------- CAP / ATOM ----------
+//
+
+type capAlert struct {
+	Identifier string    `xml:"identifier"`
+	Status     string    `xml:"status"`
+	MsgType    string    `xml:"msgType"`
+	References string    `xml:"references"`
+	Infos      []capInfo `xml:"info"`
+}
+
+type capInfo struct {
+	Language    string    `xml:"language"`
+	Event       string    `xml:"event"`
+	Severity    string    `xml:"severity"`
+	Headline    string    `xml:"headline"`
+	Description string    `xml:"description"`
+	Effective   string    `xml:"effective"`
+	Onset       string    `xml:"onset"`
+	Expires     string    `xml:"expires"`
+	Areas       []capArea `xml:"area"`
+}
+
+type capArea struct {
+	AreaDesc string     `xml:"areaDesc"`
+	Geocodes []capValue `xml:"geocode"`
+}
+
+type capValue struct {
+	ValueName string `xml:"valueName"`
+	Value     string `xml:"value"`
+}
+
+// Элементы cap:* внутри entry ищутся по локальному имени, поэтому
+// одинаково читаются ленты с CAP 1.1 и 1.2.
+type atomFeed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Summary string `xml:"summary"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"link"`
+
+	Identifier string     `xml:"identifier"`
+	References string     `xml:"references"`
+	Event      string     `xml:"event"`
+	Severity   string     `xml:"severity"`
+	MsgType    string     `xml:"msgType"`
+	Onset      string     `xml:"onset"`
+	Effective  string     `xml:"effective"`
+	Expires    string     `xml:"expires"`
+	AreaDesc   string     `xml:"areaDesc"`
+	Geocodes   []capValue `xml:"geocode"`
+}
+
+func (e atomEntry) capLink() string {
+	for _, l := range e.Links {
+		if strings.Contains(l.Type, "cap") || strings.HasSuffix(l.Href, ".xml") {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// parseCAPDocument понимает и Atom-ленту, и отдельный CAP-документ. Для
+// записей ленты без встроенных полей cap:* возвращает ссылки на CAP,
+// которые нужно докачать.
+func parseCAPDocument(r io.Reader, region string, lang Lang) ([]WeatherWarning, []string, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("xml: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "alert":
+			var alert capAlert
+			if err := dec.DecodeElement(&alert, &start); err != nil {
+				return nil, nil, fmt.Errorf("cap: %w", err)
+			}
+			return alert.warnings(region, lang), nil, nil
+		case "feed":
+			var feed atomFeed
+			if err := dec.DecodeElement(&feed, &start); err != nil {
+				return nil, nil, fmt.Errorf("atom: %w", err)
+			}
+			warnings, links := feed.warnings(region)
+			return warnings, links, nil
+		default:
+			return nil, nil, fmt.Errorf("unexpected root element <%s>", start.Name.Local)
+		}
+	}
+}
+
+func (a capAlert) warnings(region string, lang Lang) []WeatherWarning {
+	if !strings.EqualFold(a.Status, "Actual") {
+		return nil
+	}
+	info, ok := a.preferredInfo(lang)
+	if !ok {
+		return nil
+	}
+	for _, area := range info.Areas {
+		if !regionMatches(region, area.AreaDesc, area.Geocodes) {
+			continue
+		}
+		return []WeatherWarning{{
+			Event:       info.Event,
+			Headline:    info.Headline,
+			Description: info.Description,
+			Area:        area.AreaDesc,
+			Severity:    parseSeverity(info.Severity),
+			Onset:       parseCAPTime(info.Onset, info.Effective),
+			Expires:     parseCAPTime(info.Expires),
+			id:          strings.TrimSpace(a.Identifier),
+			refs:        parseCAPReferences(a.References),
+			cancel:      strings.EqualFold(a.MsgType, "Cancel"),
+		}}
+	}
+	return nil
+}
+
+// preferredInfo выбирает блок info на языке страницы, если DWD прислал
+// несколько языков (обычно de-DE и en-GB).
+func (a capAlert) preferredInfo(lang Lang) (capInfo, bool) {
+	for _, info := range a.Infos {
+		if strings.HasPrefix(strings.ToLower(info.Language), string(lang)) {
+			return info, true
+		}
+	}
+	if len(a.Infos) > 0 {
+		return a.Infos[0], true
+	}
+	return capInfo{}, false
+}
+
+func (f atomFeed) warnings(region string) ([]WeatherWarning, []string) {
+	var warnings []WeatherWarning
+	var links []string
+	for _, e := range f.Entries {
+		if e.Event == "" {
+			// Только ссылка на CAP — качаем, если запись похожа на наш регион.
+			if link := e.capLink(); link != "" && regionMatches(region, e.Title+" "+e.Summary, nil) {
+				links = append(links, link)
+			}
+			continue
+		}
+		if !regionMatches(region, e.AreaDesc, e.Geocodes) {
+			continue
+		}
+		id := e.Identifier
+		if id == "" {
+			id = e.ID
+		}
+		warnings = append(warnings, WeatherWarning{
+			Event:       e.Event,
+			Headline:    e.Title,
+			Description: e.Summary,
+			Area:        e.AreaDesc,
+			Severity:    parseSeverity(e.Severity),
+			Onset:       parseCAPTime(e.Onset, e.Effective),
+			Expires:     parseCAPTime(e.Expires),
+			id:          strings.TrimSpace(id),
+			refs:        parseCAPReferences(e.References),
+			cancel:      strings.EqualFold(e.MsgType, "Cancel"),
+		})
+	}
+	return warnings, links
+}
+
+// parseCAPReferences — identifier'ы из <references>: через пробел тройки
+// "sender,identifier,sent".
+func parseCAPReferences(s string) []string {
+	var ids []string
+	for _, ref := range strings.Fields(s) {
+		if parts := strings.Split(ref, ","); len(parts) == 3 && parts[1] != "" {
+			ids = append(ids, parts[1])
+		}
+	}
+	return ids
+}
+
+// supersede убирает сообщения, на которые ссылается более позднее Update
+// или Cancel, и сами отмены: иначе обновлённое предупреждение висит рядом
+// со старым, а отменённое продолжает перебивать ротацию.
+func supersede(warnings []WeatherWarning) []WeatherWarning {
+	replaced := map[string]bool{}
+	for _, w := range warnings {
+		for _, id := range w.refs {
+			replaced[id] = true
+		}
+	}
+	var out []WeatherWarning
+	for _, w := range warnings {
+		if w.cancel || (w.id != "" && replaced[w.id]) {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+func regionMatches(region, areaDesc string, geocodes []capValue) bool {
+	if region == "" {
+		return true
+	}
+	for _, g := range geocodes {
+		if strings.EqualFold(strings.TrimSpace(g.Value), region) {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(areaDesc), strings.ToLower(region))
+}
+
+// parseCAPTime берёт первое непустое время из списка (onset, потом effective).
+func parseCAPTime(values ...string) time.Time {
+	for _, v := range values {
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(v)); err == nil {
+			return t.In(displayZone)
+		}
+	}
+	return time.Time{}
+}
+
+//
+// ---------- LOADING ----------
+//
+
+var (
+	warningsMu     sync.RWMutex
+	latestWarnings = map[Lang][]WeatherWarning{}
+)
+
+func loadWarnings(ctx context.Context, lang Lang) ([]WeatherWarning, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	body, err := cycleLoad(ctx, "cap "+warningsFeedURL, func() ([]byte, error) {
+		return fetchCAP(ctx, client, warningsFeedURL)
+	})
+	if err != nil {
+		return nil, err
+	}
+	warnings, links, err := parseCAPDocument(bytes.NewReader(body), warningsRegion, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(links) > maxCAPFetches {
+		log.Printf("warnings: %d CAP links match, fetching first %d", len(links), maxCAPFetches)
+		links = links[:maxCAPFetches]
+	}
+	for _, link := range links {
+		doc, err := cycleLoad(ctx, "cap "+link, func() ([]byte, error) {
+			return fetchCAP(ctx, client, link)
+		})
+		if err != nil {
+			log.Println("warnings: CAP fetch:", err)
+			continue
+		}
+		ws, _, err := parseCAPDocument(bytes.NewReader(doc), warningsRegion, lang)
+		if err != nil {
+			log.Println("warnings: CAP parse:", err)
+			continue
+		}
+		warnings = append(warnings, ws...)
+	}
+
+	now := clock.Now()
+	var active []WeatherWarning
+	for _, w := range supersede(warnings) {
+		if w.Active(now) {
+			active = append(active, w)
+		}
+	}
+	// Самые серьёзные — первыми
+	sort.SliceStable(active, func(i, j int) bool { return active[i].Severity > active[j].Severity })
+	return active, nil
+}
+
+func fetchCAP(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("warnings fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+}
+
+// currentWarnings — последние загруженные предупреждения, которые ещё
+// действуют: если ленту не удалось обновить, истёкшее предупреждение не
+// должно висеть баннером до следующей удачной загрузки.
+func currentWarnings(lang Lang) []WeatherWarning {
+	warningsMu.RLock()
+	defer warningsMu.RUnlock()
+	ws, ok := latestWarnings[lang]
+	if !ok {
+		ws = latestWarnings[defaultLang]
+	}
+	now := clock.Now()
+	var active []WeatherWarning
+	for _, w := range ws {
+		if w.Active(now) {
+			active = append(active, w)
+		}
+	}
+	return active
+}
+
+// preemptingWarning — есть ли предупреждение, ради которого стоит показать
+// страницу предупреждений вне очереди.
+func preemptingWarning(lang Lang) bool {
+	ws := currentWarnings(lang)
+	return len(ws) > 0 && warningsPreempt != severityUnknown && ws[0].Severity >= warningsPreempt
+}
+
+//
+// ---------- RENDERING ----------
+//
+
+type warningsPageData struct {
+	Lang      Lang
+	UpdatedAt time.Time
+	Warnings  []WeatherWarning
+}
+
+func renderWarningsBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	warnings, err := loadWarnings(ctx, lang)
+	if err != nil {
+		log.Println("error fetching:", err)
+		return nil, fmt.Errorf("unable to fetch warnings: %w", err)
+	}
+
+	warningsMu.Lock()
+	latestWarnings[lang] = warnings
+	warningsMu.Unlock()
+
+	var buf bytes.Buffer
+	data := warningsPageData{Lang: lang, UpdatedAt: displayNow(), Warnings: warnings}
+	if err := warningsTpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("unable render warnings template: %w", err)
+	}
+
+	// На самой странице предупреждений баннер не нужен
+	bmp, err := htmlToBMPNoBanner(buf.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable render warnings bmp: %w", err)
+	}
+	return bmp, nil
+}
+
+// withWarningBanner дорисовывает внизу любой страницы полосу с самым
+// серьёзным активным предупреждением.
+func withWarningBanner(img image.Image, lang Lang) image.Image {
+	ws := currentWarnings(lang)
+	if len(ws) == 0 {
+		return img
+	}
+	w := ws[0]
+
+	const h = 44.0
+	dc := gg.NewContextForImage(img)
+	W, H := float64(dc.Width()), float64(dc.Height())
+
+	// Белый кант, чтобы полоса читалась и на чёрном фоне (акции)
+	dc.SetRGB(1, 1, 1)
+	dc.DrawRectangle(0, H-h-3, W, h+3)
+	dc.Fill()
+	dc.SetRGB(0, 0, 0)
+	dc.DrawRectangle(0, H-h, W, h)
+	dc.Fill()
+
+	// Треугольник «внимание»
+	dc.SetRGB(1, 1, 1)
+	dc.MoveTo(14, H-8)
+	dc.LineTo(38, H-8)
+	dc.LineTo(26, H-36)
+	dc.ClosePath()
+	dc.Fill()
+	dc.SetRGB(0, 0, 0)
+	dc.DrawRectangle(25, H-29, 2, 12)
+	dc.DrawRectangle(25, H-14, 2, 3)
+	dc.Fill()
+
+	dc.SetRGB(1, 1, 1)
+	dc.SetFontFace(boldFace(13))
+	text := strings.ToUpper(w.Severity.Label(lang)) + ": " + w.Event
+	if !w.Expires.IsZero() {
+		text += " · " + lang.T("warnings.until", lang.Date(w.Expires, "weekday_at"))
+	}
+	if extra := len(ws) - 1; extra > 0 {
+		text += fmt.Sprintf(" (+%d)", extra)
+	}
+	dc.DrawStringAnchored(text, 50, H-h/2, 0, 0.35)
+
+	return dc.Image()
+}
+
+func handleWarningsBMP(w http.ResponseWriter, r *http.Request) {
+	serveCachedImage(w, r, &warningsCache)
+}
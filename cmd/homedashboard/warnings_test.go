@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCAPDocument(t *testing.T) {
+	tests := []struct {
+		file    string
+		region  string
+		lang    Lang
+		want    []WeatherWarning
+		links   []string
+		wantErr bool
+	}{
+		{
+			file:   "cap_dwd.xml",
+			region: "105111000",
+			lang:   langDE,
+			want: []WeatherWarning{{
+				Event:    "STURMBÖEN",
+				Headline: "Amtliche WARNUNG vor STURMBÖEN",
+				Area:     "Kreis und Stadt Düsseldorf",
+				Severity: severityModerate,
+				Onset:    time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				Expires:  time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			// Блок info на языке страницы
+			file:   "cap_dwd.xml",
+			region: "105111000",
+			lang:   langEN,
+			want: []WeatherWarning{{
+				Event:    "gale-force gusts",
+				Headline: "Official WARNING of GALE-FORCE GUSTS",
+				Area:     "Kreis und Stadt Düsseldorf",
+				Severity: severityModerate,
+				Onset:    time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				Expires:  time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			// Языка нет — первый блок
+			file:   "cap_dwd.xml",
+			region: "düsseldorf",
+			lang:   langRU,
+			want: []WeatherWarning{{
+				Event:    "STURMBÖEN",
+				Headline: "Amtliche WARNUNG vor STURMBÖEN",
+				Area:     "Kreis und Stadt Düsseldorf",
+				Severity: severityModerate,
+				Onset:    time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				Expires:  time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+			}},
+		},
+		{file: "cap_cancel.xml", region: "105111000", lang: langDE},
+		{file: "cap_exercise.xml", region: "105111000", lang: langDE},
+		{file: "cap_other_region.xml", region: "105111000", lang: langDE},
+		{
+			file:   "atom_inline.xml",
+			region: "DE111",
+			lang:   langDE,
+			want: []WeatherWarning{
+				{
+					Event:    "SCHWERE STURMBÖEN",
+					Headline: "Orange Wind Warning issued for Düsseldorf",
+					Area:     "Düsseldorf",
+					Severity: severitySevere,
+					Onset:    time.Date(2026, 10, 18, 16, 0, 0, 0, time.UTC),
+					Expires:  time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC),
+				},
+				{
+					// Без onset — с effective
+					Event:    "DAUERREGEN",
+					Headline: "Yellow Rain Warning issued for Düsseldorf",
+					Area:     "Düsseldorf",
+					Severity: severityMinor,
+					Onset:    time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC),
+					Expires:  time.Date(2026, 10, 18, 18, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			file:   "atom_links.xml",
+			region: "105111000",
+			lang:   langDE,
+			links: []string{
+				"https://www.dwd.de/DWD/warnungen/cap-feed/de/0001.xml",
+				"https://www.dwd.de/DWD/warnungen/cap-feed/de/0005.xml",
+			},
+		},
+		{file: "cap_truncated.xml", region: "105111000", lang: langDE, wantErr: true},
+		{file: "not_cap.html", region: "105111000", lang: langDE, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.region+"/"+string(tt.lang), func(t *testing.T) {
+			got, links, err := parseCAPDocument(bytes.NewReader(readTestdata(t, "warnings/"+tt.file)), tt.region, tt.lang)
+			got = supersede(got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %d warnings", len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d warnings, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Event != w.Event || g.Headline != w.Headline || g.Area != w.Area || g.Severity != w.Severity {
+					t.Errorf("warning %d = %+v, want %+v", i, g, w)
+				}
+				if !g.Onset.Equal(w.Onset) || !g.Expires.Equal(w.Expires) {
+					t.Errorf("warning %d: %s – %s, want %s – %s", i, g.Onset, g.Expires, w.Onset, w.Expires)
+				}
+				if g.Onset.Location() != displayZone {
+					t.Errorf("warning %d: onset in %s, want %s", i, g.Onset.Location(), displayZone)
+				}
+			}
+			if strings.Join(links, " ") != strings.Join(tt.links, " ") {
+				t.Errorf("links = %q, want %q", links, tt.links)
+			}
+		})
+	}
+}
+
+func TestWarningActive(t *testing.T) {
+	onset := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	expires := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		w    WeatherWarning
+		now  time.Time
+		want bool
+	}{
+		{"before onset", WeatherWarning{Onset: onset, Expires: expires}, onset.Add(-time.Second), false},
+		{"at onset", WeatherWarning{Onset: onset, Expires: expires}, onset, true},
+		{"running", WeatherWarning{Onset: onset, Expires: expires}, onset.Add(time.Hour), true},
+		{"at expiry", WeatherWarning{Onset: onset, Expires: expires}, expires, false},
+		{"no onset", WeatherWarning{Expires: expires}, onset.Add(-24 * time.Hour), true},
+		{"no expiry", WeatherWarning{Onset: onset}, onset.Add(24 * 365 * time.Hour), true},
+		{"no times", WeatherWarning{}, onset, true},
+	}
+	for _, tt := range tests {
+		if got := tt.w.Active(tt.now); got != tt.want {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want warningSeverity
+	}{
+		{"Minor", severityMinor},
+		{"moderate", severityModerate},
+		{" SEVERE ", severitySevere},
+		{"Extreme", severityExtreme},
+		{"Unknown", severityUnknown},
+		{"", severityUnknown},
+	}
+	for _, tt := range tests {
+		if got := parseSeverity(tt.in); got != tt.want {
+			t.Errorf("parseSeverity(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	if !(severityMinor < severityModerate && severityModerate < severitySevere && severitySevere < severityExtreme) {
+		t.Error("severities are not ranked")
+	}
+}
+
+// TestLoadWarnings гоняет ленту DWD со ссылками на CAP-документы через
+// загрузку целиком: докачка, фильтр по времени и порядок по серьёзности.
+func TestLoadWarnings(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/atom.xml":
+			feed := strings.ReplaceAll(string(readTestdata(t, "warnings/atom_links.xml")),
+				"https://www.dwd.de/DWD/warnungen/cap-feed/de", srv.URL)
+			w.Write([]byte(feed))
+		case "/0001.xml":
+			w.Write(readTestdata(t, "warnings/cap_dwd.xml"))
+		case "/0005.xml":
+			w.Write(readTestdata(t, "warnings/cap_dwd_severe.xml"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	swap(t, &warningsFeedURL, srv.URL+"/atom.xml")
+	swap(t, &warningsRegion, "105111000")
+
+	tests := []struct {
+		now  time.Time
+		want []string
+	}{
+		{berlin(t, 2026, 10, 18, 11, 0), nil},
+		{berlin(t, 2026, 10, 18, 12, 30), []string{"GLÄTTE"}},
+		{berlin(t, 2026, 10, 18, 15, 0), []string{"GLÄTTE", "STURMBÖEN"}},
+		{berlin(t, 2026, 10, 18, 23, 0), []string{"GLÄTTE"}},
+		{berlin(t, 2026, 10, 19, 10, 0), nil},
+	}
+	for _, tt := range tests {
+		useFakeClock(t, tt.now)
+		ws, err := loadWarnings(context.Background(), langDE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, w := range ws {
+			got = append(got, w.Event)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %q, want %q", tt.now.Format(time.DateTime), got, tt.want)
+		}
+	}
+}
+
+// Отмена убирает исходное предупреждение, обновление заменяет его — даже
+// если они пришли отдельными CAP-документами одной ленты.
+func TestLoadWarningsSuperseded(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		files := map[string]string{
+			"/0001.xml": "cap_dwd.xml",
+			"/0002.xml": "cap_cancel.xml", // отменяет 0001
+			"/0005.xml": "cap_dwd_severe.xml",
+			"/0006.xml": "cap_update.xml", // заменяет 0005, Moderate вместо Severe
+		}
+		if r.URL.Path == "/atom.xml" {
+			feed := strings.ReplaceAll(string(readTestdata(t, "warnings/atom_superseded.xml")),
+				"https://www.dwd.de/DWD/warnungen/cap-feed/de", srv.URL)
+			w.Write([]byte(feed))
+			return
+		}
+		name, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(readTestdata(t, "warnings/"+name))
+	}))
+	defer srv.Close()
+	swap(t, &warningsFeedURL, srv.URL+"/atom.xml")
+	swap(t, &warningsRegion, "105111000")
+	useFakeClock(t, berlin(t, 2026, 10, 18, 15, 0))
+
+	ws, err := loadWarnings(context.Background(), langDE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) != 1 {
+		t.Fatalf("got %+v, want only the updated GLÄTTE", ws)
+	}
+	if w := ws[0]; w.Event != "GLÄTTE" || w.Severity != severityModerate ||
+		!w.Expires.Equal(berlin(t, 2026, 10, 19, 6, 0)) {
+		t.Errorf("got %+v, want the update", w)
+	}
+}
+
+func TestSupersede(t *testing.T) {
+	refs := parseCAPReferences("opendata@dwd.de,A,2026-10-18T10:52:00+02:00\n\t opendata@dwd.de,B,2026-10-18T11:00:00+02:00 broken,,x")
+	if strings.Join(refs, " ") != "A B" {
+		t.Fatalf("references = %q", refs)
+	}
+
+	ws := []WeatherWarning{
+		{Event: "a", id: "A"},
+		{Event: "b", id: "B"},
+		{Event: "c", id: "C"},
+		{Event: "b2", id: "B2", refs: []string{"B"}},
+		{Event: "cancel", id: "X", refs: []string{"A", "Z"}, cancel: true},
+		{Event: "no id"},
+	}
+	var got []string
+	for _, w := range supersede(ws) {
+		got = append(got, w.Event)
+	}
+	if strings.Join(got, ",") != "c,b2,no id" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// TestCurrentWarningsExpire — сохранённое предупреждение пропадает, когда
+// истекло, даже если ленту с тех пор не удалось обновить.
+func TestCurrentWarningsExpire(t *testing.T) {
+	fc := useFakeClock(t, berlin(t, 2026, 10, 18, 15, 0))
+	swap(t, &warningsPreempt, severitySevere)
+	swap(t, &defaultLang, langDE)
+	swap(t, &latestWarnings, map[Lang][]WeatherWarning{
+		langDE: {{
+			Event:    "GLÄTTE",
+			Severity: severitySevere,
+			Onset:    berlin(t, 2026, 10, 18, 12, 0),
+			Expires:  berlin(t, 2026, 10, 18, 18, 0),
+		}},
+	})
+
+	if got := currentWarnings(langDE); len(got) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(got))
+	}
+	if got := currentWarnings(langEN); len(got) != 1 {
+		t.Fatalf("fallback to %s: got %d warnings, want 1", defaultLang, len(got))
+	}
+	if !preemptingWarning(langDE) {
+		t.Error("severe warning does not preempt")
+	}
+
+	fc.Set(berlin(t, 2026, 10, 18, 18, 0))
+	if got := currentWarnings(langDE); len(got) != 0 {
+		t.Errorf("expired warning still shown: %+v", got)
+	}
+	if preemptingWarning(langDE) {
+		t.Error("expired warning still preempts")
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+//
+// ---------- CONFIG ----------
+//
+
+// Abfuhrkalender — ICS-экспорт с сайта города или CSV «дата;вид»:
+//
+//	DASHBOARD_WASTE=/etc/homedashboard/abfuhr.ics
+//	DASHBOARD_WASTE=/etc/homedashboard/abfuhr.csv   # 2026-10-20;Restmüll или 20.10.2026;Biotonne
+//
+// Пусто — страницы нет. Вечером накануне вывоза страница закрепляется на
+// дашборде в окне DASHBOARD_WASTE_PIN.
+var (
+	wasteLocation            = envString("DASHBOARD_WASTE", "")
+	wastePinFrom, wastePinTo = parseWastePin(envString("DASHBOARD_WASTE_PIN", "18:00-22:00"))
+)
+
+const wasteHorizon = 60 // дней вперёд
+
+func wasteConfigured() bool { return wasteLocation != "" }
+
+// parseWastePin — окно закрепления; "off" или ошибка — не закреплять.
+func parseWastePin(s string) (TimeOfDay, TimeOfDay) {
+	if strings.EqualFold(s, "off") {
+		return 0, 0
+	}
+	from, to, err := parseClockSpan(s)
+	if err != nil {
+		log.Printf("config: DASHBOARD_WASTE_PIN: %v, pinning disabled", err)
+		return 0, 0
+	}
+	return from, to
+}
+
+//
+// ---------- BINS ----------
+//
+
+type wasteBin string
+
+const (
+	binRest      wasteBin = "rest"
+	binBio       wasteBin = "bio"
+	binPaper     wasteBin = "paper"
+	binPackaging wasteBin = "packaging"
+	binGlass     wasteBin = "glass"
+	binBulky     wasteBin = "bulky"
+	binHazardous wasteBin = "hazardous"
+	binOther     wasteBin = "other"
+)
+
+// Ключевые слова в названиях у разных городов. Порядок важен: стекло
+// раньше цветов баков — «Grünglas» и «Glas (grün, braun)» не Biotonne.
+var wasteKeywords = []struct {
+	bin   wasteBin
+	words []string
+}{
+	{binGlass, []string{"glas", "glass"}},
+	{binRest, []string{"rest", "grau", "schwarz"}},
+	{binBio, []string{"bio", "grün", "kompost", "laub"}},
+	{binPaper, []string{"papier", "pappe", "blau", "paper"}},
+	{binPackaging, []string{"gelb", "wertstoff", "verpackung", "plastic"}},
+	{binBulky, []string{"sperr", "bulky"}},
+	{binHazardous, []string{"schadstoff", "problem", "hazard"}},
+}
+
+func classifyWaste(name string) wasteBin {
+	lower := strings.ToLower(name)
+	for _, kw := range wasteKeywords {
+		for _, w := range kw.words {
+			if strings.Contains(lower, w) {
+				return kw.bin
+			}
+		}
+	}
+	return binOther
+}
+
+// wastePickup — ближайший вывоз одного вида. Name — как в исходном
+// календаре, показывается для binOther.
+type wastePickup struct {
+	Bin  wasteBin
+	Name string
+	Date time.Time
+}
+
+func (p wastePickup) Label(lang Lang) string {
+	if p.Bin == binOther {
+		return p.Name
+	}
+	return lang.T("waste." + string(p.Bin))
+}
+
+//
+// ---------- LOADING ----------
+//
+
+var (
+	wasteMu     sync.RWMutex
+	latestWaste []wastePickup
+
+	wasteICS = &icsSource{Location: wasteLocation}
+)
+
+// loadWaste — ближайший вывоз каждого вида, по дате.
+func loadWaste(ctx context.Context) ([]wastePickup, error) {
+	if !wasteConfigured() {
+		return nil, errors.New("DASHBOARD_WASTE is not set")
+	}
+
+	from := displayToday()
+	to := from.AddDate(0, 0, wasteHorizon)
+
+	var all []wastePickup
+	var err error
+	if strings.HasSuffix(strings.ToLower(wasteLocation), ".csv") {
+		all, err = loadWasteCSV(ctx, from, to)
+	} else {
+		all, err = loadWasteICS(ctx, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	next := map[string]wastePickup{}
+	for _, p := range all {
+		key := string(p.Bin)
+		if p.Bin == binOther {
+			key += ":" + p.Name
+		}
+		if cur, ok := next[key]; !ok || p.Date.Before(cur.Date) {
+			next[key] = p
+		}
+	}
+	pickups := make([]wastePickup, 0, len(next))
+	for _, p := range next {
+		pickups = append(pickups, p)
+	}
+	sort.Slice(pickups, func(i, j int) bool {
+		if !pickups[i].Date.Equal(pickups[j].Date) {
+			return pickups[i].Date.Before(pickups[j].Date)
+		}
+		return pickups[i].Bin < pickups[j].Bin
+	})
+	return pickups, nil
+}
+
+func loadWasteICS(ctx context.Context, from, to time.Time) ([]wastePickup, error) {
+	events, err := wasteICS.Events(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var out []wastePickup
+	for _, ev := range events {
+		day := ev.Start.In(displayZone)
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, displayZone)
+		if day.Before(from) {
+			continue
+		}
+		// «Restmüll, Biotonne» — несколько видов в одном событии
+		for _, name := range splitWasteNames(ev.Summary) {
+			out = append(out, wastePickup{Bin: classifyWaste(name), Name: name, Date: day})
+		}
+	}
+	return out, nil
+}
+
+func splitWasteNames(s string) []string {
+	var out []string
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '/' || r == '&' || r == '+'
+	}) {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// loadWasteCSV читает строки «дата;вид» (или через запятую). Заголовок и
+// строки с непонятной датой пропускаются.
+func loadWasteCSV(ctx context.Context, from, to time.Time) ([]wastePickup, error) {
+	data, err := readWasteFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []wastePickup
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		sep := ";"
+		if !strings.Contains(line, sep) {
+			sep = ","
+		}
+		date, name, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		day, err := parseWasteDate(strings.TrimSpace(date))
+		if err != nil || day.Before(from) || !day.Before(to) {
+			continue
+		}
+		name = strings.Trim(strings.TrimSpace(name), `"`)
+		out = append(out, wastePickup{Bin: classifyWaste(name), Name: name, Date: day})
+	}
+	return out, sc.Err()
+}
+
+func parseWasteDate(s string) (time.Time, error) {
+	s = strings.Trim(s, `"`)
+	for _, layout := range []string{time.DateOnly, "02.01.2006", "2.1.2006"} {
+		if t, err := time.ParseInLocation(layout, s, displayZone); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date %q", s)
+}
+
+func readWasteFile(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(wasteLocation, "http://") && !strings.HasPrefix(wasteLocation, "https://") {
+		return os.ReadFile(strings.TrimPrefix(wasteLocation, "file://"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wasteLocation, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := icsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, icsMaxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	// Обрезанный календарь хуже, чем ошибка — как и в parseICSLimited
+	if len(data) > icsMaxBody {
+		return nil, fmt.Errorf("feed larger than %d MB", icsMaxBody>>20)
+	}
+	return data, nil
+}
+
+// wastePinned — вечер перед вывозом: страницу пора показывать вместо ротации.
+func wastePinned() bool {
+	if wastePinFrom == wastePinTo || !nowInMinutes().Between(wastePinFrom, wastePinTo) {
+		return false
+	}
+	tomorrow := displayToday().AddDate(0, 0, 1)
+
+	wasteMu.RLock()
+	defer wasteMu.RUnlock()
+	for _, p := range latestWaste {
+		if p.Date.Equal(tomorrow) {
+			return true
+		}
+	}
+	return false
+}
+
+//
+// ---------- ICONS ----------
+//
+
+// wasteIcon — мусорный бак на колёсах; вид различается рисунком на баке,
+// цвета на экране нет. Стекло и Sperrmüll — свои силуэты.
+func wasteIcon(bin wasteBin) iconBitmap {
+	var b iconBitmap
+	switch bin {
+	case binGlass:
+		// Бутылка
+		b.rect(10, 1, 14, 3)
+		b.rect(10.5, 3, 13.5, 8)
+		b.polygon([2]float64{10.5, 8}, [2]float64{13.5, 8}, [2]float64{17, 12}, [2]float64{7, 12})
+		b.rect(7, 12, 17, 23)
+		b.plot(func(x, y float64) bool { return x >= 9 && x < 15 && y >= 15 && y < 20 }, false)
+		return b
+	case binBulky:
+		// Кресло
+		b.rect(4, 6, 20, 14)
+		b.rect(2, 10, 5, 20)
+		b.rect(19, 10, 22, 20)
+		b.rect(4, 14, 20, 18)
+		b.rect(4, 18, 6, 22)
+		b.rect(18, 18, 20, 22)
+		return b
+	}
+
+	// Крышка с ручкой, корпус сужается книзу, колесо
+	b.rect(9, 1, 15, 3)
+	b.rect(3, 3, 21, 6)
+	b.polygon([2]float64{4, 7}, [2]float64{20, 7}, [2]float64{18.5, 21}, [2]float64{5.5, 21})
+	b.disk(17, 21.5, 2)
+
+	// Окно под рисунок
+	inner := func(x, y float64) bool { return x >= 7 && x < 17 && y >= 9 && y < 19 }
+	b.plot(inner, false)
+	var mark func(x, y int) bool
+	switch bin {
+	case binRest:
+		mark = func(x, y int) bool { return true }
+	case binBio:
+		mark = func(x, y int) bool { return x%3 == 0 && y%3 == 0 }
+	case binPaper:
+		mark = func(x, y int) bool { return y%3 == 0 }
+	case binPackaging:
+		mark = func(x, y int) bool { return (x+y)%4 == 0 }
+	case binHazardous:
+		mark = func(x, y int) bool { return x == 11 || x == 12 || (y == 16 && x > 8 && x < 15) }
+	default:
+		mark = func(x, y int) bool { return false }
+	}
+	b.plot(func(x, y float64) bool { return inner(x, y) && mark(int(x), int(y)) }, true)
+	return b
+}
+
+//
+// ---------- RENDERING ----------
+//
+
+func renderWaste(pickups []wastePickup, lang Lang) *gg.Context {
+	const W, H = 800, 480
+
+	dc := gg.NewContext(W, H)
+	dc.SetRGB(1, 1, 1)
+	dc.Clear()
+	dc.SetRGB(0, 0, 0)
+
+	dc.SetFontFace(boldFace(20))
+	dc.DrawString(lang.T("waste.title"), 24, 44)
+	dc.SetLineWidth(2)
+	dc.DrawLine(24, 58, W-24, 58)
+	dc.Stroke()
+
+	if len(pickups) == 0 {
+		dc.SetFontFace(boldFace(16))
+		dc.DrawStringAnchored(lang.T("waste.none"), W/2, H/2, 0.5, 0.5)
+		return dc
+	}
+
+	today := displayToday()
+	y := 76.0
+	for i, p := range pickups {
+		if i == 6 {
+			break // больше шести строк не влезает
+		}
+		drawIcon(dc, wasteIcon(p.Bin), 32, y, 2)
+
+		dc.SetFontFace(boldFace(18))
+		dc.DrawString(p.Label(lang), 100, y+34)
+		dc.DrawStringAnchored(lang.Date(p.Date, "weekday_day"), 560, y+34, 1, 0)
+		dc.SetFontFace(boldFace(14))
+		dc.DrawStringAnchored(wasteWhen(p.Date, today, lang), W-32, y+34, 1, 0)
+		y += 66
+	}
+	return dc
+}
+
+// wasteWhen — «heute», «morgen» или «in 5 Tagen».
+func wasteWhen(day, today time.Time, lang Lang) string {
+	// Считаем по датам, а не часам: в дни перевода часов сутки не 24 ч
+	days := int(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	switch days {
+	case 0:
+		return lang.T("waste.today")
+	case 1:
+		return lang.T("waste.tomorrow")
+	}
+	return lang.T("waste.in_days", days)
+}
+
+func renderWasteBMP(ctx context.Context, lang Lang) ([]byte, error) {
+	pickups, err := cycleLoad(ctx, "waste", func() ([]wastePickup, error) {
+		return loadWaste(ctx)
+	})
+	if err != nil {
+		log.Println("error loading waste schedule:", err)
+		return nil, fmt.Errorf("unable to load waste schedule: %w", err)
+	}
+
+	wasteMu.Lock()
+	latestWaste = pickups
+	wasteMu.Unlock()
+
+	return encode1bppBMP(withWarningBanner(renderWaste(pickups, lang).Image(), lang))
+}
+
+func handleWasteBMP(w http.ResponseWriter, r *http.Request) {
+	serveCachedImage(w, r, &wasteCache)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyWaste(t *testing.T) {
+	tests := map[string]wasteBin{
+		"Restmüll":                 binRest,
+		"Restabfalltonne 14-tägl.": binRest,
+		"Graue Tonne":              binRest,
+		"Schwarze Tonne":           binRest,
+		"Biotonne":                 binBio,
+		"Grüne Tonne":              binBio,
+		"Grünschnitt":              binBio,
+		"Laubsammlung":             binBio,
+		"Altpapier":                binPaper,
+		"Blaue Tonne":              binPaper,
+		"Papier/Pappe/Kartonage":   binPaper,
+		"Gelber Sack":              binPackaging,
+		"Wertstofftonne":           binPackaging,
+		"LVP Verpackungen":         binPackaging,
+		"Altglas":                  binGlass,
+		"Grünglas":                 binGlass,
+		"Braunglas":                binGlass,
+		"Weißglas":                 binGlass,
+		"Glas (grün":               binGlass, // «Glas (grün, braun)» после splitWasteNames
+		"Schwarzglas":              binGlass,
+		"Sperrmüll":                binBulky,
+		"Bulky waste":              binBulky,
+		"Schadstoffmobil":          binHazardous,
+		"Problemabfälle":           binHazardous,
+		"Weihnachtsbäume":          binOther,
+		"Elektroschrott":           binOther,
+	}
+	for name, want := range tests {
+		if got := classifyWaste(name); got != want {
+			t.Errorf("classifyWaste(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestSplitWasteNames(t *testing.T) {
+	tests := map[string]string{
+		"Restmüll, Biotonne":       "Restmüll|Biotonne",
+		"Papier / Gelber Sack":     "Papier|Gelber Sack",
+		"Bio & Rest + Glas":        "Bio|Rest|Glas",
+		"Sperrmüll":                "Sperrmüll",
+		" , ":                      "",
+		"Glas (grün, braun, weiß)": "Glas (grün|braun|weiß)",
+	}
+	for in, want := range tests {
+		if got := strings.Join(splitWasteNames(in), "|"); got != want {
+			t.Errorf("splitWasteNames(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseWasteDate(t *testing.T) {
+	loc := berlin(t, 2026, 1, 1, 0, 0).Location()
+	swap(t, &displayZone, loc)
+	tests := []struct {
+		in   string
+		want string // пусто — ошибка
+	}{
+		{"2026-10-20", "2026-10-20"},
+		{"20.10.2026", "2026-10-20"},
+		{"3.11.2026", "2026-11-03"},
+		{`"20.10.2026"`, "2026-10-20"},
+		{"20/10/2026", ""},
+		{"Datum", ""},
+		{"2026-02-30", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseWasteDate(tt.in)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("parseWasteDate(%q) = %v, want error", tt.in, got)
+		case tt.want != "" && (err != nil || got.Format(time.DateOnly) != tt.want || got.Location() != loc):
+			t.Errorf("parseWasteDate(%q) = %v, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// CSV с заголовком, BOM, разными разделителями и датами; из нескольких
+// вывозов одного вида остаётся ближайший.
+func TestLoadWasteCSV(t *testing.T) {
+	loc := berlin(t, 2026, 1, 1, 0, 0).Location()
+	swap(t, &displayZone, loc)
+	useFakeClock(t, time.Date(2026, 10, 18, 9, 0, 0, 0, loc))
+
+	path := filepath.Join(t.TempDir(), "abfuhr.csv")
+	csv := "\ufeffDatum;Abfuhrart\r\n" +
+		"2026-10-17;Restmüll\r\n" + // вчера
+		"2026-10-18;Biotonne\r\n" +
+		"20.10.2026;\"Grünglas\"\r\n" +
+		"2026-10-21,Gelber Sack\r\n" +
+		"\r\n" +
+		"31.10.2026;Restmüll\r\n" +
+		"3.11.2026;Restmüll\r\n" +
+		"irgendwann;Sperrmüll\r\n" +
+		"Weihnachtsbäume\r\n" +
+		"2026-12-17;Sperrmüll\r\n" + // за горизонтом
+		"9.1.2027;Weihnachtsbäume\r\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &wasteLocation, path)
+
+	from := displayToday()
+	raw, err := loadWasteCSV(context.Background(), from, from.AddDate(0, 0, wasteHorizon))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(wasteSummary(raw)); got != "[2026-10-18 bio Biotonne 2026-10-20 glass Grünglas 2026-10-21 packaging Gelber Sack "+
+		"2026-10-31 rest Restmüll 2026-11-03 rest Restmüll]" {
+		t.Errorf("loadWasteCSV = %s", got)
+	}
+
+	pickups, err := loadWaste(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(wasteSummary(pickups)); got != "[2026-10-18 bio Biotonne 2026-10-20 glass Grünglas 2026-10-21 packaging Gelber Sack "+
+		"2026-10-31 rest Restmüll]" {
+		t.Errorf("loadWaste = %s", got)
+	}
+}
+
+func wasteSummary(pickups []wastePickup) []string {
+	var out []string
+	for _, p := range pickups {
+		out = append(out, fmt.Sprintf("%s %s %s", p.Date.Format(time.DateOnly), p.Bin, p.Name))
+	}
+	return out
+}
+
+// Страница закрепляется только вечером накануне вывоза, в окне
+// DASHBOARD_WASTE_PIN.
+func TestWastePinned(t *testing.T) {
+	loc := berlin(t, 2026, 1, 1, 0, 0).Location()
+	swap(t, &displayZone, loc)
+	fc := useFakeClock(t, time.Date(2026, 10, 19, 17, 59, 0, 0, loc))
+	from, to := parseWastePin("18:00-22:00")
+	swap(t, &wastePinFrom, from)
+	swap(t, &wastePinTo, to)
+	swap(t, &latestWaste, []wastePickup{
+		{Bin: binRest, Date: time.Date(2026, 10, 20, 0, 0, 0, 0, loc)},
+		{Bin: binBio, Date: time.Date(2026, 10, 23, 0, 0, 0, 0, loc)},
+	})
+
+	steps := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 10, 19, 17, 59, 0, 0, loc), false},
+		{time.Date(2026, 10, 19, 18, 0, 0, 0, loc), true},
+		{time.Date(2026, 10, 19, 21, 59, 0, 0, loc), true},
+		{time.Date(2026, 10, 19, 22, 0, 0, 0, loc), false},
+		{time.Date(2026, 10, 20, 19, 0, 0, 0, loc), false}, // в день вывоза уже поздно
+		{time.Date(2026, 10, 21, 19, 0, 0, 0, loc), false},
+		{time.Date(2026, 10, 22, 19, 0, 0, 0, loc), true},
+	}
+	for _, s := range steps {
+		fc.Set(s.at)
+		if got := wastePinned(); got != s.want {
+			t.Errorf("%s: pinned = %v, want %v", s.at.Format(time.DateTime), got, s.want)
+		}
+	}
+
+	// "off" выключает закрепление совсем
+	from, to = parseWastePin("off")
+	wastePinFrom, wastePinTo = from, to
+	fc.Set(time.Date(2026, 10, 19, 19, 0, 0, 0, loc))
+	if wastePinned() {
+		t.Error("pinned with DASHBOARD_WASTE_PIN=off")
+	}
+}
@@ -3,187 +3,225 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"html/template"
 	"log"
 	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 )
 
-//
-// ---------- RAW API RESPONSE ----------
-//
-
-type openMeteoResponse struct {
-	CurrentWeather struct {
-		Temperature2m       float64 `json:"temperature_2m"`
-		WeatherCode         int     `json:"weather_code"`
-		RelativeHumidity2m  float64 `json:"relative_humidity_2m"`
-		WindSpeed10m        float64 `json:"wind_speed_10m"`
-		ApparentTemperature float64 `json:"apparent_temperature"`
-		Time                string  `json:"time"`
-	} `json:"current"`
-
-	Daily struct {
-		Time                 []string  `json:"time"`
-		WeatherCode          []int     `json:"weather_code"`
-		Temperature2mMin     []float64 `json:"temperature_2m_min"`
-		Temperature2mMax     []float64 `json:"temperature_2m_max"`
-		RelativeHumidityMean []float64 `json:"relative_humidity_2m_mean"`
-	} `json:"daily"`
-}
-
 //
 // ---------- VIEW MODELS ----------
 //
 
 type WeatherDay struct {
+	Date      time.Time
+	Code      int
 	Label     string
 	DateShort string
-	Icon      string
+	IconSVG   template.HTML
 	Text      string
 	TempMin   int
 	TempMax   int
+	Sunrise   time.Time
+	Sunset    time.Time
+	Daylight  time.Duration
+	UVIndex   float64
+	PrecipSum float64
+	GustKmh   float64
+}
+
+// DaylightHM — длина светового дня в виде «10:42».
+func (d WeatherDay) DaylightHM() string {
+	m := int(d.Daylight.Round(time.Minute).Minutes())
+	return fmt.Sprintf("%d:%02d", m/60, m%60)
 }
 
 type WeatherView struct {
-	City        string
-	UpdatedAt   time.Time
-	CurrentTemp int
-	TodayMin    int
-	TodayMax    int
-	TodayText   string
-	TodayIcon   string
-	TodayTime   time.Time
-	Days        []WeatherDay
-	Humidity    int
-	WindKmh     float64
-	FeelsLike   int
+	Lang         Lang
+	City         string
+	UpdatedAt    time.Time
+	CurrentTemp  int
+	TodayMin     int
+	TodayMax     int
+	TodayCode    int
+	TodayText    string
+	TodayIconSVG template.HTML
+	TodayTime    time.Time
+	IsNight      bool
+	Today        WeatherDay
+	Days         []WeatherDay
+	Hours        []WeatherHour
+	HourlyChart  template.HTML
+	RainSummary  string
+	AirWarning   string
+	Indoor       []IndoorTile
+	Humidity     int
+	WindKmh      float64
+	FeelsLike    int
 }
 
-//
-// ---------- WEATHER CODE MAP ----------
-//
+// WeatherCompareView — сводная страница по всем местам.
+type WeatherCompareView struct {
+	Lang      Lang
+	UpdatedAt time.Time
+	Places    []WeatherComparePlace
+}
 
-type weatherDesc struct {
-	Icon string // один символ: S,R,W,C,N,G
-	Text string
-}
-
-var weatherMap = map[int]weatherDesc{
-	0:  {Icon: "☀", Text: "Klar"},
-	1:  {Icon: "🌤", Text: "Überwiegend klar"},
-	2:  {Icon: "⛅", Text: "Teilw. bewölkt"},
-	3:  {Icon: "☁", Text: "Bewölkt"},
-	45: {Icon: "〰", Text: "Nebel"},
-	48: {Icon: "〰", Text: "Nebel"},
-	51: {Icon: "☂", Text: "Leichter Niesel"},
-	53: {Icon: "☂", Text: "Nieselregen"},
-	55: {Icon: "☂", Text: "Starker Niesel"},
-	61: {Icon: "☂", Text: "Leichter Regen"},
-	63: {Icon: "☂", Text: "Regen"},
-	65: {Icon: "☂", Text: "Starker Regen"},
-	71: {Icon: "❄", Text: "Leichter Schnee"},
-	73: {Icon: "❄", Text: "Schnee"},
-	75: {Icon: "❄", Text: "Starker Schnee"},
-	80: {Icon: "☂", Text: "Schauer"},
-	81: {Icon: "☂", Text: "Starke Schauer"},
-	82: {Icon: "☂", Text: "Gewittrige Schauer"},
-	95: {Icon: "⛈", Text: "Gewitter"},
-	96: {Icon: "⛈", Text: "Gewitter & Hagel"},
-	99: {Icon: "⛈", Text: "Starkes Gewitter"},
-}
-
-func w(code int) weatherDesc {
-	if d, ok := weatherMap[code]; ok {
-		return d
-	}
-	return weatherDesc{"·", "—"}
+type WeatherComparePlace struct {
+	Name      string
+	LocalTime time.Time
+	Weather   *WeatherView // nil — данных нет
 }
 
 //
-// ---------- THE FUNCTION YOU NEED ----------
+// ---------- WEATHER CODE MAP ----------
 //
 
-func loadWeather(ctx context.Context) (*WeatherView, error) {
-	// Нормальные координаты Дюссельдорфа
-	const url = "https://api.open-meteo.com/v1/forecast" +
-		"?latitude=51.2277&longitude=6.7735" +
-		"&current=temperature_2m,weather_code,relative_humidity_2m,apparent_temperature,wind_speed_10m" +
-		"&daily=weather_code,temperature_2m_max,temperature_2m_min,relative_humidity_2m_mean" +
-		"&forecast_days=7" +
-		"&timezone=Europe%2FBerlin"
-
-	// Таймаут запроса (важно!)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
+// Известные WMO-коды; тексты к ним — в каталогах под ключами wmo.<код>,
+// иконки рисует weatherIcon.
+var knownWMOCodes = map[int]bool{
+	0: true, 1: true, 2: true, 3: true, 45: true, 48: true,
+	51: true, 53: true, 55: true, 56: true, 57: true,
+	61: true, 63: true, 65: true, 66: true, 67: true,
+	71: true, 73: true, 75: true, 77: true,
+	80: true, 81: true, 82: true, 85: true, 86: true,
+	95: true, 96: true, 99: true,
+}
+
+func weatherText(lang Lang, code int) string {
+	if !knownWMOCodes[code] {
+		return lang.T("wmo.unknown")
 	}
+	return lang.T(fmt.Sprintf("wmo.%d", code))
+}
 
-	// Короткий HTTP-клиент — НЕ зависнет
-	client := &http.Client{Timeout: 5 * time.Second}
+//
+// ---------- PROVIDER-NEUTRAL DATA ----------
+//
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("weather fetch: %w", err)
-	}
-	defer resp.Body.Close()
+// weatherData — прогноз в общих единицах, в который переводит ответ каждый
+// провайдер: температура в °C, ветер в км/ч, погода в виде WMO-кода.
+type weatherData struct {
+	Current weatherCurrent
+	Hours   []WeatherHour
+	Days    []weatherDayData
+}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status: %s", resp.Status)
-	}
+type weatherCurrent struct {
+	Time      time.Time
+	Temp      float64
+	FeelsLike float64
+	Code      int
+	Humidity  float64
+	WindKmh   float64
+}
 
-	var raw openMeteoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return nil, fmt.Errorf("json: %w", err)
-	}
+type weatherDayData struct {
+	Date      time.Time
+	Code      int
+	TempMin   float64
+	TempMax   float64
+	Sunrise   time.Time // нулевое — посчитать самим
+	Sunset    time.Time
+	Daylight  time.Duration
+	UVIndex   float64
+	PrecipSum float64
+	GustKmh   float64
+}
 
-	// Timezone
-	loc, _ := time.LoadLocation("Europe/Berlin")
-	currentTime, _ := time.ParseInLocation(time.RFC3339, raw.CurrentWeather.Time, loc)
+// buildWeatherView превращает данные любого провайдера во view для шаблона.
+func buildWeatherView(place weatherLocation, loc *time.Location, d weatherData) *WeatherView {
+	currentTime := d.Current.Time.In(loc)
 
-	// Подготовка view
 	view := &WeatherView{
-		City:        "Düsseldorf",
-		UpdatedAt:   time.Now().In(loc),
-		CurrentTemp: int(round(raw.CurrentWeather.Temperature2m)),
-		TodayMin:    int(round(raw.Daily.Temperature2mMin[0])),
-		TodayMax:    int(round(raw.Daily.Temperature2mMax[0])),
-		Humidity:    int(raw.CurrentWeather.RelativeHumidity2m),
-		TodayText:   w(raw.CurrentWeather.WeatherCode).Text,
-		TodayIcon:   w(raw.CurrentWeather.WeatherCode).Icon,
+		City:        place.Name,
+		UpdatedAt:   clock.Now().In(loc),
+		CurrentTemp: int(round(d.Current.Temp)),
+		Humidity:    int(d.Current.Humidity),
+		TodayCode:   d.Current.Code,
 		TodayTime:   currentTime,
-		FeelsLike:   int(math.Round(raw.CurrentWeather.ApparentTemperature)),
-		WindKmh:     raw.CurrentWeather.WindSpeed10m,
+		FeelsLike:   int(math.Round(d.Current.FeelsLike)),
+		WindKmh:     d.Current.WindKmh,
 	}
 
-	// Дни
-	for i, dateStr := range raw.Daily.Time {
-		date, _ := time.ParseInLocation("2006-01-02", dateStr, loc)
+	// Ближайшие часы
+	view.Hours = upcomingHours(d.Hours, currentTime)
+	view.HourlyChart = hourlyChartSVG(view.Hours)
 
-		label := func(i int) string {
-			if i == 0 {
-				return "Heute"
-			}
-			if i == 1 {
-				return "Morgen"
-			}
-			return date.Format("Mon") // Mo / Di / Mi …
-		}(i)
+	// Дни
+	for _, day := range d.Days {
+		date := day.Date.In(loc)
 
 		wd := WeatherDay{
-			Label:     label,
-			DateShort: date.Format("02.01."),
-			Icon:      w(raw.Daily.WeatherCode[i]).Icon,
-			Text:      w(raw.Daily.WeatherCode[i]).Text,
-			TempMin:   int(round(raw.Daily.Temperature2mMin[i])),
-			TempMax:   int(round(raw.Daily.Temperature2mMax[i])),
+			Date:      date,
+			Code:      day.Code,
+			IconSVG:   weatherIcon(day.Code, false).SVG(1),
+			TempMin:   int(round(day.TempMin)),
+			TempMax:   int(round(day.TempMax)),
+			Sunrise:   day.Sunrise,
+			Sunset:    day.Sunset,
+			Daylight:  day.Daylight,
+			UVIndex:   day.UVIndex,
+			PrecipSum: day.PrecipSum,
+			GustKmh:   day.GustKmh,
 		}
+		fillSunTimes(&wd, date, place)
 		view.Days = append(view.Days, wd)
 	}
 
-	return view, nil
+	// Сегодня и время суток
+	if len(view.Days) > 0 {
+		view.Today = view.Days[0]
+		view.TodayMin = view.Today.TempMin
+		view.TodayMax = view.Today.TempMax
+	}
+	if !view.Today.Sunrise.IsZero() {
+		view.IsNight = currentTime.Before(view.Today.Sunrise) || !currentTime.Before(view.Today.Sunset)
+	}
+	view.TodayIconSVG = weatherIcon(d.Current.Code, view.IsNight).SVG(2)
+
+	return view
+}
+
+// localize подставляет тексты и подписи на языке страницы. Провайдеры о
+// языке не знают, поэтому view локализуется уже перед рендером.
+func (v *WeatherView) localize(lang Lang) {
+	v.Lang = lang
+	v.TodayText = weatherText(lang, v.TodayCode)
+	v.RainSummary = rainSummary(lang, v.Hours)
+	for i := range v.Days {
+		d := &v.Days[i]
+		switch i {
+		case 0:
+			d.Label = lang.T("weather.today")
+		case 1:
+			d.Label = lang.T("weather.tomorrow")
+		default:
+			d.Label = lang.Date(d.Date, "weekday")
+		}
+		d.DateShort = lang.Date(d.Date, "day")
+		d.Text = weatherText(lang, d.Code)
+	}
+	if len(v.Days) > 0 {
+		v.Today = v.Days[0]
+	}
+}
+
+// fillSunTimes досчитывает восход, закат и длину дня по координатам,
+// если провайдер их не прислал.
+func fillSunTimes(wd *WeatherDay, date time.Time, place weatherLocation) {
+	if wd.Sunrise.IsZero() || wd.Sunset.IsZero() {
+		if rise, set, ok := sunTimes(date, place.Latitude, place.Longitude); ok {
+			wd.Sunrise, wd.Sunset = rise, set
+		}
+	}
+	if wd.Daylight == 0 && !wd.Sunrise.IsZero() {
+		wd.Daylight = wd.Sunset.Sub(wd.Sunrise)
+	}
 }
 
 func round(v float64) float64 {
@@ -193,21 +231,85 @@ func round(v float64) float64 {
 	return float64(int(v - 0.5))
 }
 
-func renderWeatherBMP(ctx context.Context) ([]byte, error) {
-	weatherView, err := loadWeather(ctx)
+// renderWeatherPages рендерит страницы погоды: первая — всегда домашнее
+// место, дальше либо сводка по всем местам, либо по странице на место.
+func renderWeatherPages(ctx context.Context, lang Lang) ([][]byte, error) {
+	places, _ := cycleLoad(ctx, "weather locations", func() ([]weatherLocation, error) {
+		return weatherLocations(ctx), nil
+	})
+
+	views := make([]*WeatherView, len(places))
+	for i, place := range places {
+		shared, err := cycleLoad(ctx, fmt.Sprintf("weather %s %.4f,%.4f", place.Name, place.Latitude, place.Longitude), func() (*WeatherView, error) {
+			return loadWeather(ctx, place)
+		})
+		if err != nil {
+			log.Println("error fetching:", err)
+			if i == 0 {
+				return nil, fmt.Errorf("unable to fetch weather: %w", err)
+			}
+			continue
+		}
+		// Копия: localize пишет подписи в view и его дни
+		view := *shared
+		view.Days = slices.Clone(shared.Days)
+		view.localize(lang)
+		view.AirWarning = airQualityWarning(lang)
+		if i == 0 {
+			view.Indoor = indoorTiles(clock.Now())
+		}
+		views[i] = &view
+	}
+
+	home, err := renderWeatherView(views[0])
 	if err != nil {
-		log.Println("error fetching:", err)
-		return nil, fmt.Errorf("unable to fetch weather: %w", err)
+		return nil, err
+	}
+	pages := [][]byte{home}
+	if len(places) == 1 {
+		return pages, nil
+	}
+
+	if weatherLayout == "pages" {
+		for _, view := range views[1:] {
+			if view == nil {
+				continue
+			}
+			if page, err := renderWeatherView(view); err == nil {
+				pages = append(pages, page)
+			}
+		}
+		return pages, nil
 	}
 
+	compare := WeatherCompareView{Lang: lang, UpdatedAt: views[0].UpdatedAt}
+	for i, place := range places {
+		row := WeatherComparePlace{Name: place.Name, Weather: views[i]}
+		if loc, err := place.location(); err == nil {
+			row.LocalTime = compare.UpdatedAt.In(loc)
+		}
+		compare.Places = append(compare.Places, row)
+	}
+	var buf bytes.Buffer
+	if err := weatherCompareTpl.Execute(&buf, compare); err != nil {
+		log.Println("execute template:", err)
+		return pages, nil
+	}
+	if page, err := htmlToBMP(buf.String(), lang); err == nil {
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+func renderWeatherView(weatherView *WeatherView) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := weatherTpl.Execute(&buf, weatherView); err != nil {
 		log.Println("execute template:", err)
-		return nil, fmt.Errorf("unable render quotes template: %w", err)
+		return nil, fmt.Errorf("unable render weather template: %w", err)
 	}
 	htmlStr := buf.String()
 
-	png, err := htmlToBMP(htmlStr)
+	png, err := htmlToBMP(htmlStr, weatherView.Lang)
 	if err != nil {
 		log.Println("html to png:", err)
 		return nil, fmt.Errorf("unable render bmp: %w", err)
@@ -216,6 +318,8 @@ func renderWeatherBMP(ctx context.Context) ([]byte, error) {
 	return png, nil
 }
 
+// handleWeatherBMP отдаёт страницу ?page=N (по умолчанию домашнюю).
 func handleWeatherBMP(w http.ResponseWriter, r *http.Request) {
-	serveCachedImage(w, r, &weatherCache)
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	serveCachedPage(w, r, &weatherPages, page)
 }
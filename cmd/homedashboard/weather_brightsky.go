@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+//
+// ---------- RAW API RESPONSE ----------
+//
+
+type brightSkyResponse struct {
+	Weather []struct {
+		Timestamp                time.Time `json:"timestamp"`
+		Temperature              *float64  `json:"temperature"`
+		RelativeHumidity         *float64  `json:"relative_humidity"`
+		WindSpeed                *float64  `json:"wind_speed"`      // км/ч
+		WindGustSpeed            *float64  `json:"wind_gust_speed"` // км/ч
+		Precipitation            *float64  `json:"precipitation"`
+		PrecipitationProbability *float64  `json:"precipitation_probability"`
+		Condition                string    `json:"condition"`
+		Icon                     string    `json:"icon"`
+	} `json:"weather"`
+}
+
+// Значки Bright Sky → WMO. Интенсивность осадков Bright Sky в значке не
+// передаёт, поэтому берём «средние» коды.
+var brightSkyIcons = map[string]int{
+	"clear-day":           0,
+	"clear-night":         0,
+	"partly-cloudy-day":   2,
+	"partly-cloudy-night": 2,
+	"cloudy":              3,
+	"fog":                 45,
+	"wind":                3,
+	"rain":                63,
+	"sleet":               66,
+	"snow":                73,
+	"hail":                96,
+	"thunderstorm":        95,
+}
+
+func brightSkyCode(icon string) int {
+	if code, ok := brightSkyIcons[icon]; ok {
+		return code
+	}
+	return 3
+}
+
+// val разыменовывает необязательное число из ответа Bright Sky.
+func val(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+//
+// ---------- PROVIDER ----------
+//
+
+// brightSkyProvider — бесплатный JSON-фасад над открытыми данными DWD
+// (MOSMIX-прогноз и наблюдения станций).
+type brightSkyProvider struct{}
+
+func (brightSkyProvider) Name() string { return "brightsky" }
+
+func (brightSkyProvider) Load(ctx context.Context, place weatherLocation) (*WeatherView, error) {
+	loc, err := place.location()
+	if err != nil {
+		return nil, err
+	}
+	today := dayOf(clock.Now(), loc)
+	u := "https://api.brightsky.dev/weather" +
+		fmt.Sprintf("?lat=%.4f&lon=%.4f", place.Latitude, place.Longitude) +
+		"&date=" + url.QueryEscape(today.Format(time.RFC3339)) +
+		"&last_date=" + url.QueryEscape(today.AddDate(0, 0, 7).Format(time.RFC3339)) +
+		"&tz=" + url.QueryEscape(place.TimeZone)
+
+	var raw brightSkyResponse
+	if err := getJSON(ctx, u, nil, &raw); err != nil {
+		return nil, err
+	}
+
+	var d weatherData
+	var codes []int
+	gust := map[time.Time]float64{}
+	now := clock.Now()
+	for _, rec := range raw.Weather {
+		if rec.Temperature == nil {
+			continue
+		}
+		t := rec.Timestamp.In(loc)
+		code := brightSkyCode(rec.Icon)
+		d.Hours = append(d.Hours, WeatherHour{
+			Time:       t,
+			Temp:       *rec.Temperature,
+			PrecipProb: int(val(rec.PrecipitationProbability)),
+			PrecipMM:   val(rec.Precipitation),
+		})
+		codes = append(codes, code)
+
+		day := dayOf(t, loc)
+		gust[day] = max(gust[day], val(rec.WindGustSpeed))
+
+		// Текущие условия — последняя запись не позже текущего часа
+		if !t.After(now) {
+			d.Current = weatherCurrent{
+				Time:      t,
+				Temp:      *rec.Temperature,
+				FeelsLike: *rec.Temperature,
+				Code:      code,
+				Humidity:  val(rec.RelativeHumidity),
+				WindKmh:   val(rec.WindSpeed),
+			}
+		}
+	}
+	if len(d.Hours) == 0 {
+		return nil, fmt.Errorf("brightsky: no weather records")
+	}
+
+	d.Days = dailyFromHours(d.Hours, codes, loc)
+	for i := range d.Days {
+		d.Days[i].GustKmh = gust[d.Days[i].Date]
+	}
+	return buildWeatherView(place, loc, d), nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+//
+// ---------- HOURLY FORECAST ----------
+//
+
+const (
+	hourlyHours   = 24
+	hourlyChartW  = 764
+	hourlyChartH  = 96
+	rainThreshold = 40 // % вероятности, с которого стоит брать зонт
+)
+
+type WeatherHour struct {
+	Time       time.Time
+	Temp       float64
+	PrecipProb int
+	PrecipMM   float64
+}
+
+// upcomingHours берёт ближайшие hourlyHours часов, начиная с текущего;
+// now — в зоне места.
+func upcomingHours(all []WeatherHour, now time.Time) []WeatherHour {
+	from := startOfHour(now, now.Location())
+	var hours []WeatherHour
+	for _, h := range all {
+		if h.Time.Before(from) {
+			continue
+		}
+		hours = append(hours, h)
+		if len(hours) == hourlyHours {
+			break
+		}
+	}
+	return hours
+}
+
+// startOfHour — начало часа по местным часам. Truncate считает от UTC и в
+// зонах со сдвигом не на целый час (Индия, Непал) попадает в середину часа.
+func startOfHour(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+}
+
+// rainSummary отвечает на главный вопрос утра: нужен ли зонт.
+func rainSummary(lang Lang, hours []WeatherHour) string {
+	var total float64
+	for _, h := range hours {
+		total += h.PrecipMM
+	}
+	for i, h := range hours {
+		if h.PrecipProb < rainThreshold {
+			continue
+		}
+		if i == 0 {
+			return lang.T("weather.rain_now", h.PrecipProb, lang.Number(total, 1))
+		}
+		return lang.T("weather.rain_from", h.Time.Format("15"), h.PrecipProb, lang.Number(total, 1))
+	}
+	return lang.T("weather.rain_none")
+}
+
+// hourlyChartSVG рисует только чёрным по белому и без сглаживания:
+// сверху линия температуры, снизу столбики вероятности осадков,
+// закрашенные на высоту ожидаемого количества (до 5 мм).
+func hourlyChartSVG(hours []WeatherHour) template.HTML {
+	if len(hours) < 2 {
+		return ""
+	}
+
+	const (
+		left       = 28.0
+		right      = 4.0
+		tempTop    = 14.0
+		tempBottom = 44.0
+		barsTop    = 50.0
+		barsBottom = 80.0
+		labelY     = 94.0
+		maxMM      = 5.0
+	)
+	step := (hourlyChartW - left - right) / float64(len(hours))
+
+	minT, maxT := hours[0].Temp, hours[0].Temp
+	minI, maxI := 0, 0
+	for i, h := range hours {
+		if h.Temp < minT {
+			minT, minI = h.Temp, i
+		}
+		if h.Temp > maxT {
+			maxT, maxI = h.Temp, i
+		}
+	}
+	span := math.Max(maxT-minT, 1)
+	x := func(i int) float64 { return left + step*(float64(i)+0.5) }
+	y := func(t float64) float64 { return tempBottom - (t-minT)/span*(tempBottom-tempTop) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" shape-rendering="crispEdges" font-size="12" font-weight="700">`,
+		hourlyChartW, hourlyChartH)
+
+	// Температура
+	b.WriteString(`<polyline fill="none" stroke="#000" stroke-width="2" points="`)
+	for i, h := range hours {
+		fmt.Fprintf(&b, "%.0f,%.0f ", x(i), y(h.Temp))
+	}
+	b.WriteString(`"/>`)
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle">%+.0f°</text>`, x(maxI), y(maxT)-4, maxT)
+	if minI != maxI {
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle">%+.0f°</text>`, x(minI), y(minT)+14, minT)
+	}
+
+	// Осадки
+	fmt.Fprintf(&b, `<text x="0" y="%.0f">%%</text>`, barsBottom)
+	fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#000"/>`, left, barsBottom, hourlyChartW-right, barsBottom)
+	for i, h := range hours {
+		bx := math.Round(left + step*float64(i) + 1)
+		bw := math.Max(math.Round(step)-3, 1)
+		if h.PrecipProb > 0 {
+			bh := math.Round(float64(h.PrecipProb) / 100 * (barsBottom - barsTop))
+			fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" fill="#fff" stroke="#000"/>`,
+				bx, barsBottom-bh, bw, bh)
+		}
+		if h.PrecipMM >= 0.1 {
+			mh := math.Max(math.Round(math.Min(h.PrecipMM, maxMM)/maxMM*(barsBottom-barsTop)), 2)
+			fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" fill="#000"/>`,
+				bx, barsBottom-mh, bw, mh)
+		}
+		if h.Time.Hour()%3 == 0 {
+			fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" text-anchor="middle">%s</text>`, x(i), labelY, h.Time.Format("15"))
+		}
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
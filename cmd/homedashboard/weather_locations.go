@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ---------- LOCATIONS ----------
+//
+
+// Дополнительные места к homeLocation, через «;»:
+//
+//	DASHBOARD_WEATHER_LOCATIONS="Büro=Essen; Oma=Winterberg|brightsky; Urlaub=Palma"
+//
+// Слева от «=» подпись (можно опустить), справа — название для геокодера,
+// после «|» — свои провайдеры через запятую вместо общих.
+var weatherLocationSpecs = parseLocationSpecs(envString("DASHBOARD_WEATHER_LOCATIONS", ""))
+
+// Как показывать несколько мест: "compact" — одна сводная страница после
+// домашней, "pages" — по полной странице на каждое место.
+var weatherLayout = envString("DASHBOARD_WEATHER_LAYOUT", "compact")
+
+type locationSpec struct {
+	Label     string
+	Query     string
+	Providers []string
+}
+
+func parseLocationSpecs(s string) []locationSpec {
+	var specs []locationSpec
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var spec locationSpec
+		item, providers, _ := strings.Cut(item, "|")
+		for _, p := range strings.Split(providers, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				spec.Providers = append(spec.Providers, p)
+			}
+		}
+		if label, query, ok := strings.Cut(item, "="); ok {
+			spec.Label, spec.Query = strings.TrimSpace(label), strings.TrimSpace(query)
+		} else {
+			spec.Query = strings.TrimSpace(item)
+		}
+		if spec.Query == "" {
+			log.Printf("config: empty weather location %q skipped", item)
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// weatherLocations — домашнее место и все дополнительные, которые удалось
+// геокодировать. Неудачные пропускаются, чтобы не ронять всю страницу.
+func weatherLocations(ctx context.Context) []weatherLocation {
+	places := []weatherLocation{homeLocation}
+	for _, spec := range weatherLocationSpecs {
+		geo, err := geocode(ctx, spec.Query)
+		if err != nil {
+			log.Printf("geocode %q: %v", spec.Query, err)
+			continue
+		}
+
+		place := weatherLocation{
+			Name:      geo.Name,
+			Latitude:  geo.Latitude,
+			Longitude: geo.Longitude,
+			TimeZone:  geo.TimeZone,
+			Providers: spec.Providers,
+		}
+		if spec.Label != "" {
+			place.Name = spec.Label
+		}
+		if place.TimeZone == "" {
+			place.TimeZone = homeLocation.TimeZone
+		}
+		if len(place.Providers) == 0 {
+			place.Providers = homeLocation.Providers
+		}
+		places = append(places, place)
+	}
+	return places
+}
+
+//
+// ---------- GEOCODING ----------
+//
+
+type geocodeResult struct {
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	TimeZone  string  `json:"timezone"`
+}
+
+type openMeteoGeocodeResponse struct {
+	Results []geocodeResult `json:"results"`
+}
+
+// Координаты городов не меняются, поэтому результаты геокодера храним в
+// файле и в API ходим только за новыми названиями.
+var geocodeCachePath = envString("DASHBOARD_GEOCODE_CACHE", defaultGeocodeCachePath())
+
+// Неудачные запросы (нет такого места, API недоступен) помним
+// geocodeRetryAfter, чтобы не спрашивать о них на каждом рендере.
+const geocodeRetryAfter = 30 * time.Minute
+
+type geocodeFailure struct {
+	err error
+	at  time.Time
+}
+
+var (
+	geocodeMu       sync.Mutex
+	geocodeCache    map[string]geocodeResult
+	geocodeFailures = map[string]geocodeFailure{}
+)
+
+func defaultGeocodeCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "homedashboard", "geocode.json")
+}
+
+func geocode(ctx context.Context, query string) (geocodeResult, error) {
+	key := strings.ToLower(strings.TrimSpace(query))
+
+	geocodeMu.Lock()
+	if geocodeCache == nil {
+		geocodeCache = readGeocodeCache()
+	}
+	if res, ok := geocodeCache[key]; ok {
+		geocodeMu.Unlock()
+		return res, nil
+	}
+	if f, ok := geocodeFailures[key]; ok && clock.Now().Sub(f.at) < geocodeRetryAfter {
+		geocodeMu.Unlock()
+		return geocodeResult{}, f.err
+	}
+	geocodeMu.Unlock()
+
+	// Сеть — без блокировки: медленный запрос не держит остальные места
+	res, err := lookupPlace(ctx, query)
+
+	geocodeMu.Lock()
+	defer geocodeMu.Unlock()
+	if err != nil {
+		geocodeFailures[key] = geocodeFailure{err: err, at: clock.Now()}
+		return geocodeResult{}, err
+	}
+	delete(geocodeFailures, key)
+	geocodeCache[key] = res
+	if err := writeGeocodeCache(geocodeCache); err != nil {
+		log.Println("geocode cache:", err)
+	}
+	return res, nil
+}
+
+func lookupPlace(ctx context.Context, query string) (geocodeResult, error) {
+	u := "https://geocoding-api.open-meteo.com/v1/search" +
+		"?name=" + url.QueryEscape(query) +
+		"&count=1&language=de&format=json"
+
+	var raw openMeteoGeocodeResponse
+	if err := getJSON(ctx, u, nil, &raw); err != nil {
+		return geocodeResult{}, err
+	}
+	if len(raw.Results) == 0 {
+		return geocodeResult{}, errors.New("no such place")
+	}
+	return raw.Results[0], nil
+}
+
+func readGeocodeCache() map[string]geocodeResult {
+	cache := map[string]geocodeResult{}
+	data, err := os.ReadFile(geocodeCachePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Println("geocode cache:", err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Println("geocode cache:", err)
+		return map[string]geocodeResult{}
+	}
+	return cache
+}
+
+// writeGeocodeCache пишет через временный файл, чтобы не оставить
+// половинку при выключении Pi посреди записи.
+func writeGeocodeCache(cache map[string]geocodeResult) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(geocodeCachePath), 0o755); err != nil {
+		return err
+	}
+	tmp := geocodeCachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, geocodeCachePath); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//
+// ---------- RAW API RESPONSE ----------
+//
+
+type metNorwayResponse struct {
+	Properties struct {
+		Timeseries []struct {
+			Time time.Time `json:"time"`
+			Data struct {
+				Instant struct {
+					Details struct {
+						AirTemperature   float64 `json:"air_temperature"`
+						RelativeHumidity float64 `json:"relative_humidity"`
+						WindSpeed        float64 `json:"wind_speed"`         // м/с
+						WindSpeedOfGust  float64 `json:"wind_speed_of_gust"` // м/с
+						UVIndexClearSky  float64 `json:"ultraviolet_index_clear_sky"`
+					} `json:"details"`
+				} `json:"instant"`
+				Next1Hours *metNorwayPeriod `json:"next_1_hours"`
+				Next6Hours *metNorwayPeriod `json:"next_6_hours"`
+			} `json:"data"`
+		} `json:"timeseries"`
+	} `json:"properties"`
+}
+
+type metNorwayPeriod struct {
+	Summary struct {
+		SymbolCode string `json:"symbol_code"`
+	} `json:"summary"`
+	Details struct {
+		PrecipitationAmount        float64 `json:"precipitation_amount"`
+		ProbabilityOfPrecipitation float64 `json:"probability_of_precipitation"`
+	} `json:"details"`
+}
+
+// Символы yr.no без суффиксов _day/_night/_polartwilight → WMO.
+var metNorwaySymbols = map[string]int{
+	"clearsky":                   0,
+	"fair":                       1,
+	"partlycloudy":               2,
+	"cloudy":                     3,
+	"fog":                        45,
+	"lightrain":                  61,
+	"rain":                       63,
+	"heavyrain":                  65,
+	"lightrainshowers":           80,
+	"rainshowers":                81,
+	"heavyrainshowers":           82,
+	"lightsleet":                 66,
+	"sleet":                      66,
+	"heavysleet":                 67,
+	"lightsleetshowers":          66,
+	"sleetshowers":               66,
+	"heavysleetshowers":          67,
+	"lightsnow":                  71,
+	"snow":                       73,
+	"heavysnow":                  75,
+	"lightsnowshowers":           85,
+	"snowshowers":                85,
+	"heavysnowshowers":           86,
+	"lightrainandthunder":        95,
+	"rainandthunder":             95,
+	"heavyrainandthunder":        99,
+	"lightrainshowersandthunder": 95,
+	"rainshowersandthunder":      95,
+	"heavyrainshowersandthunder": 99,
+	"lightsnowandthunder":        95,
+	"snowandthunder":             95,
+	"heavysnowandthunder":        99,
+	"lightsleetandthunder":       95,
+	"sleetandthunder":            95,
+	"heavysleetandthunder":       99,
+}
+
+func metNorwayCode(symbol string) int {
+	base, _, _ := strings.Cut(symbol, "_")
+	if code, ok := metNorwaySymbols[base]; ok {
+		return code
+	}
+	return 3
+}
+
+//
+// ---------- PROVIDER ----------
+//
+
+// metNorwayProvider — Locationforecast 2.0 от MET Norway (данные yr.no).
+// API требует User-Agent с контактом, иначе отвечает 403.
+type metNorwayProvider struct{}
+
+func (metNorwayProvider) Name() string { return "met-norway" }
+
+func (metNorwayProvider) Load(ctx context.Context, place weatherLocation) (*WeatherView, error) {
+	url := "https://api.met.no/weatherapi/locationforecast/2.0/complete" +
+		fmt.Sprintf("?lat=%.4f&lon=%.4f", place.Latitude, place.Longitude)
+	headers := map[string]string{
+		"User-Agent": "homedashboard/1.0 github.com/mvpotter/homedashboard",
+	}
+
+	var raw metNorwayResponse
+	if err := getJSON(ctx, url, headers, &raw); err != nil {
+		return nil, err
+	}
+	series := raw.Properties.Timeseries
+	if len(series) == 0 {
+		return nil, fmt.Errorf("met.no: empty timeseries")
+	}
+
+	loc, err := place.location()
+	if err != nil {
+		return nil, err
+	}
+	var d weatherData
+	var codes []int
+	uv := map[time.Time]float64{}
+	gust := map[time.Time]float64{}
+	for _, ts := range series {
+		period := ts.Data.Next1Hours
+		if period == nil {
+			period = ts.Data.Next6Hours
+		}
+		if period == nil {
+			continue
+		}
+		inst := ts.Data.Instant.Details
+		d.Hours = append(d.Hours, WeatherHour{
+			Time:       ts.Time.In(loc),
+			Temp:       inst.AirTemperature,
+			PrecipProb: int(period.Details.ProbabilityOfPrecipitation),
+			PrecipMM:   period.Details.PrecipitationAmount,
+		})
+		codes = append(codes, metNorwayCode(period.Summary.SymbolCode))
+
+		day := dayOf(ts.Time, loc)
+		uv[day] = max(uv[day], inst.UVIndexClearSky)
+		gust[day] = max(gust[day], inst.WindSpeedOfGust*3.6)
+	}
+	if len(d.Hours) == 0 {
+		return nil, fmt.Errorf("met.no: no forecast periods")
+	}
+
+	now := series[0]
+	d.Current = weatherCurrent{
+		Time:      now.Time.In(loc),
+		Temp:      now.Data.Instant.Details.AirTemperature,
+		FeelsLike: now.Data.Instant.Details.AirTemperature,
+		Code:      codes[0],
+		Humidity:  now.Data.Instant.Details.RelativeHumidity,
+		WindKmh:   now.Data.Instant.Details.WindSpeed * 3.6,
+	}
+
+	d.Days = dailyFromHours(d.Hours, codes, loc)
+	for i := range d.Days {
+		d.Days[i].UVIndex = uv[d.Days[i].Date]
+		d.Days[i].GustKmh = gust[d.Days[i].Date]
+	}
+	return buildWeatherView(place, loc, d), nil
+}
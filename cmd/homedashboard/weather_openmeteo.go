@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+)
+
+//
+// ---------- RAW API RESPONSE ----------
+//
+
+type openMeteoResponse struct {
+	CurrentWeather struct {
+		Temperature2m       float64 `json:"temperature_2m"`
+		WeatherCode         int     `json:"weather_code"`
+		RelativeHumidity2m  float64 `json:"relative_humidity_2m"`
+		WindSpeed10m        float64 `json:"wind_speed_10m"`
+		ApparentTemperature float64 `json:"apparent_temperature"`
+		Time                string  `json:"time"`
+	} `json:"current"`
+
+	Hourly struct {
+		Time                     []string  `json:"time"`
+		Temperature2m            []float64 `json:"temperature_2m"`
+		PrecipitationProbability []int     `json:"precipitation_probability"`
+		Precipitation            []float64 `json:"precipitation"`
+	} `json:"hourly"`
+
+	Daily struct {
+		Time                 []string  `json:"time"`
+		WeatherCode          []int     `json:"weather_code"`
+		Temperature2mMin     []float64 `json:"temperature_2m_min"`
+		Temperature2mMax     []float64 `json:"temperature_2m_max"`
+		RelativeHumidityMean []float64 `json:"relative_humidity_2m_mean"`
+		Sunrise              []string  `json:"sunrise"`
+		Sunset               []string  `json:"sunset"`
+		DaylightDuration     []float64 `json:"daylight_duration"`
+		UVIndexMax           []float64 `json:"uv_index_max"`
+		PrecipitationSum     []float64 `json:"precipitation_sum"`
+		WindGusts10mMax      []float64 `json:"wind_gusts_10m_max"`
+	} `json:"daily"`
+}
+
+//
+// ---------- PROVIDER ----------
+//
+
+type openMeteoProvider struct{}
+
+func (openMeteoProvider) Name() string { return "open-meteo" }
+
+func (openMeteoProvider) Load(ctx context.Context, place weatherLocation) (*WeatherView, error) {
+	u := "https://api.open-meteo.com/v1/forecast" +
+		fmt.Sprintf("?latitude=%.4f&longitude=%.4f", place.Latitude, place.Longitude) +
+		"&current=temperature_2m,weather_code,relative_humidity_2m,apparent_temperature,wind_speed_10m" +
+		"&hourly=temperature_2m,precipitation_probability,precipitation" +
+		"&daily=weather_code,temperature_2m_max,temperature_2m_min,relative_humidity_2m_mean," +
+		"sunrise,sunset,daylight_duration,uv_index_max,precipitation_sum,wind_gusts_10m_max" +
+		"&forecast_days=7" +
+		"&timezone=" + url.QueryEscape(place.TimeZone)
+
+	loc, err := place.location()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw openMeteoResponse
+	if err := getJSON(ctx, u, nil, &raw); err != nil {
+		return nil, err
+	}
+	d, err := raw.weatherData(loc)
+	if err != nil {
+		return nil, fmt.Errorf("open-meteo response: %w", err)
+	}
+	return buildWeatherView(place, loc, d), nil
+}
+
+// Open-Meteo отдаёт время в зоне из параметра timezone, но без смещения.
+const openMeteoTime = "2006-01-02T15:04"
+
+// weatherData проверяет ответ и переводит его в общий вид. Частичный ответ
+// (обрезанные или разной длины серии, кривые даты) — ошибка, а не паника:
+// тогда loadWeather переходит к следующему провайдеру.
+func (raw openMeteoResponse) weatherData(loc *time.Location) (weatherData, error) {
+	cur := raw.CurrentWeather
+	if cur.Time == "" {
+		return weatherData{}, errors.New("current.time is missing")
+	}
+	currentTime, err := time.ParseInLocation(openMeteoTime, cur.Time, loc)
+	if err != nil {
+		return weatherData{}, fmt.Errorf("current.time: %w", err)
+	}
+
+	d := weatherData{
+		Current: weatherCurrent{
+			Time:      currentTime,
+			Temp:      cur.Temperature2m,
+			FeelsLike: cur.ApparentTemperature,
+			Code:      cur.WeatherCode,
+			Humidity:  cur.RelativeHumidity2m,
+			WindKmh:   cur.WindSpeed10m,
+		},
+	}
+
+	hourly := raw.Hourly
+	if err := sameLength("hourly", len(hourly.Time), map[string]int{
+		"temperature_2m": len(hourly.Temperature2m),
+	}, map[string]int{
+		"precipitation_probability": len(hourly.PrecipitationProbability),
+		"precipitation":             len(hourly.Precipitation),
+	}); err != nil {
+		return weatherData{}, err
+	}
+	for i, ts := range hourly.Time {
+		t, err := time.ParseInLocation(openMeteoTime, ts, loc)
+		if err != nil {
+			return weatherData{}, fmt.Errorf("hourly.time[%d]: %w", i, err)
+		}
+		d.Hours = append(d.Hours, WeatherHour{
+			Time:       t,
+			Temp:       hourly.Temperature2m[i],
+			PrecipProb: atInt(hourly.PrecipitationProbability, i),
+			PrecipMM:   at(hourly.Precipitation, i),
+		})
+	}
+
+	daily := raw.Daily
+	if len(daily.Time) == 0 {
+		return weatherData{}, errors.New("daily.time is empty")
+	}
+	if err := sameLength("daily", len(daily.Time), map[string]int{
+		"weather_code":       len(daily.WeatherCode),
+		"temperature_2m_min": len(daily.Temperature2mMin),
+		"temperature_2m_max": len(daily.Temperature2mMax),
+	}, map[string]int{
+		"sunrise":            len(daily.Sunrise),
+		"sunset":             len(daily.Sunset),
+		"daylight_duration":  len(daily.DaylightDuration),
+		"uv_index_max":       len(daily.UVIndexMax),
+		"precipitation_sum":  len(daily.PrecipitationSum),
+		"wind_gusts_10m_max": len(daily.WindGusts10mMax),
+	}); err != nil {
+		return weatherData{}, err
+	}
+	for i, dateStr := range daily.Time {
+		date, err := time.ParseInLocation("2006-01-02", dateStr, loc)
+		if err != nil {
+			return weatherData{}, fmt.Errorf("daily.time[%d]: %w", i, err)
+		}
+		day := weatherDayData{
+			Date:      date,
+			Code:      daily.WeatherCode[i],
+			TempMin:   daily.Temperature2mMin[i],
+			TempMax:   daily.Temperature2mMax[i],
+			UVIndex:   at(daily.UVIndexMax, i),
+			PrecipSum: at(daily.PrecipitationSum, i),
+			GustKmh:   at(daily.WindGusts10mMax, i),
+		}
+		if i < len(daily.Sunrise) && i < len(daily.Sunset) {
+			// Полярный день/ночь приходят пустыми строками — досчитает fillSunTimes
+			day.Sunrise, _ = time.ParseInLocation(openMeteoTime, daily.Sunrise[i], loc)
+			day.Sunset, _ = time.ParseInLocation(openMeteoTime, daily.Sunset[i], loc)
+		}
+		if i < len(daily.DaylightDuration) {
+			day.Daylight = time.Duration(daily.DaylightDuration[i] * float64(time.Second))
+		}
+		d.Days = append(d.Days, day)
+	}
+
+	return d, nil
+}
+
+// sameLength сверяет длины серий с длиной оси времени. Обязательные серии
+// должны совпадать по длине, необязательные — либо совпадать, либо
+// отсутствовать целиком.
+func sameLength(block string, n int, required, optional map[string]int) error {
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		if l := required[name]; l != n {
+			return fmt.Errorf("%s.%s: %d values for %d timestamps", block, name, l, n)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(optional)) {
+		if l := optional[name]; l != 0 && l != n {
+			return fmt.Errorf("%s.%s: %d values for %d timestamps", block, name, l, n)
+		}
+	}
+	return nil
+}
+
+// at безопасно достаёт i-е значение необязательной серии.
+func at(values []float64, i int) float64 {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
+
+func atInt(values []int, i int) int {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
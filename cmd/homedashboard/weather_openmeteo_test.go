@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Ответы Open-Meteo лежат в testdata/openmeteo: целый и испорченные на
+// разные лады. Кривой ответ должен давать ошибку, а не панику.
+func TestOpenMeteoWeatherData(t *testing.T) {
+	berlinLoc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arctic, err := time.LoadLocation("Arctic/Longyearbyen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		loc     *time.Location
+		wantErr string // подстрока; пусто — ответ годный
+		check   func(t *testing.T, d weatherData)
+	}{
+		{
+			file: "forecast.json",
+			loc:  berlinLoc,
+			check: func(t *testing.T, d weatherData) {
+				if want := time.Date(2026, 10, 18, 14, 15, 0, 0, berlinLoc); !d.Current.Time.Equal(want) {
+					t.Errorf("current time = %s, want %s", d.Current.Time, want)
+				}
+				if d.Current.Temp != 13.8 || d.Current.Code != 3 || d.Current.Humidity != 71 {
+					t.Errorf("current = %+v", d.Current)
+				}
+				if len(d.Hours) != 48 || len(d.Days) != 3 {
+					t.Fatalf("got %d hours and %d days", len(d.Hours), len(d.Days))
+				}
+				if h := d.Hours[13]; h.Time.Hour() != 13 || h.PrecipProb != 45 || h.PrecipMM != 0.5 {
+					t.Errorf("hour 13 = %+v", h)
+				}
+				day := d.Days[1]
+				if day.Code != 61 || day.TempMin != 8.4 || day.TempMax != 12.8 || day.UVIndex != 1.3 ||
+					day.PrecipSum != 6.4 || day.GustKmh != 52.6 {
+					t.Errorf("day 1 = %+v", day)
+				}
+				if want := time.Date(2026, 10, 19, 8, 8, 0, 0, berlinLoc); !day.Sunrise.Equal(want) {
+					t.Errorf("sunrise = %s, want %s", day.Sunrise, want)
+				}
+				if day.Daylight.Round(time.Minute) != 10*time.Hour+21*time.Minute {
+					t.Errorf("daylight = %s", day.Daylight)
+				}
+			},
+		},
+		{
+			// Необязательных серий нет вовсе — нули, а не выход за границы
+			file: "minimal.json",
+			loc:  berlinLoc,
+			check: func(t *testing.T, d weatherData) {
+				if len(d.Hours) != 48 || len(d.Days) != 3 {
+					t.Fatalf("got %d hours and %d days", len(d.Hours), len(d.Days))
+				}
+				if h := d.Hours[13]; h.PrecipProb != 0 || h.PrecipMM != 0 {
+					t.Errorf("hour 13 = %+v", h)
+				}
+				if day := d.Days[2]; day.UVIndex != 0 || !day.Sunrise.IsZero() || day.Daylight != 0 {
+					t.Errorf("day 2 = %+v", day)
+				}
+			},
+		},
+		{
+			// Пустые восход и закат — не ошибка, их досчитает fillSunTimes
+			file: "polar_night.json",
+			loc:  arctic,
+			check: func(t *testing.T, d weatherData) {
+				for _, day := range d.Days {
+					if !day.Sunrise.IsZero() || !day.Sunset.IsZero() {
+						t.Errorf("%s: sunrise %s, sunset %s", day.Date, day.Sunrise, day.Sunset)
+					}
+				}
+			},
+		},
+		{file: "truncated.json", loc: berlinLoc, wantErr: "unexpected end of JSON input"},
+		{file: "wrong_type.json", loc: berlinLoc, wantErr: "cannot unmarshal string"},
+		{file: "no_current.json", loc: berlinLoc, wantErr: "current.time is missing"},
+		{file: "current_time_rfc3339.json", loc: berlinLoc, wantErr: "current.time"},
+		{file: "hourly_short.json", loc: berlinLoc, wantErr: "hourly.temperature_2m: 30 values for 48 timestamps"},
+		{file: "hourly_bad_time.json", loc: berlinLoc, wantErr: "hourly.time[5]"},
+		{file: "daily_optional_short.json", loc: berlinLoc, wantErr: "daily.uv_index_max: 2 values for 3 timestamps"},
+		{file: "no_daily.json", loc: berlinLoc, wantErr: "daily.time is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var raw openMeteoResponse
+			err := json.Unmarshal(readTestdata(t, "openmeteo/"+tt.file), &raw)
+			var d weatherData
+			if err == nil {
+				d, err = raw.weatherData(tt.loc)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, d)
+		})
+	}
+}
+
+func TestSameLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		required map[string]int
+		optional map[string]int
+		wantErr  string
+	}{
+		{"all equal", 3, map[string]int{"a": 3}, map[string]int{"b": 3}, ""},
+		{"optional missing", 3, map[string]int{"a": 3}, map[string]int{"b": 0}, ""},
+		{"required short", 3, map[string]int{"a": 2}, nil, "x.a: 2 values for 3 timestamps"},
+		{"required missing", 3, map[string]int{"a": 0}, nil, "x.a: 0 values for 3 timestamps"},
+		{"optional long", 3, nil, map[string]int{"b": 4}, "x.b: 4 values for 3 timestamps"},
+		{"first by name", 3, map[string]int{"z": 1, "a": 2}, nil, "x.a:"},
+		{"empty axis", 0, map[string]int{"a": 0}, map[string]int{"b": 0}, ""},
+	}
+	for _, tt := range tests {
+		err := sameLength("x", tt.n, tt.required, tt.optional)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAt(t *testing.T) {
+	floats := []float64{1.5, 2.5}
+	ints := []int{7}
+	tests := []struct {
+		i         int
+		wantFloat float64
+		wantInt   int
+	}{
+		{0, 1.5, 7},
+		{1, 2.5, 0},
+		{2, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := at(floats, tt.i); got != tt.wantFloat {
+			t.Errorf("at(%d) = %v, want %v", tt.i, got, tt.wantFloat)
+		}
+		if got := atInt(ints, tt.i); got != tt.wantInt {
+			t.Errorf("atInt(%d) = %v, want %v", tt.i, got, tt.wantInt)
+		}
+	}
+	if got := at(nil, 0); got != 0 {
+		t.Errorf("at(nil, 0) = %v", got)
+	}
+}
+
+// stubWeatherProvider отдаёт заготовленный ответ или падает.
+type stubWeatherProvider struct {
+	name  string
+	view  *WeatherView
+	err   error
+	crash func()
+}
+
+func (p stubWeatherProvider) Name() string { return p.name }
+
+func (p stubWeatherProvider) Load(context.Context, weatherLocation) (*WeatherView, error) {
+	if p.crash != nil {
+		p.crash()
+	}
+	return p.view, p.err
+}
+
+func TestLoadFromRecoversPanic(t *testing.T) {
+	var nilSlice []float64
+	tests := []struct {
+		name  string
+		panic func()
+		want  string
+	}{
+		{"index", func() { _ = nilSlice[3] }, "panic: runtime error: index out of range"},
+		{"value", func() { panic("boom") }, "panic: boom"},
+		{"error", func() { panic(errors.New("bad payload")) }, "panic: bad payload"},
+	}
+	for _, tt := range tests {
+		p := stubWeatherProvider{name: "panicky", crash: tt.panic}
+		view, err := loadFrom(context.Background(), p, homeLocation)
+		if view != nil || err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: view = %v, err = %v, want %q", tt.name, view, err, tt.want)
+		}
+	}
+}
+
+// TestLoadWeatherFallback — упавший, сломанный и протухший провайдеры
+// пропускаются, данные берутся у следующего.
+func TestLoadWeatherFallback(t *testing.T) {
+	fresh := &WeatherView{City: "fresh", TodayTime: time.Now()}
+	swap(t, &weatherProviders, map[string]WeatherProvider{
+		"panicky": stubWeatherProvider{name: "panicky", crash: func() { panic("index out of range") }},
+		"broken":  stubWeatherProvider{name: "broken", err: errors.New("json: unexpected EOF")},
+		"stale":   stubWeatherProvider{name: "stale", view: &WeatherView{City: "stale", TodayTime: time.Now().Add(-4 * time.Hour)}},
+		"fresh":   stubWeatherProvider{name: "fresh", view: fresh},
+	})
+
+	tests := []struct {
+		providers []string
+		want      string // City или подстрока ошибки
+		wantErr   bool
+	}{
+		{[]string{"panicky", "fresh"}, "fresh", false},
+		{[]string{"broken", "stale", "fresh"}, "fresh", false},
+		{[]string{"panicky", "broken", "stale"}, "panicky: panic: index out of range", true},
+		{[]string{"unknown"}, "unknown weather provider", true},
+		{nil, "no weather providers configured", true},
+	}
+	for _, tt := range tests {
+		place := weatherLocation{Name: "Test", TimeZone: "Europe/Berlin", Providers: tt.providers}
+		view, err := loadWeather(context.Background(), place)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%v: err = %v, want %q", tt.providers, err, tt.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.providers, err)
+			continue
+		}
+		if view.City != tt.want {
+			t.Errorf("%v: got view from %s, want %s", tt.providers, view.City, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//
+// ---------- RAW API RESPONSE ----------
+//
+
+type owmCondition struct {
+	ID int `json:"id"`
+}
+
+type openWeatherMapResponse struct {
+	Current struct {
+		Dt        int64          `json:"dt"`
+		Temp      float64        `json:"temp"`
+		FeelsLike float64        `json:"feels_like"`
+		Humidity  float64        `json:"humidity"`
+		WindSpeed float64        `json:"wind_speed"` // м/с
+		Weather   []owmCondition `json:"weather"`
+	} `json:"current"`
+	Hourly []struct {
+		Dt      int64          `json:"dt"`
+		Temp    float64        `json:"temp"`
+		Pop     float64        `json:"pop"` // 0..1
+		Weather []owmCondition `json:"weather"`
+		Rain    struct {
+			OneHour float64 `json:"1h"`
+		} `json:"rain"`
+		Snow struct {
+			OneHour float64 `json:"1h"`
+		} `json:"snow"`
+	} `json:"hourly"`
+	Daily []struct {
+		Dt      int64 `json:"dt"`
+		Sunrise int64 `json:"sunrise"`
+		Sunset  int64 `json:"sunset"`
+		Temp    struct {
+			Min float64 `json:"min"`
+			Max float64 `json:"max"`
+		} `json:"temp"`
+		WindGust float64        `json:"wind_gust"` // м/с
+		UVI      float64        `json:"uvi"`
+		Rain     float64        `json:"rain"`
+		Snow     float64        `json:"snow"`
+		Weather  []owmCondition `json:"weather"`
+	} `json:"daily"`
+}
+
+// owmCode переводит id условия OpenWeatherMap в код WMO.
+func owmCode(conditions []owmCondition) int {
+	if len(conditions) == 0 {
+		return 3
+	}
+	id := conditions[0].ID
+	switch {
+	case id >= 200 && id < 300:
+		return 95
+	case id == 300 || id == 310:
+		return 51
+	case id == 302 || id == 312 || id == 314:
+		return 55
+	case id >= 300 && id < 400:
+		return 53
+	case id == 500:
+		return 61
+	case id == 501:
+		return 63
+	case id >= 502 && id <= 504:
+		return 65
+	case id == 511:
+		return 66
+	case id == 520:
+		return 80
+	case id == 521:
+		return 81
+	case id == 522 || id == 531:
+		return 82
+	case id == 600:
+		return 71
+	case id == 601:
+		return 73
+	case id == 602:
+		return 75
+	case id >= 611 && id <= 616:
+		return 66
+	case id == 620:
+		return 85
+	case id == 621 || id == 622:
+		return 86
+	case id >= 700 && id < 800:
+		return 45
+	case id == 800:
+		return 0
+	case id == 801:
+		return 1
+	case id == 802:
+		return 2
+	default:
+		return 3
+	}
+}
+
+//
+// ---------- PROVIDER ----------
+//
+
+// openWeatherMapProvider — One Call 3.0. Нужен ключ (DASHBOARD_OWM_API_KEY),
+// без него провайдер сразу отказывается.
+type openWeatherMapProvider struct {
+	APIKey string
+}
+
+func (openWeatherMapProvider) Name() string { return "openweathermap" }
+
+func (p openWeatherMapProvider) Load(ctx context.Context, place weatherLocation) (*WeatherView, error) {
+	if p.APIKey == "" {
+		return nil, errors.New("openweathermap: DASHBOARD_OWM_API_KEY is not set")
+	}
+	url := "https://api.openweathermap.org/data/3.0/onecall" +
+		fmt.Sprintf("?lat=%.4f&lon=%.4f", place.Latitude, place.Longitude) +
+		"&units=metric&exclude=minutely,alerts&appid=" + p.APIKey
+
+	var raw openWeatherMapResponse
+	if err := getJSON(ctx, url, nil, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Current.Dt == 0 || len(raw.Daily) == 0 {
+		return nil, fmt.Errorf("openweathermap: incomplete response")
+	}
+
+	loc, err := place.location()
+	if err != nil {
+		return nil, err
+	}
+	d := weatherData{
+		Current: weatherCurrent{
+			Time:      time.Unix(raw.Current.Dt, 0).In(loc),
+			Temp:      raw.Current.Temp,
+			FeelsLike: raw.Current.FeelsLike,
+			Code:      owmCode(raw.Current.Weather),
+			Humidity:  raw.Current.Humidity,
+			WindKmh:   raw.Current.WindSpeed * 3.6,
+		},
+	}
+	for _, h := range raw.Hourly {
+		d.Hours = append(d.Hours, WeatherHour{
+			Time:       time.Unix(h.Dt, 0).In(loc),
+			Temp:       h.Temp,
+			PrecipProb: int(h.Pop*100 + 0.5),
+			PrecipMM:   h.Rain.OneHour + h.Snow.OneHour,
+		})
+	}
+	for _, day := range raw.Daily {
+		rise := time.Unix(day.Sunrise, 0).In(loc)
+		set := time.Unix(day.Sunset, 0).In(loc)
+		d.Days = append(d.Days, weatherDayData{
+			Date:      dayOf(time.Unix(day.Dt, 0), loc),
+			Code:      owmCode(day.Weather),
+			TempMin:   day.Temp.Min,
+			TempMax:   day.Temp.Max,
+			Sunrise:   rise,
+			Sunset:    set,
+			Daylight:  set.Sub(rise),
+			UVIndex:   day.UVI,
+			PrecipSum: day.Rain + day.Snow,
+			GustKmh:   day.WindGust * 3.6,
+		})
+	}
+	return buildWeatherView(place, loc, d), nil
+}
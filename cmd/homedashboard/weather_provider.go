@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+//
+// ---------- PROVIDERS ----------
+//
+
+// WeatherProvider — источник прогноза. Каждый провайдер сам ходит в своё API
+// и переводит ответ в weatherData, а view собирается общим buildWeatherView.
+type WeatherProvider interface {
+	Name() string
+	Load(ctx context.Context, place weatherLocation) (*WeatherView, error)
+}
+
+var weatherProviders = map[string]WeatherProvider{
+	"open-meteo":     openMeteoProvider{},
+	"met-norway":     metNorwayProvider{},
+	"brightsky":      brightSkyProvider{},
+	"openweathermap": openWeatherMapProvider{APIKey: envString("DASHBOARD_OWM_API_KEY", "")},
+}
+
+// Текущие условия старше этого считаем протухшими и идём к следующему провайдеру.
+const weatherMaxAge = 3 * time.Hour
+
+//
+// ---------- LOCATIONS ----------
+//
+
+type weatherLocation struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	TimeZone  string
+	Providers []string // по порядку: первый — основной, остальные — запасные
+}
+
+var homeLocation = weatherLocation{
+	Name:      "Düsseldorf",
+	Latitude:  51.2277, // нормальные координаты Дюссельдорфа
+	Longitude: 6.7735,
+	TimeZone:  "Europe/Berlin",
+	Providers: envList("DASHBOARD_WEATHER_PROVIDERS", "open-meteo,met-norway,brightsky"),
+}
+
+func (l weatherLocation) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(l.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%s: time zone %q: %w", l.Name, l.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// loadWeather опрашивает провайдеров места по очереди, пока кто-нибудь не
+// отдаст свежие данные.
+func loadWeather(ctx context.Context, place weatherLocation) (*WeatherView, error) {
+	var errs []error
+	for _, name := range place.Providers {
+		provider, ok := weatherProviders[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown weather provider", name))
+			continue
+		}
+
+		view, err := loadFrom(ctx, provider, place)
+		// Возраст данных — по настоящим часам: при DASHBOARD_FAKE_NOW иначе
+		// протухшим оказался бы любой ответ
+		if err == nil && time.Since(view.TodayTime) > weatherMaxAge {
+			err = fmt.Errorf("stale data from %s", view.TodayTime.Format(time.RFC3339))
+		}
+		if err != nil {
+			log.Printf("weather provider %s failed for %s: %v", name, place.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		return view, nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no weather providers configured for %s", place.Name)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// loadFrom превращает панику провайдера в обычную ошибку: кривой ответ
+// одного API не должен ронять фоновый рендер и весь процесс.
+func loadFrom(ctx context.Context, provider WeatherProvider, place weatherLocation) (view *WeatherView, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return provider.Load(ctx, place)
+}
+
+// dayOf — полночь того же дня в зоне места.
+func dayOf(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// dailyFromHours собирает дневные значения из почасового ряда для
+// провайдеров, у которых нет отдельного дневного прогноза.
+func dailyFromHours(hours []WeatherHour, codes []int, loc *time.Location) []weatherDayData {
+	var days []weatherDayData
+	for i, h := range hours {
+		date := dayOf(h.Time, loc)
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, weatherDayData{Date: date, TempMin: h.Temp, TempMax: h.Temp})
+		}
+		d := &days[len(days)-1]
+		d.TempMin = min(d.TempMin, h.Temp)
+		d.TempMax = max(d.TempMax, h.Temp)
+		d.PrecipSum += h.PrecipMM
+		// Дневной значок — самый «тяжёлый» код за световой день
+		if hour := h.Time.In(loc).Hour(); i < len(codes) && hour >= 8 && hour <= 20 && codes[i] > d.Code {
+			d.Code = codes[i]
+		}
+	}
+	return days
+}